@@ -0,0 +1,118 @@
+package eth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// ABI selectors of the contract functions called, the first 4 bytes of the
+// keccak hash of their signature.
+const (
+	balanceOfSelector = "0x70a08231"
+	decimalsSelector  = "0x313ce567"
+	symbolSelector    = "0x95d89b41"
+
+	// ENS registry and resolver functions
+	resolverSelector = "0x0178b8bf"
+	addrSelector     = "0x3b3b57de"
+	nameSelector     = "0x691f3431"
+)
+
+// words are the 32-byte units ABI arguments and results are made of, in hex.
+const wordLength = 64
+
+// encodeCall is the call data of a function taking static arguments.
+func encodeCall(selector string, words ...string) string {
+	return selector + strings.Join(words, "")
+}
+
+// addressWord left pads an address to a word.
+func addressWord(address string) string {
+	return leftPad(strings.ToLower(strings.TrimPrefix(address, "0x")))
+}
+
+func leftPad(word string) string {
+	if len(word) >= wordLength {
+		return word
+	}
+	return strings.Repeat("0", wordLength-len(word)) + word
+}
+
+// resultWords splits an eth_call result into words.
+func resultWords(result string) ([]string, error) {
+	data := strings.TrimPrefix(result, "0x")
+	if len(data) == 0 || len(data)%wordLength != 0 {
+		return nil, fmt.Errorf("call result of %d hex digits is not a sequence of words", len(data))
+	}
+	words := make([]string, len(data)/wordLength)
+	for i := range words {
+		words[i] = data[i*wordLength : (i+1)*wordLength]
+	}
+	return words, nil
+}
+
+// decodeUint decodes a uint result.
+func decodeUint(result string) (*big.Int, error) {
+	words, err := resultWords(result)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := new(big.Int).SetString(words[0], 16)
+	if !ok {
+		return nil, fmt.Errorf("call result %q is not hex", words[0])
+	}
+	return value, nil
+}
+
+// decodeAddress decodes an address result in lower case.
+func decodeAddress(result string) (string, error) {
+	words, err := resultWords(result)
+	if err != nil {
+		return "", err
+	}
+	return "0x" + strings.ToLower(words[0][wordLength-40:]), nil
+}
+
+// decodeString decodes a string result. A single word is taken as bytes32,
+// which older tokens like MKR return from symbol.
+func decodeString(result string) (string, error) {
+	words, err := resultWords(result)
+	if err != nil {
+		return "", err
+	}
+	if len(words) == 1 {
+		data, err := hex.DecodeString(words[0])
+		if err != nil {
+			return "", fmt.Errorf("call result is not hex: %w", err)
+		}
+		return checkString(bytes.TrimRight(data, "\x00"))
+	}
+
+	offset, ok := new(big.Int).SetString(words[0], 16)
+	if !ok || !offset.IsInt64() || offset.Int64()%32 != 0 || int(offset.Int64()/32) >= len(words) {
+		return "", fmt.Errorf("invalid string offset %s", words[0])
+	}
+	start := int(offset.Int64() / 32)
+	length, ok := new(big.Int).SetString(words[start], 16)
+	data := strings.Join(words[start+1:], "")
+	// the length is compared before doubling it, which a hostile length overflows
+	if !ok || length.Cmp(big.NewInt(int64(len(data)/2))) > 0 {
+		return "", fmt.Errorf("invalid string length %s", words[start])
+	}
+	decoded, err := hex.DecodeString(data[:length.Int64()*2])
+	if err != nil {
+		return "", fmt.Errorf("call result is not hex: %w", err)
+	}
+	return checkString(decoded)
+}
+
+func checkString(data []byte) (string, error) {
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("call result %x is not UTF-8", data)
+	}
+	return string(data), nil
+}
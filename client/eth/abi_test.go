@@ -0,0 +1,72 @@
+package eth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EncodeCall(t *testing.T) {
+	assert.Equal(t, "0x70a08231000000000000000000000000d8da6bf26964af9d7eed9e03e53415d37aa96045",
+		encodeCall(balanceOfSelector, addressWord("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")))
+	assert.Equal(t, "0x313ce567", encodeCall(decimalsSelector))
+}
+
+func Test_DecodeUint(t *testing.T) {
+	value, err := decodeUint("0x0000000000000000000000000000000000000000000000000000000000000012")
+	require.NoError(t, err)
+	assert.Equal(t, int64(18), value.Int64())
+
+	_, err = decodeUint("0x")
+	assert.Error(t, err)
+	_, err = decodeUint("0x12")
+	assert.Error(t, err)
+}
+
+func Test_DecodeString(t *testing.T) {
+	tests := []struct {
+		name   string
+		result string
+		want   string
+	}{
+		{
+			name: "string",
+			result: "0x0000000000000000000000000000000000000000000000000000000000000020" +
+				"0000000000000000000000000000000000000000000000000000000000000004" +
+				"5553445400000000000000000000000000000000000000000000000000000000",
+			want: "USDT",
+		},
+		{
+			name:   "bytes32",
+			result: "0x4d4b520000000000000000000000000000000000000000000000000000000000",
+			want:   "MKR",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeString(tt.result)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := decodeString("0x0000000000000000000000000000000000000000000000000000000000000020" +
+		"00000000000000000000000000000000000000000000000000000000000000ff" + strings.Repeat("0", 64))
+	assert.Error(t, err)
+	_, err = decodeString("0x00000000000000000000000000000000000000000000000000000000000000c0" + strings.Repeat("0", 64))
+	assert.Error(t, err)
+
+	// a contract can return lengths and offsets that overflow when scaled
+	_, err = decodeString("0x0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000004000000000000000" + strings.Repeat("0", 64))
+	assert.Error(t, err)
+	_, err = decodeString("0x0000000000000000000000000000000000000000000000000000000000000020" +
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" + strings.Repeat("0", 64))
+	assert.Error(t, err)
+	_, err = decodeString("0x0000000000000000000000000000000000000000000000004000000000000000" + strings.Repeat("0", 64))
+	assert.Error(t, err)
+	_, err = decodeString("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe0" + strings.Repeat("0", 64))
+	assert.Error(t, err)
+}
@@ -5,23 +5,49 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math/big"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
 	"deshev.com/eth-address-watch/config"
 	"deshev.com/eth-address-watch/domain"
+	"deshev.com/eth-address-watch/tracing"
 )
 
 type Client struct {
+	endpoint atomic.Pointer[endpoint]
+}
+
+// endpoint is the node a Client talks to, swapped as a whole on Reconfigure
+// so a request never mixes the URL of one node with the settings of another.
+type endpoint struct {
 	nodeURL        string
+	label          string
 	requestTimeout time.Duration
 }
 
 func NewClient(cfg *config.Config) *Client {
-	return &Client{
+	c := &Client{}
+	c.Reconfigure(cfg)
+	return c
+}
+
+// Reconfigure switches to the node and request timeout of cfg. Requests
+// already in flight finish against the previous node.
+func (c *Client) Reconfigure(cfg *config.Config) {
+	c.endpoint.Store(&endpoint{
 		nodeURL:        cfg.EthNodeURL,
+		label:          endpointLabel(cfg.EthNodeURL),
 		requestTimeout: cfg.EthRequestTimeout,
-	}
+	})
 }
 
 func (c *Client) GetBlock(ctx context.Context, blockNumber int) (*domain.Block, error) {
@@ -55,8 +81,59 @@ func (c *Client) GetLatestBlock(ctx context.Context) (int, error) {
 	return blockNumber, nil
 }
 
-func jsonRPCRequest[R any](ctx context.Context, c *Client, call rpcMethodCall, result *R) error {
-	ctx, cancel := context.WithTimeout(ctx, c.requestTimeout)
+// GetBalance returns the wei balance of an address after the block.
+func (c *Client) GetBalance(ctx context.Context, address string, blockNumber int) (*big.Int, error) {
+	req := balanceCall(address, blockNumber)
+	var result struct {
+		Result string `json:"result"`
+	}
+	err := jsonRPCRequest(ctx, c, req, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, ok := new(big.Int).SetString(strings.TrimPrefix(result.Result, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("balance parse error: %q", result.Result)
+	}
+	return balance, nil
+}
+
+// GetReceipt returns the receipt of a mined transaction.
+func (c *Client) GetReceipt(ctx context.Context, hash string) (*domain.Receipt, error) {
+	req := receiptCall(hash)
+	var result struct {
+		Result *domain.Receipt `json:"result"`
+	}
+	err := jsonRPCRequest(ctx, c, req, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Result == nil {
+		return nil, fmt.Errorf("no receipt for transaction %s", hash)
+	}
+	return result.Result, nil
+}
+
+func jsonRPCRequest[R any](ctx context.Context, c *Client, call rpcMethodCall, result *R) (err error) {
+	ep := c.endpoint.Load()
+	ctx, span := tracing.Start(ctx, call.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.RPCSystemKey.String("jsonrpc"),
+		semconv.RPCMethod(call.Method),
+		semconv.RPCJsonrpcVersion(call.JSONRPC),
+		semconv.ServerAddress(ep.label),
+	))
+	start := time.Now()
+	defer func() {
+		observeRequest(call.Method, ep.label, time.Since(start), err)
+		if err != nil {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, ep.requestTimeout)
 	defer cancel()
 
 	payload := bytes.Buffer{}
@@ -64,11 +141,12 @@ func jsonRPCRequest[R any](ctx context.Context, c *Client, call rpcMethodCall, r
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.nodeURL, &payload)
+	req, err := http.NewRequestWithContext(ctx, "POST", ep.nodeURL, &payload)
 	if err != nil {
 		return fmt.Errorf("http request create error: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -76,12 +154,35 @@ func jsonRPCRequest[R any](ctx context.Context, c *Client, call rpcMethodCall, r
 	}
 	defer resp.Body.Close()
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("http response read error: %w", err)
+	}
+	var response struct {
+		Error *rpcError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("http response parse error: %w", err)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
 		return fmt.Errorf("http response parse error: %w", err)
 	}
 	return nil
 }
 
+// rpcError is the error of a JSON-RPC response, like a reverted eth_call.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("node error %d: %s", e.Code, e.Message)
+}
+
 type rpcMethodCall struct {
 	JSONRPC string `json:"jsonrpc"`
 	Method  string `json:"method"`
@@ -106,3 +207,22 @@ func blockByNumberCall(blockNumber int) rpcMethodCall {
 		ID:      1,
 	}
 }
+
+func balanceCall(address string, blockNumber int) rpcMethodCall {
+	hexBlockNumber := fmt.Sprintf("0x%x", blockNumber)
+	return rpcMethodCall{
+		JSONRPC: "2.0",
+		Method:  "eth_getBalance",
+		Params:  []any{address, hexBlockNumber},
+		ID:      1,
+	}
+}
+
+func receiptCall(hash string) rpcMethodCall {
+	return rpcMethodCall{
+		JSONRPC: "2.0",
+		Method:  "eth_getTransactionReceipt",
+		Params:  []any{hash},
+		ID:      1,
+	}
+}
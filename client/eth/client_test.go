@@ -92,3 +92,68 @@ func TestClient_GetBlock(t *testing.T) {
 	assert.Equal(t, "0x1234", block.Transactions[0].From)
 	assert.Equal(t, "0x5678", block.Transactions[0].To)
 }
+
+func TestClient_GetBalance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "eth_getBalance", req["method"])
+		assert.Equal(t, []interface{}{"0x1111", "0x1234"}, req["params"])
+
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]string{"result": "0x1bc16d674ec80000"}))
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{EthNodeURL: server.URL, EthRequestTimeout: time.Second})
+	balance, err := client.GetBalance(context.Background(), "0x1111", 0x1234)
+	require.NoError(t, err)
+	assert.Equal(t, "2000000000000000000", balance.String())
+}
+
+func TestClient_GetReceipt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "eth_getTransactionReceipt", req["method"])
+
+		response := map[string]interface{}{"result": nil}
+		if req["params"].([]interface{})[0] == "0xa" {
+			response["result"] = map[string]string{"status": "0x1", "gasUsed": "0x5208", "effectiveGasPrice": "0x3b9aca00"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{EthNodeURL: server.URL, EthRequestTimeout: time.Second})
+	receipt, err := client.GetReceipt(context.Background(), "0xa")
+	require.NoError(t, err)
+	assert.Equal(t, "0x1", receipt.Status)
+	assert.Equal(t, "21000000000000", receipt.Fee().String())
+
+	_, err = client.GetReceipt(context.Background(), "0xb")
+	assert.Error(t, err)
+}
+
+func TestClient_Reconfigure(t *testing.T) {
+	node := func(result string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			assert.NoError(t, json.NewEncoder(w).Encode(map[string]string{"result": result}))
+		}))
+	}
+	first, second := node("0x1"), node("0x2")
+	defer first.Close()
+	defer second.Close()
+
+	client := NewClient(&config.Config{EthNodeURL: first.URL, EthRequestTimeout: time.Second})
+	block, err := client.GetLatestBlock(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, block)
+
+	client.Reconfigure(&config.Config{EthNodeURL: second.URL, EthRequestTimeout: time.Second})
+	block, err = client.GetLatestBlock(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, block)
+}
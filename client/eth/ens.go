@@ -0,0 +1,120 @@
+package eth
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const (
+	// ensRegistry is the ENS registry on mainnet
+	ensRegistry = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+	zeroAddress = "0x0000000000000000000000000000000000000000"
+)
+
+// namehash is the ENS node of a name, hashing its labels from the last one.
+// Names are only lower cased, not fully normalized.
+func namehash(name string) string {
+	node := make([]byte, 32)
+	if name == "" {
+		return hex.EncodeToString(node)
+	}
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		label := keccak256([]byte(labels[i]))
+		node = keccak256(append(node, label...))
+	}
+	return hex.EncodeToString(node)
+}
+
+func keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// resolver returns the resolver contract of an ENS node, or
+// domain.ErrNameNotFound when it has none.
+func (c *Client) resolver(ctx context.Context, name, node string) (string, error) {
+	result, err := c.Call(ctx, ensRegistry, encodeCall(resolverSelector, node), 0)
+	if err != nil {
+		return "", err
+	}
+	resolver, err := decodeAddress(result)
+	if err != nil {
+		return "", fmt.Errorf("resolver of %s: %w", name, err)
+	}
+	if resolver == zeroAddress {
+		return "", fmt.Errorf("%w: %s has no resolver", domain.ErrNameNotFound, name)
+	}
+	return resolver, nil
+}
+
+// ResolveName returns the address an ENS name points to, in lower case like
+// the addresses in blocks.
+func (c *Client) ResolveName(ctx context.Context, name string) (string, error) {
+	node := namehash(name)
+	resolver, err := c.resolver(ctx, name, node)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := c.Call(ctx, resolver, encodeCall(addrSelector, node), 0)
+	if err != nil {
+		return "", err
+	}
+	address, err := decodeAddress(result)
+	if err != nil {
+		return "", fmt.Errorf("address of %s: %w", name, err)
+	}
+	if address == zeroAddress {
+		return "", fmt.Errorf("%w: %s has no address", domain.ErrNameNotFound, name)
+	}
+	return address, nil
+}
+
+// LookupAddress returns the primary ENS name of an address, or an empty
+// name when it has none. Anyone can claim any name in their reverse record,
+// so only names that resolve back to the address count.
+func (c *Client) LookupAddress(ctx context.Context, address string) (string, error) {
+	reverse := strings.ToLower(strings.TrimPrefix(address, "0x")) + ".addr.reverse"
+	node := namehash(reverse)
+	resolver, err := c.resolver(ctx, reverse, node)
+	if err != nil {
+		return "", ignoreNotFound(err)
+	}
+
+	result, err := c.Call(ctx, resolver, encodeCall(nameSelector, node), 0)
+	if err != nil || result == "0x" {
+		return "", err
+	}
+	name, err := decodeString(result)
+	if err != nil {
+		return "", fmt.Errorf("name of %s: %w", address, err)
+	}
+	if name == "" {
+		return "", nil
+	}
+
+	resolved, err := c.ResolveName(ctx, name)
+	if err != nil {
+		return "", ignoreNotFound(err)
+	}
+	if !strings.EqualFold(resolved, address) {
+		return "", nil
+	}
+	return name, nil
+}
+
+func ignoreNotFound(err error) error {
+	if errors.Is(err, domain.ErrNameNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package eth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/domain"
+)
+
+const (
+	resolverAddress = "0x231b0ee14048e9dccd1d247744d114a4eb5e8e63"
+	vitalik         = "0xd8da6bf26964af9d7eed9e03e53415d37aa96045"
+	zeroWord        = "0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+func Test_Namehash(t *testing.T) {
+	assert.Equal(t, zeroWord, namehash(""))
+	assert.Equal(t, "93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae", namehash("eth"))
+	assert.Equal(t, "de9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f", namehash("foo.eth"))
+	assert.Equal(t, namehash("foo.eth"), namehash("Foo.ETH"))
+}
+
+// ensResults are the canned calls resolving vitalik.eth both ways.
+func ensResults() map[string]string {
+	name := namehash("vitalik.eth")
+	reverse := namehash(vitalik[2:] + ".addr.reverse")
+	return map[string]string{
+		ensRegistry + " " + resolverSelector + name + " latest":    "0x" + addressWord(resolverAddress),
+		resolverAddress + " " + addrSelector + name + " latest":    "0x" + addressWord(vitalik),
+		ensRegistry + " " + resolverSelector + reverse + " latest": "0x" + addressWord(resolverAddress),
+		resolverAddress + " " + nameSelector + reverse + " latest": "0x0000000000000000000000000000000000000000000000000000000000000020" +
+			"000000000000000000000000000000000000000000000000000000000000000b" +
+			"766974616c696b2e657468000000000000000000000000000000000000000000",
+	}
+}
+
+func TestClient_ResolveName(t *testing.T) {
+	results := ensResults()
+	results[ensRegistry+" "+resolverSelector+namehash("nobody.eth")+" latest"] = "0x" + zeroWord
+	server := callNode(t, results)
+	defer server.Close()
+
+	client := NewClient(&config.Config{EthNodeURL: server.URL, EthRequestTimeout: time.Second})
+	address, err := client.ResolveName(context.Background(), "vitalik.eth")
+	require.NoError(t, err)
+	assert.Equal(t, vitalik, address)
+
+	_, err = client.ResolveName(context.Background(), "nobody.eth")
+	assert.ErrorIs(t, err, domain.ErrNameNotFound)
+	// reverts are node errors
+	_, err = client.ResolveName(context.Background(), "broken.eth")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, domain.ErrNameNotFound)
+}
+
+func TestClient_LookupAddress(t *testing.T) {
+	results := ensResults()
+	other := "0x1111111111111111111111111111111111111111"
+	// claims vitalik.eth, which does not resolve to it
+	otherReverse := namehash(other[2:] + ".addr.reverse")
+	results[ensRegistry+" "+resolverSelector+otherReverse+" latest"] = "0x" + addressWord(resolverAddress)
+	results[resolverAddress+" "+nameSelector+otherReverse+" latest"] = results[resolverAddress+" "+nameSelector+namehash(vitalik[2:]+".addr.reverse")+" latest"]
+	nobody := "0x2222222222222222222222222222222222222222"
+	results[ensRegistry+" "+resolverSelector+namehash(nobody[2:]+".addr.reverse")+" latest"] = "0x" + zeroWord
+	// a reverse record with a name length that overflows when decoded
+	hostile := "0x3333333333333333333333333333333333333333"
+	hostileReverse := namehash(hostile[2:] + ".addr.reverse")
+	results[ensRegistry+" "+resolverSelector+hostileReverse+" latest"] = "0x" + addressWord(resolverAddress)
+	results[resolverAddress+" "+nameSelector+hostileReverse+" latest"] = "0x0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000004000000000000000" + zeroWord
+	server := callNode(t, results)
+	defer server.Close()
+
+	client := NewClient(&config.Config{EthNodeURL: server.URL, EthRequestTimeout: time.Second})
+	name, err := client.LookupAddress(context.Background(), "0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	require.NoError(t, err)
+	assert.Equal(t, "vitalik.eth", name)
+
+	name, err = client.LookupAddress(context.Background(), other)
+	require.NoError(t, err)
+	assert.Empty(t, name)
+	name, err = client.LookupAddress(context.Background(), nobody)
+	require.NoError(t, err)
+	assert.Empty(t, name)
+	_, err = client.LookupAddress(context.Background(), hostile)
+	assert.Error(t, err)
+}
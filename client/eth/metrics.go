@@ -0,0 +1,32 @@
+package eth
+
+import (
+	"net/url"
+	"time"
+
+	"deshev.com/eth-address-watch/metrics"
+)
+
+var (
+	requestDuration = metrics.Default.NewHistogram("eaw_rpc_request_duration_seconds",
+		"Latency of JSON-RPC requests to the node.", metrics.DefaultBuckets, "method", "endpoint")
+	requestErrors = metrics.Default.NewCounter("eaw_rpc_request_errors_total",
+		"Failed JSON-RPC requests to the node.", "method", "endpoint")
+)
+
+func observeRequest(method, endpoint string, duration time.Duration, err error) {
+	requestDuration.With(method, endpoint).Observe(duration.Seconds())
+	if err != nil {
+		requestErrors.With(method, endpoint).Inc()
+	}
+}
+
+// endpointLabel keeps only the scheme and host of a node URL, since hosted
+// node providers put the API key in the path.
+func endpointLabel(nodeURL string) string {
+	u, err := url.Parse(nodeURL)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,51 @@
+package eth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"deshev.com/eth-address-watch/config"
+)
+
+func Test_RequestMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/broken":
+			w.Write([]byte("not json"))
+			return
+		case "/limited":
+			w.Write([]byte(`{"error":{"code":-32005,"message":"limit exceeded"}}`))
+			return
+		}
+		w.Write([]byte(`{"result":"0x10"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{EthNodeURL: server.URL + "/key", EthRequestTimeout: time.Second})
+	broken := NewClient(&config.Config{EthNodeURL: server.URL + "/broken", EthRequestTimeout: time.Second})
+	limited := NewClient(&config.Config{EthNodeURL: server.URL + "/limited", EthRequestTimeout: time.Second})
+	latency := requestDuration.With("eth_blockNumber", server.URL)
+	failures := requestErrors.With("eth_blockNumber", server.URL)
+	observed, failed := latency.Count(), failures.Value()
+
+	_, err := client.GetLatestBlock(context.Background())
+	assert.NoError(t, err)
+	_, err = broken.GetLatestBlock(context.Background())
+	assert.Error(t, err)
+	_, err = limited.GetLatestBlock(context.Background())
+	assert.EqualError(t, err, "node error -32005: limit exceeded")
+
+	assert.Equal(t, observed+3, latency.Count())
+	assert.Equal(t, failed+2, failures.Value())
+}
+
+func Test_EndpointLabel(t *testing.T) {
+	assert.Equal(t, "https://mainnet.infura.io", endpointLabel("https://mainnet.infura.io/v3/secret"))
+	assert.Equal(t, "http://localhost:8545", endpointLabel("http://localhost:8545"))
+	assert.Equal(t, "unknown", endpointLabel("localhost"))
+}
@@ -0,0 +1,108 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+// Call runs a read-only contract call with eth_call at the block, or at the
+// latest block for 0, and returns the hex result.
+func (c *Client) Call(ctx context.Context, to, data string, blockNumber int) (string, error) {
+	req := callCall(to, data, blockNumber)
+	var result struct {
+		Result string `json:"result"`
+	}
+	err := jsonRPCRequest(ctx, c, req, &result)
+	if err != nil {
+		return "", fmt.Errorf("call to %s failed: %w", to, err)
+	}
+	return result.Result, nil
+}
+
+// GetTokenBalance returns the balance of a holder in an ERC-20 token after
+// the block, in the smallest unit of the token.
+func (c *Client) GetTokenBalance(ctx context.Context, token, holder string, blockNumber int) (*big.Int, error) {
+	result, err := c.Call(ctx, token, encodeCall(balanceOfSelector, addressWord(holder)), blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := decodeUint(result)
+	if err != nil {
+		return nil, fmt.Errorf("balanceOf of %s: %w", token, err)
+	}
+	return balance, nil
+}
+
+// GetTokenInfo reads the symbol and decimals of an ERC-20 token.
+func (c *Client) GetTokenInfo(ctx context.Context, token string) (*domain.TokenInfo, error) {
+	result, err := c.Call(ctx, token, encodeCall(decimalsSelector), 0)
+	if err != nil {
+		return nil, err
+	}
+	decimals, err := decodeUint(result)
+	if err != nil || !decimals.IsInt64() || decimals.Int64() > 255 {
+		return nil, fmt.Errorf("decimals of %s: invalid result %q", token, result)
+	}
+
+	result, err = c.Call(ctx, token, encodeCall(symbolSelector), 0)
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := decodeString(result)
+	if err != nil {
+		return nil, fmt.Errorf("symbol of %s: %w", token, err)
+	}
+
+	return &domain.TokenInfo{Address: strings.ToLower(token), Symbol: symbol, Decimals: int(decimals.Int64())}, nil
+}
+
+// GetTransferLogs returns the Transfer logs the token contracts emitted in
+// the block.
+func (c *Client) GetTransferLogs(ctx context.Context, tokens []string, blockNumber int) ([]*domain.Log, error) {
+	req := logsCall(tokens, []string{domain.TransferTopic}, blockNumber)
+	var result struct {
+		Result []*domain.Log `json:"result"`
+	}
+	err := jsonRPCRequest(ctx, c, req, &result)
+	if err != nil {
+		return nil, fmt.Errorf("logs of block %d: %w", blockNumber, err)
+	}
+	return result.Result, nil
+}
+
+// blockTag is the block parameter of a call, latest for 0.
+func blockTag(blockNumber int) string {
+	if blockNumber == 0 {
+		return "latest"
+	}
+	return fmt.Sprintf("0x%x", blockNumber)
+}
+
+func callCall(to, data string, blockNumber int) rpcMethodCall {
+	return rpcMethodCall{
+		JSONRPC: "2.0",
+		Method:  "eth_call",
+		Params:  []any{map[string]string{"to": to, "data": data}, blockTag(blockNumber)},
+		ID:      1,
+	}
+}
+
+func logsCall(addresses, topics []string, blockNumber int) rpcMethodCall {
+	hexBlockNumber := fmt.Sprintf("0x%x", blockNumber)
+	return rpcMethodCall{
+		JSONRPC: "2.0",
+		Method:  "eth_getLogs",
+		Params: []any{map[string]any{
+			"address":   addresses,
+			"topics":    []any{topics},
+			"fromBlock": hexBlockNumber,
+			"toBlock":   hexBlockNumber,
+		}},
+		ID: 1,
+	}
+}
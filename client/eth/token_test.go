@@ -0,0 +1,108 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/domain"
+)
+
+const token = "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+
+// callNode answers eth_call with the canned result of the call data, and
+// reverts unknown calls.
+func callNode(t *testing.T, results map[string]string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "eth_call", req.Method)
+		var call struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(req.Params[0], &call))
+		var block string
+		assert.NoError(t, json.Unmarshal(req.Params[1], &block))
+
+		response := map[string]any{}
+		if result, exists := results[call.To+" "+call.Data+" "+block]; exists {
+			response["result"] = result
+		} else {
+			response["error"] = map[string]any{"code": 3, "message": "execution reverted"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+}
+
+func TestClient_GetTokenBalance(t *testing.T) {
+	server := callNode(t, map[string]string{
+		token + " 0x70a08231000000000000000000000000d8da6bf26964af9d7eed9e03e53415d37aa96045 0x1234": "0x00000000000000000000000000000000000000000000000000000000000f4240",
+	})
+	defer server.Close()
+
+	client := NewClient(&config.Config{EthNodeURL: server.URL, EthRequestTimeout: time.Second})
+	balance, err := client.GetTokenBalance(context.Background(), token, "0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045", 0x1234)
+	require.NoError(t, err)
+	assert.Equal(t, "1000000", balance.String())
+
+	_, err = client.GetTokenBalance(context.Background(), token, "0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045", 0)
+	assert.EqualError(t, err, "call to "+token+" failed: node error 3: execution reverted")
+}
+
+func TestClient_GetTokenInfo(t *testing.T) {
+	server := callNode(t, map[string]string{
+		token + " 0x313ce567 latest": "0x0000000000000000000000000000000000000000000000000000000000000006",
+		token + " 0x95d89b41 latest": "0x0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000004" +
+			"5553445400000000000000000000000000000000000000000000000000000000",
+	})
+	defer server.Close()
+
+	client := NewClient(&config.Config{EthNodeURL: server.URL, EthRequestTimeout: time.Second})
+	info, err := client.GetTokenInfo(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, &domain.TokenInfo{Address: "0xdac17f958d2ee523a2206206994597c13d831ec7", Symbol: "USDT", Decimals: 6}, info)
+}
+
+func TestClient_GetTransferLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "eth_getLogs", req["method"])
+		assert.Equal(t, []any{map[string]any{
+			"address":   []any{token},
+			"topics":    []any{[]any{domain.TransferTopic}},
+			"fromBlock": "0x1234",
+			"toBlock":   "0x1234",
+		}}, req["params"])
+
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"result": []any{map[string]any{
+			"address": token,
+			"topics":  []string{domain.TransferTopic, "0x01", "0x02"},
+			"data":    "0x0f",
+		}}}))
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.Config{EthNodeURL: server.URL, EthRequestTimeout: time.Second})
+	logs, err := client.GetTransferLogs(context.Background(), []string{token}, 0x1234)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, token, logs[0].Address)
+	assert.Equal(t, "0x0f", logs[0].Data)
+}
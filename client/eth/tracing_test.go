@@ -0,0 +1,55 @@
+package eth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/tracing"
+	"deshev.com/eth-address-watch/tracing/tracingtest"
+)
+
+func Test_Tracing(t *testing.T) {
+	recorder := tracingtest.Record(t)
+	traceparents := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("traceparent")
+		if r.URL.Path == "/broken" {
+			w.Write([]byte("not json"))
+			return
+		}
+		w.Write([]byte(`{"result":"0x10"}`))
+	}))
+	defer server.Close()
+
+	ctx, parent := tracing.Start(context.Background(), "parent")
+	client := NewClient(&config.Config{EthNodeURL: server.URL, EthRequestTimeout: time.Second})
+	_, err := client.GetLatestBlock(ctx)
+	require.NoError(t, err)
+	broken := NewClient(&config.Config{EthNodeURL: server.URL + "/broken", EthRequestTimeout: time.Second})
+	_, err = broken.GetBlock(ctx, 0x10)
+	require.Error(t, err)
+	parent.End()
+
+	spans := tracingtest.Named(recorder, "eth_blockNumber")
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.Equal(t, codes.Unset, span.Status().Code)
+	// version 00, trace ID, span ID of the request span, sampled
+	assert.Equal(t, "00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01", <-traceparents)
+
+	spans = tracingtest.Named(recorder, "eth_getBlockByNumber")
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.NotEmpty(t, <-traceparents)
+}
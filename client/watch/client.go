@@ -0,0 +1,437 @@
+// Package watch is the Go client of the eth-address-watch API. It talks to
+// the /v1 routes, retries requests that failed for transient reasons and
+// streams live notifications.
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const (
+	defaultRetries    = 3
+	defaultBackoff    = 200 * time.Millisecond
+	maxBackoff        = 10 * time.Second
+	defaultTimeout    = 30 * time.Second
+	apiKeyHeader      = "X-API-Key"
+	apiVersionPrefix  = "/v1"
+	lastEventIDHeader = "Last-Event-ID"
+)
+
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+	retries int
+	backoff time.Duration
+}
+
+type Option func(*Client)
+
+// WithAPIKey authenticates requests with a tenant key, or the admin key for
+// the key management methods.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithHTTPClient replaces the default client, which has a 30 second timeout
+// that does not apply to streams.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// WithRetries sets how many times a failed request is retried and the delay
+// before the first retry, which doubles with every attempt.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// NewClient creates a client for the service at baseURL, like
+// http://localhost:9000.
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: baseURL,
+		http:    &http.Client{Timeout: defaultTimeout},
+		retries: defaultRetries,
+		backoff: defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GetCurrentBlock returns the last block parsed by the service.
+func (c *Client) GetCurrentBlock(ctx context.Context) (int, error) {
+	var block int
+	err := c.do(ctx, http.MethodGet, "/block", nil, &block)
+	return block, err
+}
+
+// GetTransactions returns the stored transactions of a subscribed address.
+func (c *Client) GetTransactions(ctx context.Context, address string) ([]*domain.Transaction, error) {
+	var txs []*domain.Transaction
+	err := c.do(ctx, http.MethodGet, "/addresses/"+url.PathEscape(address)+"/transactions", nil, &txs)
+	return txs, err
+}
+
+// Subscribe starts watching an address. Subscribing twice is a no-op.
+func (c *Client) Subscribe(ctx context.Context, address string) (*domain.Subscription, error) {
+	var sub domain.Subscription
+	err := c.do(ctx, http.MethodPut, subscriptionPath(address), nil, &sub)
+	return &sub, err
+}
+
+// SetMetadata subscribes an address if needed and replaces its metadata.
+func (c *Client) SetMetadata(ctx context.Context, address string, meta domain.SubscriptionMetadata) (*domain.Subscription, error) {
+	var sub domain.Subscription
+	err := c.do(ctx, http.MethodPut, subscriptionPath(address), meta, &sub)
+	return &sub, err
+}
+
+// Unsubscribe stops watching an address and drops its transactions.
+func (c *Client) Unsubscribe(ctx context.Context, address string) error {
+	return c.do(ctx, http.MethodDelete, subscriptionPath(address), nil, nil)
+}
+
+// Backfill stores the transactions of a subscribed address in the blocks
+// from..to, at most 100 at a time.
+func (c *Client) Backfill(ctx context.Context, address string, from, to int) (*domain.BackfillResult, error) {
+	var result domain.BackfillResult
+	body := map[string]int{"from": from, "to": to}
+	err := c.do(ctx, http.MethodPost, subscriptionPath(address)+"/backfill", body, &result)
+	return &result, err
+}
+
+// Replay publishes the stored transactions of an address that match its
+// current rules again and returns how many did.
+func (c *Client) Replay(ctx context.Context, address string) (int, error) {
+	var result struct {
+		Matched int `json:"matched"`
+	}
+	err := c.do(ctx, http.MethodPost, subscriptionPath(address)+"/replay", nil, &result)
+	return result.Matched, err
+}
+
+// GetBalance returns the ETH balance of a subscribed address after a block,
+// or the current balance for block 0.
+func (c *Client) GetBalance(ctx context.Context, address string, block int) (*domain.Balance, error) {
+	path := "/addresses/" + url.PathEscape(address) + "/balance"
+	if block > 0 {
+		path += "?block=" + strconv.Itoa(block)
+	}
+	var balance domain.Balance
+	err := c.do(ctx, http.MethodGet, path, nil, &balance)
+	return &balance, err
+}
+
+// GetBalanceHistory returns the balance changes of a subscribed address in
+// the blocks from..to, where a zero to is open.
+func (c *Client) GetBalanceHistory(ctx context.Context, address string, from, to int) ([]*domain.BalancePoint, error) {
+	var points []*domain.BalancePoint
+	err := c.do(ctx, http.MethodGet, blockRangePath("/addresses/"+url.PathEscape(address)+"/balance/history", from, to), nil, &points)
+	return points, err
+}
+
+// GetTokenBalances returns the balances of a subscribed address in the
+// tracked ERC-20 tokens.
+func (c *Client) GetTokenBalances(ctx context.Context, address string) ([]*domain.TokenBalance, error) {
+	var balances []*domain.TokenBalance
+	err := c.do(ctx, http.MethodGet, "/addresses/"+url.PathEscape(address)+"/tokens", nil, &balances)
+	return balances, err
+}
+
+// GetTokenBalanceHistory returns the balance changes of a subscribed address
+// in a token in the blocks from..to, where a zero to is open.
+func (c *Client) GetTokenBalanceHistory(ctx context.Context, address, token string, from, to int) ([]*domain.TokenBalancePoint, error) {
+	path := "/addresses/" + url.PathEscape(address) + "/tokens/" + url.PathEscape(token) + "/history"
+	var points []*domain.TokenBalancePoint
+	err := c.do(ctx, http.MethodGet, blockRangePath(path, from, to), nil, &points)
+	return points, err
+}
+
+// blockRangePath adds the from and to blocks set to path.
+func blockRangePath(path string, from, to int) string {
+	query := url.Values{}
+	if from > 0 {
+		query.Set("from", strconv.Itoa(from))
+	}
+	if to > 0 {
+		query.Set("to", strconv.Itoa(to))
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+func (c *Client) GetSubscription(ctx context.Context, address string) (*domain.Subscription, error) {
+	var sub domain.Subscription
+	err := c.do(ctx, http.MethodGet, subscriptionPath(address), nil, &sub)
+	return &sub, err
+}
+
+func (c *Client) ListSubscriptions(ctx context.Context, filter domain.SubscriptionFilter) ([]*domain.Subscription, error) {
+	query := url.Values{}
+	if filter.Tag != "" {
+		query.Set("tag", filter.Tag)
+	}
+	if filter.Owner != "" {
+		query.Set("owner", filter.Owner)
+	}
+
+	path := "/subscriptions"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var subs []*domain.Subscription
+	err := c.do(ctx, http.MethodGet, path, nil, &subs)
+	return subs, err
+}
+
+// ImportSubscriptions subscribes a batch of addresses atomically. When a row
+// is invalid nothing is imported, and the per-row results are returned along
+// with an error matching domain.ErrInvalidImport.
+func (c *Client) ImportSubscriptions(ctx context.Context, rows []*domain.SubscriptionImport) ([]*domain.ImportResult, error) {
+	var results []*domain.ImportResult
+	err := c.do(ctx, http.MethodPost, "/subscriptions/import", rows, &results)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && len(apiErr.Data) > 0 {
+		// the error details are the row results
+		_ = json.Unmarshal(apiErr.Data, &results)
+	}
+	return results, err
+}
+
+func (c *Client) GetRules(ctx context.Context, address string) ([]*domain.Rule, error) {
+	var rules []*domain.Rule
+	err := c.do(ctx, http.MethodGet, subscriptionPath(address)+"/rules", nil, &rules)
+	return rules, err
+}
+
+func (c *Client) SetRules(ctx context.Context, address string, rules []*domain.Rule) error {
+	body := struct {
+		Rules []*domain.Rule `json:"rules"`
+	}{rules}
+	if body.Rules == nil {
+		body.Rules = []*domain.Rule{}
+	}
+	return c.do(ctx, http.MethodPut, subscriptionPath(address)+"/rules", body, nil)
+}
+
+func (c *Client) CreateGroup(ctx context.Context, name string, members []string) (*domain.Group, error) {
+	body := struct {
+		Name    string   `json:"name"`
+		Members []string `json:"members"`
+	}{name, members}
+	var group domain.Group
+	err := c.do(ctx, http.MethodPost, "/groups", body, &group)
+	return &group, err
+}
+
+func (c *Client) DeleteGroup(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, groupPath(name), nil, nil)
+}
+
+func (c *Client) GetGroup(ctx context.Context, name string) (*domain.Group, error) {
+	var group domain.Group
+	err := c.do(ctx, http.MethodGet, groupPath(name), nil, &group)
+	return &group, err
+}
+
+func (c *Client) ListGroups(ctx context.Context) ([]*domain.Group, error) {
+	var groups []*domain.Group
+	err := c.do(ctx, http.MethodGet, "/groups", nil, &groups)
+	return groups, err
+}
+
+func (c *Client) UpdateGroupMembers(ctx context.Context, name string, add, remove []string) (*domain.Group, error) {
+	body := struct {
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}{add, remove}
+	var group domain.Group
+	err := c.do(ctx, http.MethodPost, groupPath(name)+"/members", body, &group)
+	return &group, err
+}
+
+func (c *Client) GetGroupTransactions(ctx context.Context, name string) ([]*domain.GroupTransaction, error) {
+	var txs []*domain.GroupTransaction
+	err := c.do(ctx, http.MethodGet, groupPath(name)+"/transactions", nil, &txs)
+	return txs, err
+}
+
+// CreateAPIKey issues a key for a tenant and returns it with its plaintext.
+// It needs a client with the admin key.
+func (c *Client) CreateAPIKey(ctx context.Context, tenant, name string) (*domain.APIKey, string, error) {
+	body := struct {
+		Tenant string `json:"tenant"`
+		Name   string `json:"name"`
+	}{tenant, name}
+	var key struct {
+		domain.APIKey
+		Key string `json:"key"`
+	}
+	err := c.do(ctx, http.MethodPost, "/admin/keys", body, &key)
+	return &key.APIKey, key.Key, err
+}
+
+func (c *Client) ListAPIKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	var keys []*domain.APIKey
+	err := c.do(ctx, http.MethodGet, "/admin/keys", nil, &keys)
+	return keys, err
+}
+
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/keys/"+url.PathEscape(id), nil, nil)
+}
+
+func subscriptionPath(address string) string {
+	return "/subscriptions/" + url.PathEscape(address)
+}
+
+func groupPath(name string) string {
+	return "/groups/" + url.PathEscape(name)
+}
+
+// do sends a request to a /v1 route, retrying transient failures, and
+// decodes the data of the response into result.
+func (c *Client) do(ctx context.Context, method, path string, body, result any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("request encode error: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, payload)
+		if err == nil {
+			err = decodeResponse(resp, result)
+		}
+
+		wait, retry := c.retryAfter(method, attempt, resp, err)
+		if !retry {
+			return err
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request execute error: %w", err)
+	}
+	return resp, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+apiVersionPrefix+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("http request create error: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.apiKey)
+	}
+	return req, nil
+}
+
+func decodeResponse(resp *http.Response, result any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return readAPIError(resp)
+	}
+	if result == nil {
+		return nil
+	}
+
+	envelope := struct {
+		Data any `json:"data"`
+	}{Data: result}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("http response parse error: %w", err)
+	}
+	return nil
+}
+
+// retryAfter decides whether a failed attempt is retried and how long to
+// wait. Requests that may have reached the service are only retried when they
+// are idempotent, while rate limited requests are always retried.
+func (c *Client) retryAfter(method string, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if err == nil || attempt >= c.retries {
+		return 0, false
+	}
+
+	wait := min(c.backoff<<attempt, maxBackoff)
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Status == http.StatusTooManyRequests:
+		if apiErr.Code == "quota_exceeded" {
+			return 0, false
+		}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(seconds) * time.Second
+		}
+		return wait, true
+	case errors.As(err, &apiErr):
+		transient := apiErr.Status == http.StatusBadGateway ||
+			apiErr.Status == http.StatusServiceUnavailable ||
+			apiErr.Status == http.StatusGatewayTimeout
+		return wait, transient && idempotent(method)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return 0, false
+	default:
+		return wait, idempotent(method)
+	}
+}
+
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
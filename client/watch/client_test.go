@@ -0,0 +1,408 @@
+package watch
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deshev.com/eth-address-watch/domain"
+	api "deshev.com/eth-address-watch/http"
+)
+
+const (
+	address1 = "0x0000000000000000000000000000000000000001"
+	address2 = "0x0000000000000000000000000000000000000002"
+)
+
+type testService struct {
+	bus    *domain.Bus
+	router *api.Router
+	server *httptest.Server
+	client *Client
+}
+
+// newTestService runs the API on top of an in-memory service that is fed
+// blocks directly through the bus.
+func newTestService(t *testing.T, wrap func(http.Handler) http.Handler) *testService {
+	t.Helper()
+
+	log := slog.Default()
+	bus := domain.NewBus(log)
+	service := domain.NewService(log, bus)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = service.Start(ctx)
+	}()
+
+	router := api.NewRouter(log, service)
+	var handler http.Handler = router
+	if wrap != nil {
+		handler = wrap(router)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(func() {
+		server.Close()
+		cancel()
+		<-done
+	})
+
+	return &testService{
+		bus:    bus,
+		router: router,
+		server: server,
+		client: NewClient(server.URL, WithRetries(3, time.Millisecond)),
+	}
+}
+
+func (s *testService) publishBlock(number int, txs ...*domain.Transaction) {
+	s.bus.Publish(&domain.BlockIngested{Block: &domain.Block{NumberParsed: number, Transactions: txs}})
+}
+
+func Test_Client(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, nil)
+	c := s.client
+
+	sub, err := c.Subscribe(ctx, address1)
+	require.NoError(t, err)
+	assert.Equal(t, address1, sub.Address)
+
+	sub, err = c.SetMetadata(ctx, address1, domain.SubscriptionMetadata{Label: "hot", Tags: []string{"exchange"}})
+	require.NoError(t, err)
+	assert.Equal(t, "hot", sub.Label)
+
+	// subscribing again keeps the metadata
+	sub, err = c.Subscribe(ctx, address1)
+	require.NoError(t, err)
+	assert.Equal(t, "hot", sub.Label)
+
+	subs, err := c.ListSubscriptions(ctx, domain.SubscriptionFilter{Tag: "exchange"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(subs))
+	subs, err = c.ListSubscriptions(ctx, domain.SubscriptionFilter{Tag: "other"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(subs))
+
+	require.NoError(t, c.SetRules(ctx, address1, []*domain.Rule{{Direction: domain.DirectionIn}}))
+	rules, err := c.GetRules(ctx, address1)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DirectionIn, rules[0].Direction)
+
+	err = c.SetRules(ctx, address1, []*domain.Rule{{Expression: "value >"}})
+	assert.ErrorIs(t, err, domain.ErrInvalidRule)
+	_, err = c.GetTransactions(ctx, address2)
+	assert.NoError(t, err)
+	_, err = c.GetRules(ctx, address2)
+	assert.ErrorIs(t, err, domain.ErrNotSubscribed)
+
+	group, err := c.CreateGroup(ctx, "wallets", []string{address1, address2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{address1, address2}, group.Members)
+	_, err = c.CreateGroup(ctx, "wallets", nil)
+	assert.ErrorIs(t, err, domain.ErrGroupExists)
+	group, err = c.UpdateGroupMembers(ctx, "wallets", nil, []string{address2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{address1}, group.Members)
+
+	s.publishBlock(1, &domain.Transaction{Hash: "0xa", BlockNumber: "0x1", From: address2, To: address1})
+	assert.Eventually(t, func() bool {
+		block, err := c.GetCurrentBlock(ctx)
+		return err == nil && block == 1
+	}, time.Second, time.Millisecond)
+
+	txs, err := c.GetTransactions(ctx, address1)
+	require.NoError(t, err)
+	assert.Equal(t, "0xa", txs[0].Hash)
+	groupTxs, err := c.GetGroupTransactions(ctx, "wallets")
+	require.NoError(t, err)
+	assert.Equal(t, "0xa", groupTxs[0].Hash)
+
+	groups, err := c.ListGroups(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(groups))
+	require.NoError(t, c.DeleteGroup(ctx, "wallets"))
+	_, err = c.GetGroup(ctx, "wallets")
+	assert.ErrorIs(t, err, domain.ErrGroupNotFound)
+
+	require.NoError(t, c.Unsubscribe(ctx, address1))
+	assert.ErrorIs(t, c.Unsubscribe(ctx, address1), domain.ErrNotSubscribed)
+}
+
+func Test_ClientReplay(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, nil)
+	c := s.client
+
+	_, err := c.Subscribe(ctx, address1)
+	require.NoError(t, err)
+	s.publishBlock(1, &domain.Transaction{Hash: "0xa", BlockNumber: "0x1", From: address2, To: address1})
+	assert.Eventually(t, func() bool {
+		txs, err := c.GetTransactions(ctx, address1)
+		return err == nil && len(txs) == 1
+	}, time.Second, time.Millisecond)
+
+	matched, err := c.Replay(ctx, address1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, matched)
+	_, err = c.Replay(ctx, address2)
+	assert.ErrorIs(t, err, domain.ErrNotSubscribed)
+
+	// the test service has no node to fetch past blocks from
+	_, err = c.Backfill(ctx, address1, 0, 1)
+	assert.ErrorIs(t, err, domain.ErrBackfillUnavailable)
+	// nor does it track balances
+	_, err = c.GetBalance(ctx, address1, 0)
+	assert.ErrorIs(t, err, domain.ErrBalanceUnavailable)
+	_, err = c.GetBalanceHistory(ctx, address1, 1, 2)
+	assert.ErrorIs(t, err, domain.ErrBalanceUnavailable)
+	_, err = c.GetTokenBalances(ctx, address1)
+	assert.ErrorIs(t, err, domain.ErrBalanceUnavailable)
+	_, err = c.GetTokenBalanceHistory(ctx, address1, "0xdac17f958d2ee523a2206206994597c13d831ec7", 1, 0)
+	assert.ErrorIs(t, err, domain.ErrBalanceUnavailable)
+}
+
+func Test_ClientExport(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, nil)
+	c := s.client
+
+	_, err := c.CreateGroup(ctx, "wallets", []string{address1})
+	require.NoError(t, err)
+	s.publishBlock(1, &domain.Transaction{Hash: "0xa", BlockNumber: "0x1", From: address2, To: address1, Value: "0x1"})
+	assert.Eventually(t, func() bool {
+		txs, err := c.GetTransactions(ctx, address1)
+		return err == nil && len(txs) == 1
+	}, time.Second, time.Millisecond)
+
+	body, err := c.Export(ctx, ExportQuery{Group: "wallets", Format: "ndjson"})
+	require.NoError(t, err)
+	rows, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	assert.Contains(t, string(rows), `"hash":"0xa"`)
+
+	// the block time of the transaction is unknown, so no range includes it
+	body, err = c.Export(ctx, ExportQuery{Address: address1, From: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+	rows, err = io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	assert.Equal(t, 1, strings.Count(string(rows), "\n"))
+
+	_, err = c.Export(ctx, ExportQuery{Address: address2})
+	assert.ErrorIs(t, err, domain.ErrNotSubscribed)
+	_, err = c.Export(ctx, ExportQuery{})
+	assert.Error(t, err)
+}
+
+func Test_ClientImport(t *testing.T) {
+	ctx := context.Background()
+	c := newTestService(t, nil).client
+
+	results, err := c.ImportSubscriptions(ctx, []*domain.SubscriptionImport{
+		{Address: address1},
+		{Address: "not-an-address"},
+	})
+	assert.ErrorIs(t, err, domain.ErrInvalidImport)
+	assert.Equal(t, 2, len(results))
+	assert.NotEmpty(t, results[1].Error)
+
+	results, err = c.ImportSubscriptions(ctx, []*domain.SubscriptionImport{{Address: address1}})
+	require.NoError(t, err)
+	assert.True(t, results[0].Created)
+}
+
+func Test_ClientAuth(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, nil)
+	s.router.EnableAuth("admin-secret")
+
+	_, err := s.client.GetCurrentBlock(ctx)
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+
+	admin := NewClient(s.server.URL, WithAPIKey("admin-secret"))
+	key, plaintext, err := admin.CreateAPIKey(ctx, "team-a", "ci")
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", key.Tenant)
+
+	tenant := NewClient(s.server.URL, WithAPIKey(plaintext))
+	_, err = tenant.Subscribe(ctx, address1)
+	require.NoError(t, err)
+
+	keys, err := admin.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(keys))
+	require.NoError(t, admin.RevokeAPIKey(ctx, key.ID))
+	_, err = tenant.GetTransactions(ctx, address1)
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+}
+
+// failFirst replies with the status to the first n requests.
+func failFirst(n int32, status int, attempts *atomic.Int32) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if attempts.Add(1) <= n {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(status)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func Test_ClientRetries(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	c := newTestService(t, failFirst(2, http.StatusServiceUnavailable, &attempts)).client
+	_, err := c.GetCurrentBlock(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), attempts.Load())
+
+	// requests that are not idempotent might have been applied
+	attempts.Store(0)
+	_, err = c.CreateGroup(ctx, "wallets", nil)
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.Status)
+	assert.Equal(t, int32(1), attempts.Load())
+
+	// unless they were rate limited
+	attempts.Store(0)
+	c = newTestService(t, failFirst(1, http.StatusTooManyRequests, &attempts)).client
+	_, err = c.CreateGroup(ctx, "wallets", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), attempts.Load())
+
+	// and give up after the configured retries
+	attempts.Store(0)
+	c = newTestService(t, failFirst(10, http.StatusBadGateway, &attempts)).client
+	_, err = c.GetCurrentBlock(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, int32(4), attempts.Load())
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.GetCurrentBlock(canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_Stream(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, nil)
+	c := s.client
+
+	_, err := c.Listen(ctx, address1, 0)
+	assert.ErrorIs(t, err, domain.ErrNotSubscribed)
+
+	_, err = c.Subscribe(ctx, address1)
+	require.NoError(t, err)
+	s.publishBlock(1, &domain.Transaction{Hash: "0xa", From: address2, To: address1})
+	assert.Eventually(t, func() bool {
+		txs, err := c.GetTransactions(ctx, address1)
+		return err == nil && len(txs) == 1
+	}, time.Second, time.Millisecond)
+
+	stream, err := c.Listen(ctx, address1, 0)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	// the stored transaction comes first
+	n, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n.ID)
+	assert.Equal(t, "0xa", n.Transaction.Hash)
+
+	s.publishBlock(2, &domain.Transaction{Hash: "0xb", From: address1, To: address2})
+	n, err = stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n.ID)
+	assert.Equal(t, "0xb", n.Transaction.Hash)
+
+	// after a dropped connection the stream resumes after the last ID
+	s.server.CloseClientConnections()
+	s.publishBlock(3, &domain.Transaction{Hash: "0xc", From: address1, To: address2})
+	n, err = stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 3, n.ID)
+	assert.Equal(t, "0xc", n.Transaction.Hash)
+	assert.Equal(t, 3, stream.LastID())
+
+	require.NoError(t, stream.Close())
+	_, err = stream.Next()
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_Stream_SkipsNameEvents(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, nil)
+	c := s.client
+
+	_, err := c.Subscribe(ctx, address1)
+	require.NoError(t, err)
+	s.publishBlock(1, &domain.Transaction{Hash: "0xa", From: address2, To: address1})
+	assert.Eventually(t, func() bool {
+		txs, err := c.GetTransactions(ctx, address1)
+		return err == nil && len(txs) == 1
+	}, time.Second, time.Millisecond)
+
+	stream, err := c.Listen(ctx, address1, 0)
+	require.NoError(t, err)
+	defer stream.Close()
+	n, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n.ID)
+
+	// name events have no ID, so the stream still resumes after the last
+	// transaction
+	s.bus.Publish(&domain.NameChanged{Tenant: domain.DefaultTenant, Name: "alice.eth", Address: address2, Previous: address1})
+	s.publishBlock(2, &domain.Transaction{Hash: "0xb", From: address1, To: address2})
+	n, err = stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n.ID)
+	assert.Equal(t, "0xb", n.Transaction.Hash)
+
+	s.server.CloseClientConnections()
+	s.publishBlock(3, &domain.Transaction{Hash: "0xc", From: address1, To: address2})
+	n, err = stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 3, n.ID)
+	assert.Equal(t, "0xc", n.Transaction.Hash)
+}
+
+func Test_Stream_CloseFromAnotherGoroutine(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, nil)
+	c := s.client
+
+	_, err := c.Subscribe(ctx, address1)
+	require.NoError(t, err)
+	stream, err := c.Listen(ctx, address1, 0)
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, err := stream.Next()
+		done <- err
+	}()
+	require.NoError(t, stream.Close())
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("Next did not return after Close")
+	}
+}
@@ -0,0 +1,76 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const maxErrorBodySize = 1 << 20
+
+// APIError is an error response of the service. Errors with a code of a
+// domain error match it with errors.Is, like domain.ErrNotSubscribed.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	// Data has error specific details, like the row results of an import
+	Data json.RawMessage
+}
+
+var domainErrors = map[string]error{
+	"unauthorized":         domain.ErrUnauthorized,
+	"quota_exceeded":       domain.ErrQuotaExceeded,
+	"not_subscribed":       domain.ErrNotSubscribed,
+	"group_not_found":      domain.ErrGroupNotFound,
+	"group_exists":         domain.ErrGroupExists,
+	"invalid_group":        domain.ErrInvalidGroup,
+	"invalid_rule":         domain.ErrInvalidRule,
+	"invalid_import":       domain.ErrInvalidImport,
+	"invalid_tenant":       domain.ErrInvalidTenant,
+	"api_key_not_found":    domain.ErrAPIKeyNotFound,
+	"invalid_range":        domain.ErrInvalidRange,
+	"backfill_unavailable": domain.ErrBackfillUnavailable,
+	"node_unavailable":     domain.ErrNodeUnavailable,
+	"balance_unavailable":  domain.ErrBalanceUnavailable,
+	"balance_pending":      domain.ErrBalancePending,
+	"unknown_token":        domain.ErrUnknownToken,
+	"name_not_found":       domain.ErrNameNotFound,
+	"names_unavailable":    domain.ErrNamesUnavailable,
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("eth-address-watch: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return domainErrors[e.Code]
+}
+
+func readAPIError(resp *http.Response) error {
+	var problem struct {
+		Title  string          `json:"title"`
+		Detail string          `json:"detail"`
+		Code   string          `json:"code"`
+		Data   json.RawMessage `json:"data"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err := json.Unmarshal(body, &problem); err != nil {
+		// not from the service, like a proxy error page
+		problem.Detail = http.StatusText(resp.StatusCode)
+	}
+
+	apiErr := &APIError{
+		Status:  resp.StatusCode,
+		Code:    problem.Code,
+		Message: problem.Detail,
+		Data:    problem.Data,
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = problem.Title
+	}
+	return apiErr
+}
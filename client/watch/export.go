@@ -0,0 +1,86 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ExportQuery selects the transactions to export, of an address or of the
+// members of a group. Zero times are open bounds.
+type ExportQuery struct {
+	Address string
+	Group   string
+	// Format is csv, ndjson or parquet, csv when empty
+	Format string
+	// From is included and To excluded
+	From time.Time
+	To   time.Time
+}
+
+// Export streams the transactions of an address or group in an export
+// format. The caller reads the rows from the returned body and closes it.
+// Exports are not bound by the client timeout, use ctx to cancel them.
+func (c *Client) Export(ctx context.Context, q ExportQuery) (io.ReadCloser, error) {
+	var path string
+	switch {
+	case q.Address != "" && q.Group == "":
+		path = "/addresses/" + url.PathEscape(q.Address) + "/transactions/export"
+	case q.Group != "" && q.Address == "":
+		path = groupPath(q.Group) + "/transactions/export"
+	default:
+		return nil, errors.New("export needs either an address or a group")
+	}
+	query := url.Values{}
+	if q.Format != "" {
+		query.Set("format", q.Format)
+	}
+	if !q.From.IsZero() {
+		query.Set("from", q.From.Format(time.RFC3339))
+	}
+	if !q.To.IsZero() {
+		query.Set("to", q.To.Format(time.RFC3339))
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	// the client timeout would cut long exports short
+	exportClient := *c.http
+	exportClient.Timeout = 0
+
+	for attempt := 0; ; attempt++ {
+		body, resp, err := c.openExport(ctx, &exportClient, path)
+		if err == nil {
+			return body, nil
+		}
+
+		wait, retry := c.retryAfter(http.MethodGet, attempt, resp, err)
+		if !retry {
+			return nil, err
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) openExport(ctx context.Context, client *http.Client, path string) (io.ReadCloser, *http.Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("http request execute error: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, resp, readAPIError(resp)
+	}
+	return resp.Body, resp, nil
+}
@@ -0,0 +1,236 @@
+package watch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+// transactionEvent is the type of the events of matched transactions.
+const transactionEvent = "transaction"
+
+// Stream is a live feed of the matched transactions of an address. It
+// reconnects when the connection drops, including when the service
+// disconnects a reader that fell behind, and resumes after the last received
+// transaction, so nothing is missed or repeated. Other events, like the name
+// events of subscriptions that follow ENS names, are skipped.
+type Stream struct {
+	client  *Client
+	http    *http.Client
+	ctx     context.Context
+	cancel  context.CancelFunc
+	address string
+	lastID  int
+
+	body   io.ReadCloser
+	reader *bufio.Reader
+	// drops counts connections lost in a row without an event
+	drops int
+}
+
+// Listen streams the transactions of a subscribed address after lastID, the
+// ID of the last transaction seen. Pass 0 to start with every stored
+// transaction.
+func (c *Client) Listen(ctx context.Context, address string, lastID int) (*Stream, error) {
+	// the client timeout would end the stream
+	streamClient := *c.http
+	streamClient.Timeout = 0
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Stream{
+		client:  c,
+		http:    &streamClient,
+		ctx:     ctx,
+		cancel:  cancel,
+		address: address,
+		lastID:  lastID,
+	}
+	if err := s.connect(); err != nil {
+		cancel()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Next blocks until the next transaction arrives. It is not safe for
+// concurrent use, but Close can be called from another goroutine to stop it.
+func (s *Stream) Next() (*domain.TransactionMatched, error) {
+	for {
+		if s.ctx.Err() != nil {
+			s.disconnect()
+			return nil, s.ctx.Err()
+		}
+		if s.reader == nil {
+			if err := s.connect(); err != nil {
+				return nil, err
+			}
+		}
+
+		n, id, err := s.readEvent()
+		if err == nil {
+			if id != "" {
+				s.lastID = n.ID
+			}
+			s.drops = 0
+			return n, nil
+		}
+		if s.ctx.Err() != nil {
+			continue
+		}
+		var parseErr *eventError
+		if errors.As(err, &parseErr) {
+			return nil, err
+		}
+
+		s.disconnect()
+		if s.drops >= s.client.retries {
+			return nil, fmt.Errorf("stream read error: %w", err)
+		}
+		if err := sleep(s.ctx, min(s.client.backoff<<s.drops, maxBackoff)); err != nil {
+			return nil, err
+		}
+		s.drops++
+	}
+}
+
+// LastID is the ID of the last received transaction, to resume from later.
+func (s *Stream) LastID() int {
+	return s.lastID
+}
+
+// Close stops the stream. It only cancels the context of the connection,
+// which ends a Next blocked on it, and Next closes the connection.
+func (s *Stream) Close() error {
+	s.cancel()
+	return nil
+}
+
+func (s *Stream) disconnect() {
+	if s.body != nil {
+		s.body.Close()
+	}
+	s.body = nil
+	s.reader = nil
+}
+
+// connect opens the event stream, retrying like other requests.
+func (s *Stream) connect() error {
+	path := "/addresses/" + url.PathEscape(s.address) + "/stream"
+	for attempt := 0; ; attempt++ {
+		err := s.open(path)
+		if err == nil {
+			return nil
+		}
+
+		wait, retry := s.client.retryAfter(http.MethodGet, attempt, s.lastResponse(err), err)
+		if !retry {
+			return err
+		}
+		if err := sleep(s.ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Stream) open(path string) error {
+	req, err := s.client.newRequest(s.ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if s.lastID > 0 {
+		req.Header.Set(lastEventIDHeader, strconv.Itoa(s.lastID))
+	}
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("http request execute error: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return &connectError{resp: resp, err: readAPIError(resp)}
+	}
+
+	s.body = resp.Body
+	s.reader = bufio.NewReader(resp.Body)
+	return nil
+}
+
+// connectError keeps the response of a failed connection for its
+// Retry-After header.
+type connectError struct {
+	resp *http.Response
+	err  error
+}
+
+func (e *connectError) Error() string { return e.err.Error() }
+func (e *connectError) Unwrap() error { return e.err }
+
+func (s *Stream) lastResponse(err error) *http.Response {
+	var connErr *connectError
+	if errors.As(err, &connErr) {
+		return connErr.resp
+	}
+	return nil
+}
+
+// eventError is a malformed event, which reconnecting does not fix.
+type eventError struct {
+	err error
+}
+
+func (e *eventError) Error() string { return "stream event parse error: " + e.err.Error() }
+func (e *eventError) Unwrap() error { return e.err }
+
+// readEvent reads Server-Sent Events until a transaction and returns it with
+// its event ID, skipping comments like the heartbeats of the service and
+// events of other types.
+func (s *Stream) readEvent() (*domain.TransactionMatched, string, error) {
+	var data strings.Builder
+	event, id := "", ""
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if data.Len() == 0 || (event != "" && event != transactionEvent) {
+				data.Reset()
+				event, id = "", ""
+				continue
+			}
+			var n domain.TransactionMatched
+			if err := json.Unmarshal([]byte(data.String()), &n); err != nil {
+				return nil, "", &eventError{err: err}
+			}
+			return &n, id, nil
+		case strings.HasPrefix(line, ":"):
+			// comment
+		case strings.HasPrefix(line, "event:"):
+			event = fieldValue(line, "event:")
+		case strings.HasPrefix(line, "id:"):
+			id = fieldValue(line, "id:")
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(fieldValue(line, "data:"))
+		}
+	}
+}
+
+func fieldValue(line, field string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(line, field), " ")
+}
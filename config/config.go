@@ -1,33 +1,110 @@
 package config
 
 import (
-	"os"
-	"strconv"
+	"fmt"
 	"time"
 )
 
 type Config struct {
 	EthNodeURL        string
 	EthRequestTimeout time.Duration
-}
+	// AdminAPIKey enables API key authentication when set
+	AdminAPIKey string
 
-func New() *Config {
-	ethRequestConfig := getEnv("ETH_REQUEST_TIMEOUT", "2000")
-	ethRequestMs, err := strconv.ParseInt(ethRequestConfig, 10, 64)
-	if err != nil {
-		ethRequestMs = 2000
-	}
+	HTTPPort    int
+	HTTPTimeout time.Duration
+	GRPCPort    int
+	// StreamHeartbeatInterval is how often idle streams get a keep-alive
+	StreamHeartbeatInterval time.Duration
+
+	// the watcher asks the node for new blocks every BlockTickInterval and
+	// gives up on a tick after TickTimeout
+	BlockTickInterval time.Duration
+	TickTimeout       time.Duration
+	// BlockBufferSize is the number of ingested blocks queued for the service
+	BlockBufferSize int
+
+	// RateLimitRPS is the request rate allowed per API key, 0 disables limiting
+	RateLimitRPS   float64
+	RateLimitBurst int
+	// per-tenant caps, 0 means unlimited
+	MaxSubscriptions int
+	MaxTransactions  int
+
+	// GraphQL queries over these limits are rejected before they run
+	GraphQLMaxComplexity int
+	GraphQLMaxDepth      int
+
+	// ValidateRequests rejects request bodies that do not match the OpenAPI spec
+	ValidateRequests bool
+
+	// /readyz fails after the watcher has not advanced for ReadyMaxStaleTicks
+	// ticks in a row, or when the node head is more than ReadyMaxBlockLag
+	// blocks ahead of the last processed block
+	ReadyMaxStaleTicks int
+	ReadyMaxBlockLag   int
+
+	// BalanceReconcileInterval is how often tracked balances are checked
+	// against the node
+	BalanceReconcileInterval time.Duration
+	// TokenContracts are the ERC-20 contracts whose balances are tracked
+	TokenContracts []string
+
+	// ENS names of subscriptions are resolved again every ENSResolveInterval,
+	// and primary names of addresses are cached for ENSCacheTTL
+	ENSResolveInterval time.Duration
+	ENSCacheTTL        time.Duration
+
+	// OTLPEndpoint is the OTLP/HTTP collector URL traces are exported to,
+	// empty disables export
+	OTLPEndpoint string
+
+	// the CLI commands that talk to a running instance send requests to
+	// APIURL, see InstanceURL, with the tenant key APIKey
+	APIURL string
+	APIKey string
 
+	// sources has where each value set by Load came from, by field key
+	sources map[string]string
+}
+
+// Default is the configuration used for anything not set in the config
+// file, the environment or flags.
+func Default() *Config {
 	return &Config{
-		EthNodeURL:        getEnv("ETH_NODE_URL", "https://cloudflare-eth.com"),
-		EthRequestTimeout: time.Duration(ethRequestMs) * time.Millisecond,
+		EthNodeURL:        "https://cloudflare-eth.com",
+		EthRequestTimeout: 2 * time.Second,
+
+		HTTPPort:                9000,
+		HTTPTimeout:             10 * time.Second,
+		GRPCPort:                9090,
+		StreamHeartbeatInterval: 15 * time.Second,
+
+		BlockTickInterval: 10 * time.Second,
+		TickTimeout:       5 * time.Second,
+		BlockBufferSize:   10,
+
+		RateLimitBurst: 20,
+
+		GraphQLMaxComplexity: 1000,
+		GraphQLMaxDepth:      8,
+
+		ReadyMaxStaleTicks: 3,
+		ReadyMaxBlockLag:   10,
+
+		BalanceReconcileInterval: 10 * time.Minute,
+
+		ENSResolveInterval: 10 * time.Minute,
+		ENSCacheTTL:        time.Hour,
+
+		sources: map[string]string{},
 	}
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// InstanceURL is APIURL, or the HTTP port on localhost when it is not set.
+func (c *Config) InstanceURL() string {
+	if c.APIURL != "" {
+		return c.APIURL
 	}
-	return value
+	return fmt.Sprintf("http://localhost:%d", c.HTTPPort)
 }
@@ -0,0 +1,311 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigFileEnv names the config file when the --config flag is not set.
+	ConfigFileEnv = "CONFIG_FILE"
+
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// field is a tunable with its key in config files, which is also the flag
+// name with dashes for underscores, and its environment variable. Live fields
+// can change on reload, the others are only read at startup.
+type field struct {
+	key    string
+	env    string
+	usage  string
+	value  value
+	redact func(string) string
+	live   bool
+}
+
+func (f *field) flagName() string {
+	return strings.ReplaceAll(f.key, "_", "-")
+}
+
+// fields lists every tunable of c in the order they are printed.
+func (c *Config) fields() []*field {
+	return []*field{
+		{key: "eth_node_url", env: "ETH_NODE_URL", usage: "Ethereum node JSON-RPC URL", value: (*stringValue)(&c.EthNodeURL), redact: redactURL, live: true},
+		{key: "eth_request_timeout", env: "ETH_REQUEST_TIMEOUT", usage: "timeout of node requests", value: (*durationValue)(&c.EthRequestTimeout), live: true},
+		{key: "admin_api_key", env: "ADMIN_API_KEY", usage: "admin key, enables API key authentication", value: (*stringValue)(&c.AdminAPIKey), redact: redactSecret},
+		{key: "http_port", env: "HTTP_PORT", usage: "HTTP API port", value: (*intValue)(&c.HTTPPort)},
+		{key: "http_timeout", env: "HTTP_TIMEOUT", usage: "HTTP read and write timeout", value: (*durationValue)(&c.HTTPTimeout)},
+		{key: "grpc_port", env: "GRPC_PORT", usage: "gRPC API port", value: (*intValue)(&c.GRPCPort)},
+		{key: "stream_heartbeat_interval", env: "STREAM_HEARTBEAT_INTERVAL", usage: "keep-alive interval of idle streams", value: (*durationValue)(&c.StreamHeartbeatInterval)},
+		{key: "block_tick_interval", env: "BLOCK_TICK_INTERVAL", usage: "how often the watcher polls the node", value: (*durationValue)(&c.BlockTickInterval), live: true},
+		{key: "tick_timeout", env: "TICK_TIMEOUT", usage: "time limit of a watcher poll", value: (*durationValue)(&c.TickTimeout), live: true},
+		{key: "block_buffer_size", env: "BLOCK_BUFFER_SIZE", usage: "ingested blocks queued for the service", value: (*intValue)(&c.BlockBufferSize)},
+		{key: "rate_limit_rps", env: "RATE_LIMIT_RPS", usage: "requests per second per API key, 0 disables limiting", value: (*floatValue)(&c.RateLimitRPS)},
+		{key: "rate_limit_burst", env: "RATE_LIMIT_BURST", usage: "request burst per API key", value: (*intValue)(&c.RateLimitBurst)},
+		{key: "max_subscriptions_per_tenant", env: "MAX_SUBSCRIPTIONS_PER_TENANT", usage: "subscriptions per tenant, 0 is unlimited", value: (*intValue)(&c.MaxSubscriptions), live: true},
+		{key: "max_transactions_per_tenant", env: "MAX_TRANSACTIONS_PER_TENANT", usage: "stored transactions per tenant, 0 is unlimited", value: (*intValue)(&c.MaxTransactions), live: true},
+		{key: "graphql_max_complexity", env: "GRAPHQL_MAX_COMPLEXITY", usage: "estimated cost limit of GraphQL queries", value: (*intValue)(&c.GraphQLMaxComplexity)},
+		{key: "graphql_max_depth", env: "GRAPHQL_MAX_DEPTH", usage: "nesting limit of GraphQL queries", value: (*intValue)(&c.GraphQLMaxDepth)},
+		{key: "validate_requests", env: "VALIDATE_REQUESTS", usage: "reject request bodies not matching the OpenAPI spec", value: (*boolValue)(&c.ValidateRequests)},
+		{key: "ready_max_stale_ticks", env: "READY_MAX_STALE_TICKS", usage: "ticks without a new block before /readyz fails, 0 disables the check", value: (*intValue)(&c.ReadyMaxStaleTicks), live: true},
+		{key: "ready_max_block_lag", env: "READY_MAX_BLOCK_LAG", usage: "blocks behind the node head before /readyz fails, 0 disables the check", value: (*intValue)(&c.ReadyMaxBlockLag), live: true},
+		{key: "balance_reconcile_interval", env: "BALANCE_RECONCILE_INTERVAL", usage: "how often tracked balances are checked against the node", value: (*durationValue)(&c.BalanceReconcileInterval), live: true},
+		{key: "token_contracts", env: "TOKEN_CONTRACTS", usage: "comma separated ERC-20 contracts whose balances are tracked", value: (*listValue)(&c.TokenContracts), live: true},
+		{key: "ens_resolve_interval", env: "ENS_RESOLVE_INTERVAL", usage: "how often ENS names of subscriptions are resolved again", value: (*durationValue)(&c.ENSResolveInterval), live: true},
+		{key: "ens_cache_ttl", env: "ENS_CACHE_TTL", usage: "how long primary ENS names of addresses are cached", value: (*durationValue)(&c.ENSCacheTTL), live: true},
+		{key: "otlp_endpoint", env: "OTEL_EXPORTER_OTLP_ENDPOINT", usage: "OTLP/HTTP collector URL for traces", value: (*stringValue)(&c.OTLPEndpoint), redact: redactUserInfo},
+		{key: "api_url", env: "API_URL", usage: "URL of the running instance CLI commands talk to, defaults to localhost on http_port", value: (*stringValue)(&c.APIURL), redact: redactUserInfo, live: true},
+		{key: "api_key", env: "API_KEY", usage: "tenant API key of CLI commands", value: (*stringValue)(&c.APIKey), redact: redactSecret, live: true},
+	}
+}
+
+// Load layers the config file, then environment variables, then flags over
+// the defaults and validates the result. The config file is named by the
+// --config flag or CONFIG_FILE, and is YAML or TOML depending on its
+// extension. Every invalid value is reported in the returned error, not only
+// the first.
+func Load(args []string) (*Config, error) {
+	fs := NewFlagSet("eth-address-watch")
+	load := Bind(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err //nolint:wrapcheck // flag errors name the flag
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return load()
+}
+
+// NewFlagSet returns a flag set that reports errors instead of printing them.
+func NewFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// Bind defines the config flags on fs, for commands with flags of their own.
+// Once fs is parsed, the returned function loads the configuration like Load.
+func Bind(fs *flag.FlagSet) func() (*Config, error) {
+	c := Default()
+	fields := c.fields()
+
+	configFile := fs.String("config", os.Getenv(ConfigFileEnv), "YAML or TOML config file")
+	flagValues := map[*field]string{}
+	for _, f := range fields {
+		set := func(s string) error {
+			flagValues[f] = s
+			return nil
+		}
+		if _, isBool := f.value.(*boolValue); isBool {
+			fs.BoolFunc(f.flagName(), f.usage, set)
+		} else {
+			fs.Func(f.flagName(), f.usage, set)
+		}
+	}
+
+	return func() (*Config, error) {
+		errs := []error{}
+		if *configFile != "" {
+			errs = append(errs, c.loadFile(*configFile)...)
+		}
+		for _, f := range fields {
+			if value := os.Getenv(f.env); value != "" {
+				errs = append(errs, c.set(f, value, SourceEnv, "environment variable "+f.env))
+			}
+		}
+		for _, f := range fields {
+			if value, exists := flagValues[f]; exists {
+				errs = append(errs, c.set(f, value, SourceFlag, "flag --"+f.flagName()))
+			}
+		}
+		errs = append(errs, c.Validate())
+
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+}
+
+// Usage writes the flags Load accepts.
+func Usage(w io.Writer) {
+	fmt.Fprintf(w, "  --config string\n\tYAML or TOML config file (env %s)\n", ConfigFileEnv)
+	for _, f := range Default().fields() {
+		fmt.Fprintf(w, "  --%s %s\n\t%s (env %s, default %s)\n", f.flagName(), f.value.kind(), f.usage, f.env, f.value)
+	}
+}
+
+func (c *Config) set(f *field, value, source, name string) error {
+	if err := f.value.Set(value); err != nil {
+		return fmt.Errorf("%s: invalid value %q: %w", name, value, err)
+	}
+	c.sources[f.key] = source
+	return nil
+}
+
+func (c *Config) loadFile(path string) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("config file read error: %w", err)}
+	}
+
+	values := map[string]any{}
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		err = fmt.Errorf("unsupported extension %q, use .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return []error{fmt.Errorf("config file %s: %w", path, err)}
+	}
+
+	byKey := map[string]*field{}
+	for _, f := range c.fields() {
+		byKey[f.key] = f
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	errs := []error{}
+	for _, key := range keys {
+		value := values[key]
+		f, exists := byKey[key]
+		if !exists {
+			errs = append(errs, fmt.Errorf("config file %s: unknown key %q", path, key))
+			continue
+		}
+		switch value := value.(type) {
+		case map[string]any:
+			errs = append(errs, fmt.Errorf("config file %s: %s must be a single value", path, key))
+			continue
+		case []any:
+			if _, isList := f.value.(*listValue); !isList {
+				errs = append(errs, fmt.Errorf("config file %s: %s must be a single value", path, key))
+				continue
+			}
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			errs = append(errs, c.set(f, strings.Join(items, ","), SourceFile, "config file "+path+": "+key))
+			continue
+		}
+		errs = append(errs, c.set(f, fmt.Sprint(value), SourceFile, "config file "+path+": "+key))
+	}
+	return errs
+}
+
+// value is a flag.Value also naming its type for the usage.
+type value interface {
+	flag.Value
+	kind() string
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string { return string(*v) }
+func (v *stringValue) kind() string   { return "string" }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return errors.New("not an integer")
+	}
+	*v = intValue(i)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+func (v *intValue) kind() string   { return "int" }
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return errors.New("not a number")
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+func (v *floatValue) kind() string   { return "float" }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return errors.New("not true or false")
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) kind() string   { return "bool" }
+
+// durationValue takes Go durations like "1m30s". Plain numbers are
+// milliseconds, as ETH_REQUEST_TIMEOUT has always been.
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	if ms, err := strconv.Atoi(s); err == nil {
+		*v = durationValue(time.Duration(ms) * time.Millisecond)
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.New(`not a duration like "500ms" or "10s"`)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+func (v *durationValue) kind() string   { return "duration" }
+
+// listValue takes comma separated items, also as a list in config files.
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*v = items
+	return nil
+}
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+func (v *listValue) kind() string   { return "list" }
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func Test_Load_Defaults(t *testing.T) {
+	c, err := Load(nil)
+	require.NoError(t, err)
+
+	want := Default()
+	assert.Equal(t, want, c)
+	assert.Equal(t, SourceDefault, c.Source("http_port"))
+}
+
+func Test_Load_Layers(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", `
+eth_node_url: https://node.example.com
+http_port: 8000
+grpc_port: 8001
+block_tick_interval: 12s
+rate_limit_rps: 2.5
+validate_requests: true
+token_contracts:
+  - "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+  - "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"
+`)
+	t.Setenv("GRPC_PORT", "8101")
+	t.Setenv("BLOCK_TICK_INTERVAL", "15s")
+	t.Setenv("ETH_REQUEST_TIMEOUT", "3000")
+
+	c, err := Load([]string{"--config", yamlFile, "--block-tick-interval", "20s", "--validate-requests=false"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://node.example.com", c.EthNodeURL)
+	assert.Equal(t, 8000, c.HTTPPort)
+	assert.Equal(t, 8101, c.GRPCPort)
+	assert.Equal(t, 20*time.Second, c.BlockTickInterval)
+	assert.Equal(t, 3*time.Second, c.EthRequestTimeout)
+	assert.Equal(t, 2.5, c.RateLimitRPS)
+	assert.False(t, c.ValidateRequests)
+	assert.Equal(t, 5*time.Second, c.TickTimeout)
+	assert.Equal(t, []string{"0xdAC17F958D2ee523a2206206994597C13D831ec7", "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"}, c.TokenContracts)
+
+	assert.Equal(t, SourceFile, c.Source("http_port"))
+	assert.Equal(t, SourceEnv, c.Source("grpc_port"))
+	assert.Equal(t, SourceFlag, c.Source("block_tick_interval"))
+	assert.Equal(t, SourceDefault, c.Source("tick_timeout"))
+}
+
+func Test_Load_TOML(t *testing.T) {
+	tomlFile := writeFile(t, "config.toml", `
+http_port = 8000
+tick_timeout = "2s"
+max_subscriptions_per_tenant = 50
+token_contracts = "0xdAC17F958D2ee523a2206206994597C13D831ec7, "
+`)
+	t.Setenv(ConfigFileEnv, tomlFile)
+
+	c, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 8000, c.HTTPPort)
+	assert.Equal(t, 2*time.Second, c.TickTimeout)
+	assert.Equal(t, 50, c.MaxSubscriptions)
+	assert.Equal(t, []string{"0xdAC17F958D2ee523a2206206994597C13D831ec7"}, c.TokenContracts)
+}
+
+func Test_Load_Errors(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", `
+http_port: eighty
+unknown: 1
+block_buffer_size: [1, 2]
+`)
+	t.Setenv("RATE_LIMIT_RPS", "fast")
+	t.Setenv("GRAPHQL_MAX_DEPTH", "0")
+
+	_, err := Load([]string{"--config", yamlFile, "--tick-timeout", "soon"})
+	require.Error(t, err)
+	assert.Equal(t, `config file `+yamlFile+`: block_buffer_size must be a single value
+config file `+yamlFile+`: http_port: invalid value "eighty": not an integer
+config file `+yamlFile+`: unknown key "unknown"
+environment variable RATE_LIMIT_RPS: invalid value "fast": not a number
+flag --tick-timeout: invalid value "soon": not a duration like "500ms" or "10s"
+graphql_max_depth: must be at least 1, got 0`, err.Error())
+}
+
+func Test_Load_Arguments(t *testing.T) {
+	_, err := Load([]string{"--help"})
+	assert.ErrorIs(t, err, flag.ErrHelp)
+
+	_, err = Load([]string{"--no-such-flag"})
+	assert.EqualError(t, err, "flag provided but not defined: -no-such-flag")
+
+	_, err = Load([]string{"serve"})
+	assert.EqualError(t, err, "unexpected arguments: serve")
+
+	_, err = Load([]string{"--config", writeFile(t, "config.json", "{}")})
+	assert.ErrorContains(t, err, `unsupported extension ".json"`)
+
+	_, err = Load([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.ErrorContains(t, err, "config file read error")
+}
+
+func Test_Bind(t *testing.T) {
+	fs := NewFlagSet("export")
+	address := fs.String("address", "", "address to export")
+	load := Bind(fs)
+	require.NoError(t, fs.Parse([]string{"--address", "0x1111", "--http-port", "8000", "extra"}))
+
+	c, err := load()
+	require.NoError(t, err)
+	assert.Equal(t, "0x1111", *address)
+	assert.Equal(t, []string{"extra"}, fs.Args())
+	assert.Equal(t, "http://localhost:8000", c.InstanceURL())
+
+	c.APIURL = "https://watch.example.com"
+	assert.Equal(t, "https://watch.example.com", c.InstanceURL())
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Print writes the effective configuration as YAML usable as a config file,
+// noting where each value came from. Secrets are redacted.
+func (c *Config) Print(w io.Writer) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range c.fields() {
+		value := f.value.String()
+		if f.redact != nil && value != "" {
+			value = f.redact(value)
+		}
+		source := c.Source(f.key)
+		comment := source
+		switch source {
+		case SourceEnv:
+			comment += " " + f.env
+		case SourceFlag:
+			comment += " --" + f.flagName()
+		}
+
+		valueNode := &yaml.Node{Kind: yaml.ScalarNode, Value: value, LineComment: comment}
+		if _, isString := f.value.(*stringValue); isString {
+			valueNode.Tag = "!!str"
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: f.key}, valueNode)
+	}
+
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("config print error: %w", err)
+	}
+	return enc.Close() //nolint:wrapcheck // closing only flushes
+}
+
+// Source is where the value of a config file key came from.
+func (c *Config) Source(key string) string {
+	if source, exists := c.sources[key]; exists {
+		return source
+	}
+	return SourceDefault
+}
+
+func redactSecret(string) string {
+	return redacted
+}
+
+// redactURL keeps the scheme and host, since node providers put API keys in
+// the path or query and collectors take credentials in the user info.
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	if u.User == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" {
+		return value
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
+// redactUserInfo only hides credentials in the user info of a URL.
+func redactUserInfo(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+	if u.User == nil {
+		return value
+	}
+	return u.Scheme + "://" + redacted + "@" + u.Host + u.EscapedPath()
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalid         = errors.New("invalid configuration")
+	ErrRestartRequired = errors.New("restart required")
+)
+
+// Diff lists the keys of the values that differ between c and next.
+func (c *Config) Diff(next *Config) []string {
+	changed := []string{}
+	nextFields := next.fields()
+	for i, f := range c.fields() {
+		if f.value.String() != nextFields[i].value.String() {
+			changed = append(changed, f.key)
+		}
+	}
+	return changed
+}
+
+// CheckReload returns the keys next changes, or fails with ErrRestartRequired
+// naming the keys that can only change with a restart, like http_port.
+func (c *Config) CheckReload(next *Config) ([]string, error) {
+	changed := c.Diff(next)
+	live := map[string]bool{}
+	for _, f := range c.fields() {
+		live[f.key] = f.live
+	}
+
+	fixed := []string{}
+	for _, key := range changed {
+		if !live[key] {
+			fixed = append(fixed, key)
+		}
+	}
+	if len(fixed) > 0 {
+		return nil, fmt.Errorf("%w: %s cannot change while running", ErrRestartRequired, strings.Join(fixed, ", "))
+	}
+	return changed, nil
+}
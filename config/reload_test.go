@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Diff(t *testing.T) {
+	c := Default()
+	assert.Empty(t, c.Diff(Default()))
+
+	next := Default()
+	next.EthNodeURL = "http://localhost:8545"
+	next.RateLimitRPS = 5
+	assert.Equal(t, []string{"eth_node_url", "rate_limit_rps"}, c.Diff(next))
+}
+
+func Test_CheckReload(t *testing.T) {
+	c := Default()
+
+	next := Default()
+	next.EthNodeURL = "http://localhost:8545"
+	next.BlockTickInterval = time.Second
+	changed, err := c.CheckReload(next)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eth_node_url", "block_tick_interval"}, changed)
+
+	next.HTTPPort = 8000
+	next.GRPCPort = 8090
+	_, err = c.CheckReload(next)
+	require.ErrorIs(t, err, ErrRestartRequired)
+	assert.EqualError(t, err, "restart required: http_port, grpc_port cannot change while running")
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// Validate reports every invalid setting, by the key used in config files.
+func (c *Config) Validate() error {
+	errs := []error{}
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+		}
+	}
+
+	check(isURL(c.EthNodeURL, "http", "https"), "eth_node_url", "must be an http or https URL, got %q", redactURL(c.EthNodeURL))
+	check(c.EthRequestTimeout > 0, "eth_request_timeout", "must be positive")
+	check(isPort(c.HTTPPort), "http_port", "must be between 1 and 65535, got %d", c.HTTPPort)
+	check(isPort(c.GRPCPort), "grpc_port", "must be between 1 and 65535, got %d", c.GRPCPort)
+	check(c.HTTPPort != c.GRPCPort, "grpc_port", "must differ from http_port %d", c.HTTPPort)
+	check(c.HTTPTimeout > 0, "http_timeout", "must be positive")
+	check(c.StreamHeartbeatInterval > 0, "stream_heartbeat_interval", "must be positive")
+	check(c.BlockTickInterval > 0, "block_tick_interval", "must be positive")
+	check(c.TickTimeout > 0, "tick_timeout", "must be positive")
+	check(c.TickTimeout <= c.BlockTickInterval, "tick_timeout", "must not exceed block_tick_interval %s", c.BlockTickInterval)
+	check(c.BlockBufferSize > 0, "block_buffer_size", "must be at least 1, got %d", c.BlockBufferSize)
+	check(c.RateLimitRPS >= 0, "rate_limit_rps", "must not be negative")
+	check(c.RateLimitRPS == 0 || c.RateLimitBurst > 0, "rate_limit_burst", "must be at least 1 with rate limiting on, got %d", c.RateLimitBurst)
+	check(c.MaxSubscriptions >= 0, "max_subscriptions_per_tenant", "must not be negative")
+	check(c.MaxTransactions >= 0, "max_transactions_per_tenant", "must not be negative")
+	check(c.GraphQLMaxComplexity > 0, "graphql_max_complexity", "must be at least 1, got %d", c.GraphQLMaxComplexity)
+	check(c.GraphQLMaxDepth > 0, "graphql_max_depth", "must be at least 1, got %d", c.GraphQLMaxDepth)
+	check(c.ReadyMaxStaleTicks >= 0, "ready_max_stale_ticks", "must not be negative")
+	check(c.ReadyMaxBlockLag >= 0, "ready_max_block_lag", "must not be negative")
+	check(c.BalanceReconcileInterval > 0, "balance_reconcile_interval", "must be positive")
+	for _, contract := range c.TokenContracts {
+		check(isAddress(contract), "token_contracts", "must be 0x and 40 hex digits, got %q", contract)
+	}
+	check(c.ENSResolveInterval > 0, "ens_resolve_interval", "must be positive")
+	check(c.ENSCacheTTL > 0, "ens_cache_ttl", "must be positive")
+	check(c.OTLPEndpoint == "" || isURL(c.OTLPEndpoint, "http", "https"), "otlp_endpoint", "must be an http or https URL, got %q", redactUserInfo(c.OTLPEndpoint))
+	check(c.APIURL == "" || isURL(c.APIURL, "http", "https"), "api_url", "must be an http or https URL, got %q", redactUserInfo(c.APIURL))
+
+	return errors.Join(errs...)
+}
+
+func isPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func isURL(value string, schemes ...string) bool {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+func isAddress(value string) bool {
+	return addressPattern.MatchString(value)
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Validate(t *testing.T) {
+	assert.NoError(t, Default().Validate())
+
+	c := Default()
+	c.EthNodeURL = "ftp://node.example.com/secret"
+	c.EthRequestTimeout = 0
+	c.HTTPPort = 70000
+	c.GRPCPort = 70000
+	c.TickTimeout = time.Minute
+	c.BlockBufferSize = 0
+	c.RateLimitRPS = 1
+	c.RateLimitBurst = 0
+	c.MaxTransactions = -1
+	c.ReadyMaxBlockLag = -1
+	c.BalanceReconcileInterval = 0
+	c.TokenContracts = []string{"0xdAC17F958D2ee523a2206206994597C13D831ec7", "usdc"}
+	c.ENSCacheTTL = -time.Second
+	c.OTLPEndpoint = "collector:4318"
+
+	assert.EqualError(t, c.Validate(), `eth_node_url: must be an http or https URL, got "ftp://node.example.com/[REDACTED]"
+eth_request_timeout: must be positive
+http_port: must be between 1 and 65535, got 70000
+grpc_port: must be between 1 and 65535, got 70000
+grpc_port: must differ from http_port 70000
+tick_timeout: must not exceed block_tick_interval 10s
+block_buffer_size: must be at least 1, got 0
+rate_limit_burst: must be at least 1 with rate limiting on, got 0
+max_transactions_per_tenant: must not be negative
+ready_max_block_lag: must not be negative
+balance_reconcile_interval: must be positive
+token_contracts: must be 0x and 40 hex digits, got "usdc"
+ens_cache_ttl: must be positive
+otlp_endpoint: must be an http or https URL, got "[REDACTED]"`)
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const apiKeyPrefix = "eaw_"
+
+var (
+	ErrUnauthorized   = errors.New("invalid API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrInvalidTenant  = errors.New("invalid tenant")
+)
+
+// APIKey grants access to the data of a single tenant. Only a hash of the
+// key is kept, the plaintext is returned once when the key is created.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Tenant    string    `json:"tenant"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	hash []byte
+}
+
+// CreateAPIKey issues a new key for the tenant and returns its plaintext.
+func (s *Service) CreateAPIKey(tenant, name string) (*APIKey, string, error) {
+	if tenant == DefaultTenant {
+		return nil, "", fmt.Errorf("%w: tenant is required", ErrInvalidTenant)
+	}
+
+	secret := make([]byte, 32)
+	id := make([]byte, 8)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("api key generation error: %w", err)
+	}
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", fmt.Errorf("api key generation error: %w", err)
+	}
+
+	plaintext := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	hash := sha256.Sum256([]byte(plaintext))
+	key := &APIKey{
+		ID:        hex.EncodeToString(id),
+		Tenant:    tenant,
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+		hash:      hash[:],
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.apiKeys[key.ID] = key
+	return key.snapshot(), plaintext, nil
+}
+
+// Authenticate returns the tenant the key belongs to.
+func (s *Service) Authenticate(plaintext string) (string, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	tenant, found := "", false
+	// compare with every key so the timing does not depend on which one matches
+	for _, key := range s.apiKeys {
+		if subtle.ConstantTimeCompare(key.hash, hash[:]) == 1 {
+			tenant, found = key.Tenant, true
+		}
+	}
+	if !found {
+		return "", ErrUnauthorized
+	}
+	return tenant, nil
+}
+
+// ListAPIKeys returns the keys of all tenants ordered by creation time.
+func (s *Service) ListAPIKeys() []*APIKey {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	result := []*APIKey{}
+	for _, key := range s.apiKeys {
+		result = append(result, key.snapshot())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+func (s *Service) RevokeAPIKey(id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, exists := s.apiKeys[id]; !exists {
+		return ErrAPIKeyNotFound
+	}
+	delete(s.apiKeys, id)
+	return nil
+}
+
+func (k *APIKey) snapshot() *APIKey {
+	c := *k
+	c.hash = nil
+	return &c
+}
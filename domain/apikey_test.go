@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_APIKeys(t *testing.T) {
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+
+	_, _, err := s.CreateAPIKey(DefaultTenant, "no tenant")
+	assert.ErrorIs(t, err, ErrInvalidTenant)
+
+	key, plaintext, err := s.CreateAPIKey("team-a", "ci")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plaintext, apiKeyPrefix))
+	assert.Equal(t, "team-a", key.Tenant)
+	assert.Nil(t, key.hash)
+	assert.NotContains(t, string(s.apiKeys[key.ID].hash), plaintext)
+
+	tenant, err := s.Authenticate(plaintext)
+	assert.NoError(t, err)
+	assert.Equal(t, "team-a", tenant)
+
+	_, err = s.Authenticate("eaw_wrong")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = s.Authenticate("")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	keys := s.ListAPIKeys()
+	assert.Equal(t, 1, len(keys))
+	assert.Equal(t, key.ID, keys[0].ID)
+
+	assert.NoError(t, s.RevokeAPIKey(key.ID))
+	assert.ErrorIs(t, s.RevokeAPIKey(key.ID), ErrAPIKeyNotFound)
+	_, err = s.Authenticate(plaintext)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
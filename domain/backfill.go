@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"deshev.com/eth-address-watch/tracing"
+)
+
+// MaxBackfillBlocks is the widest block range a single Backfill call fetches.
+const MaxBackfillBlocks = 100
+
+var (
+	ErrInvalidRange        = errors.New("invalid block range")
+	ErrBackfillUnavailable = errors.New("backfill unavailable")
+	ErrNodeUnavailable     = errors.New("node unavailable")
+)
+
+type BackfillResult struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	// Stored is the number of transactions that were not stored before
+	Stored int `json:"stored"`
+}
+
+// WithBlockSource lets Backfill fetch past blocks from the node.
+func WithBlockSource(source ETHClient) ServiceOption {
+	return func(s *Service) {
+		s.blockSource = source
+	}
+}
+
+// Backfill fetches the blocks from..to, both included, and stores the
+// transactions of a subscribed address in them that are not stored yet.
+// Backfilled transactions are appended to the address history, so they get
+// IDs after the ones already stored, and their matches are published like
+// those of new blocks.
+func (s *Service) Backfill(ctx context.Context, address string, from, to int) (*BackfillResult, error) {
+	address = normalizeAddress(address)
+
+	if s.blockSource == nil {
+		return nil, ErrBackfillUnavailable
+	}
+	if from < 0 || to < from {
+		return nil, fmt.Errorf("%w: from %d to %d", ErrInvalidRange, from, to)
+	}
+	if blocks := to - from + 1; blocks > MaxBackfillBlocks {
+		return nil, fmt.Errorf("%w: %d blocks, at most %d at a time", ErrInvalidRange, blocks, MaxBackfillBlocks)
+	}
+	if _, err := s.GetSubscription(ctx, address); err != nil {
+		return nil, err
+	}
+
+	ctx, span := tracing.Start(ctx, "Service.Backfill", trace.WithAttributes(
+		attribute.Int("block.from", from),
+		attribute.Int("block.to", to),
+	))
+	defer span.End()
+
+	// blocks are fetched without the lock, as that takes a while
+	blocks := make([]*Block, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		block, err := s.blockSource.GetBlock(ctx, i)
+		if err != nil {
+			err = fmt.Errorf("%w: block %d: %w", ErrNodeUnavailable, i, err)
+			tracing.Fail(span, err)
+			return nil, err
+		}
+		block.stampTransactions()
+		blocks = append(blocks, block)
+	}
+	matching := []*Transaction{}
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			if tx.From == address || tx.To == address {
+				matching = append(matching, tx)
+			}
+		}
+	}
+	copies := s.withStatus(ctx, matching)
+
+	s.mtx.Lock()
+	defer s.publishQueued()
+	defer s.mtx.Unlock()
+
+	t := s.readTenant(ctx)
+	txs, exists := t.store[address]
+	if !exists {
+		return nil, ErrNotSubscribed
+	}
+	stored := map[string]bool{}
+	for _, tx := range txs {
+		stored[tx.Hash] = true
+	}
+
+	result := &BackfillResult{From: from, To: to}
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			if (tx.From != address && tx.To != address) || stored[tx.Hash] {
+				continue
+			}
+			stored[tx.Hash] = true
+			tx := copyOf(tx, copies)
+			s.storeTransaction(t, address, tx)
+			s.publishMatch(t, address, tx)
+			result.Stored++
+		}
+	}
+	s.log.Info("backfilled address", "address", address, "from", from, "to", to, "stored", result.Stored)
+	return result, nil
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Backfill(t *testing.T) {
+	log := slog.Default()
+	ctx := context.Background()
+	client := &MockETHClient{}
+	client.On("GetBlock", mock.Anything, 0x10).Return(&Block{Timestamp: "0x65920080", Transactions: []*Transaction{
+		{Hash: "0xa", From: "0x1111", To: "0x2222"},
+		{Hash: "0xb", From: "0x3333", To: "0x4444"},
+	}}, nil)
+	client.On("GetBlock", mock.Anything, 0x11).Return(&Block{Transactions: []*Transaction{
+		{Hash: "0xc", From: "0x2222", To: "0x1111"},
+	}}, nil)
+	receipts := &fakeBalanceSource{receipts: map[string]*Receipt{"0xa": transfer(TxStatusFailed)}}
+	bus := NewBus(log)
+	s := NewService(log, bus, WithBlockSource(client), WithReceiptSource(receipts))
+	matches := bus.Subscribe("test", 2, OverflowBlock, OfKind(KindTransactionMatched))
+
+	_, err := s.Backfill(ctx, "0x1111", 0x10, 0x11)
+	assert.ErrorIs(t, err, ErrNotSubscribed)
+
+	_, err = s.Subscribe(ctx, "0x1111")
+	require.NoError(t, err)
+	// the live watcher already stored the transaction of block 0x11
+	s.processBlock(ctx, &Block{NumberParsed: 0x11, Transactions: []*Transaction{{Hash: "0xc", From: "0x2222", To: "0x1111"}}})
+	<-matches.C
+
+	result, err := s.Backfill(ctx, "0x1111", 0x10, 0x11)
+	require.NoError(t, err)
+	assert.Equal(t, &BackfillResult{From: 0x10, To: 0x11, Stored: 1}, result)
+	txs := s.GetTransactions(ctx, "0x1111")
+	require.Len(t, txs, 2)
+	assert.Equal(t, "0xa", txs[1].Hash)
+	assert.Equal(t, TxStatusFailed, txs[1].Status)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), txs[1].Time())
+	assert.True(t, txs[0].Time().IsZero())
+	m, ok := (<-matches.C).(*TransactionMatched)
+	require.True(t, ok)
+	assert.Equal(t, 2, m.ID)
+
+	// backfilling again stores nothing new
+	result, err = s.Backfill(ctx, "0x1111", 0x10, 0x11)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Stored)
+}
+
+func Test_Backfill_Errors(t *testing.T) {
+	log := slog.Default()
+	ctx := context.Background()
+
+	_, err := NewService(log, NewBus(log)).Backfill(ctx, "0x1111", 1, 2)
+	assert.ErrorIs(t, err, ErrBackfillUnavailable)
+
+	client := &MockETHClient{}
+	client.On("GetBlock", mock.Anything, 1).Return(nil, errors.New("connection refused"))
+	s := NewService(log, NewBus(log), WithBlockSource(client))
+	_, err = s.Subscribe(ctx, "0x1111")
+	require.NoError(t, err)
+
+	_, err = s.Backfill(ctx, "0x1111", 2, 1)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+	_, err = s.Backfill(ctx, "0x1111", 1, MaxBackfillBlocks+1)
+	assert.EqualError(t, err, "invalid block range: 101 blocks, at most 100 at a time")
+	_, err = s.Backfill(ctx, "0x1111", 1, 1)
+	assert.ErrorIs(t, err, ErrNodeUnavailable)
+	assert.EqualError(t, err, "node unavailable: block 1: connection refused")
+}
@@ -0,0 +1,651 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/tracing"
+)
+
+const (
+	// maxBalanceHistory is the number of balance changes kept per address
+	// and asset
+	maxBalanceHistory = 10_000
+
+	BalanceSourceSeed           = "seed"
+	BalanceSourceTransactions   = "transactions"
+	BalanceSourceReconciliation = "reconciliation"
+
+	// assetETH names ETH among token contracts in logs and metrics
+	assetETH = "ETH"
+)
+
+var (
+	ErrBalanceUnavailable = errors.New("balance tracking unavailable")
+	ErrBalancePending     = errors.New("balance not known yet")
+)
+
+// BalanceSource reads balances, receipts and token transfers from the node.
+type BalanceSource interface {
+	GetBalance(ctx context.Context, address string, blockNumber int) (*big.Int, error)
+	GetReceipt(ctx context.Context, hash string) (*Receipt, error)
+	GetTokenBalance(ctx context.Context, token, holder string, blockNumber int) (*big.Int, error)
+	GetTokenInfo(ctx context.Context, token string) (*TokenInfo, error)
+	GetTransferLogs(ctx context.Context, tokens []string, blockNumber int) ([]*Log, error)
+}
+
+// Balance is the ETH balance of an address after Block.
+type Balance struct {
+	Address string `json:"address"`
+	Block   int    `json:"block"`
+	Wei     string `json:"wei"`
+	ETH     string `json:"eth"`
+	// ReconciledBlock is the last block the balance was checked against the node at
+	ReconciledBlock int `json:"reconciledBlock,omitempty"`
+	// Drift is the last difference reconciliation found
+	Drift *BalanceDrift `json:"drift,omitempty"`
+}
+
+// BalanceDrift is a difference between the tracked balance and the node,
+// from transfers the tracker does not see, like internal calls of contracts,
+// validator withdrawals and rebasing tokens, or from missed blocks. Token
+// amounts are in the smallest unit of the token.
+type BalanceDrift struct {
+	Block   int    `json:"block"`
+	Tracked string `json:"tracked"`
+	Node    string `json:"node"`
+	// Wei is the node balance minus the tracked one
+	Wei        string    `json:"wei"`
+	DetectedAt time.Time `json:"detectedAt"`
+}
+
+// BalancePoint is the balance after a block that changed it.
+type BalancePoint struct {
+	Block int    `json:"block"`
+	Wei   string `json:"wei"`
+	ETH   string `json:"eth"`
+	// Change is the signed wei difference to the previous point
+	Change string `json:"change"`
+	Source string `json:"source"`
+}
+
+type trackedBalance struct {
+	// tenants subscribed to the address, which is dropped with the last one
+	tenants map[string]bool
+	eth     *ledger
+	// tokens has a ledger per tracked token contract
+	tokens map[string]*ledger
+}
+
+// ledger follows the balance of one asset of an address.
+type ledger struct {
+	// seeded is false until the balance is read from the node, and again when
+	// the tracked balance can no longer be trusted
+	seeded          bool
+	amount          *big.Int
+	block           int
+	reconciledBlock int
+	drift           *BalanceDrift
+	history         []*balancePoint
+}
+
+type balancePoint struct {
+	block  int
+	amount *big.Int
+	change *big.Int
+	source string
+}
+
+func newLedger() *ledger {
+	return &ledger{amount: new(big.Int)}
+}
+
+// Balances keeps the ETH balance, and the balances of the configured ERC-20
+// tokens, of every subscribed address. A balance is read from the node at the
+// first block after the subscription and then follows each block: ETH moves
+// between the parties of successful transactions and the sender pays the fee
+// from the receipt either way, and tokens follow their Transfer logs.
+// Reconciliation reads the balances from the node again every interval and
+// flags drift.
+type Balances struct {
+	// mtx guards the state read by the API, it is only written by Start and
+	// Reconfigure
+	mtx       sync.RWMutex
+	log       *slog.Logger
+	source    BalanceSource
+	events    *Subscriber
+	addresses map[string]*trackedBalance
+	block     int
+	// tokens are the contracts to track, in lower case, and tokenInfo their
+	// symbol and decimals once read
+	tokens    []string
+	tokenInfo map[string]*TokenInfo
+
+	interval     time.Duration
+	reconfigured chan struct{}
+}
+
+func NewBalances(log *slog.Logger, cfg *config.Config, source BalanceSource, bus *Bus) *Balances {
+	return &Balances{
+		log:          log,
+		source:       source,
+		events:       bus.Subscribe("balances", blockQueueSize, OverflowBlock, OfKind(KindBlockIngested, KindReorgDetected, KindSubscriptionChanged)),
+		addresses:    map[string]*trackedBalance{},
+		tokens:       normalizeTokens(cfg.TokenContracts),
+		tokenInfo:    map[string]*TokenInfo{},
+		interval:     cfg.BalanceReconcileInterval,
+		reconfigured: make(chan struct{}, 1),
+	}
+}
+
+func normalizeTokens(contracts []string) []string {
+	tokens := make([]string, 0, len(contracts))
+	for _, contract := range contracts {
+		tokens = append(tokens, strings.ToLower(contract))
+	}
+	return tokens
+}
+
+// Reconfigure applies a new reconciliation interval from the next one, and
+// tracks the new token contracts from the next block.
+func (b *Balances) Reconfigure(cfg *config.Config) {
+	b.mtx.Lock()
+	b.interval = cfg.BalanceReconcileInterval
+	b.tokens = normalizeTokens(cfg.TokenContracts)
+	b.mtx.Unlock()
+
+	select {
+	case b.reconfigured <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Balances) reconcileInterval() time.Duration {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	return b.interval
+}
+
+func (b *Balances) Start(ctx context.Context) error {
+	b.log.Info("starting balance tracker")
+	defer b.events.Close()
+
+	ticker := time.NewTicker(b.reconcileInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-b.reconfigured:
+			ticker.Reset(b.reconcileInterval())
+		case <-ticker.C:
+			b.reconcile(ctx)
+		case e, ok := <-b.events.C:
+			if !ok {
+				return nil
+			}
+			b.handle(ctx, e)
+		}
+	}
+}
+
+func (b *Balances) handle(ctx context.Context, e Event) {
+	switch e := e.(type) {
+	case *SubscriptionChanged:
+		b.track(e)
+	case *ReorgDetected:
+		// blocks are not rolled back, so every balance is read again
+		b.mtx.Lock()
+		for _, tracked := range b.addresses {
+			tracked.eth.seeded = false
+			for _, l := range tracked.tokens {
+				l.seeded = false
+			}
+		}
+		b.mtx.Unlock()
+	case *BlockIngested:
+		b.processBlock(trace.ContextWithSpanContext(ctx, e.SpanContext), e.Block)
+	}
+}
+
+func (b *Balances) track(e *SubscriptionChanged) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	tracked, exists := b.addresses[e.Address]
+	if !e.Subscribed {
+		if exists {
+			delete(tracked.tenants, e.Tenant)
+			if len(tracked.tenants) == 0 {
+				delete(b.addresses, e.Address)
+			}
+		}
+		return
+	}
+	if !exists {
+		tracked = &trackedBalance{tenants: map[string]bool{}, eth: newLedger(), tokens: map[string]*ledger{}}
+		b.addresses[e.Address] = tracked
+	}
+	tracked.tenants[e.Tenant] = true
+}
+
+// processBlock seeds the balances not read yet at the block and applies the
+// block to the others. Only Start writes the balances, so the node is called
+// without the lock.
+func (b *Balances) processBlock(ctx context.Context, block *Block) {
+	ctx, span := tracing.Start(ctx, "Balances.processBlock", trace.WithAttributes(
+		attribute.Int("block.number", block.NumberParsed),
+	))
+	defer span.End()
+
+	tokens := b.syncTokens()
+	b.readTokenInfo(ctx, tokens)
+	seeds := b.seed(ctx, block.NumberParsed)
+	changes := map[*ledger]*big.Int{}
+	failed := map[*ledger]bool{}
+	b.applyTransactions(ctx, block, seeds, changes, failed)
+	b.applyTransferLogs(ctx, block.NumberParsed, tokens, seeds, changes, failed)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.block = block.NumberParsed
+	for _, tracked := range b.addresses {
+		tracked.eth.update(block.NumberParsed, seeds, changes, failed)
+		for _, l := range tracked.tokens {
+			l.update(block.NumberParsed, seeds, changes, failed)
+		}
+	}
+}
+
+// syncTokens gives every address a ledger for each tracked token and drops
+// the ledgers of tokens no longer tracked.
+func (b *Balances) syncTokens() []string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	tracked := map[string]bool{}
+	for _, token := range b.tokens {
+		tracked[token] = true
+	}
+	for _, balance := range b.addresses {
+		for token := range balance.tokens {
+			if !tracked[token] {
+				delete(balance.tokens, token)
+			}
+		}
+		for _, token := range b.tokens {
+			if _, exists := balance.tokens[token]; !exists {
+				balance.tokens[token] = newLedger()
+			}
+		}
+	}
+	return b.tokens
+}
+
+func (b *Balances) readTokenInfo(ctx context.Context, tokens []string) {
+	for _, token := range tokens {
+		if _, exists := b.tokenInfo[token]; exists {
+			continue
+		}
+		info, err := b.source.GetTokenInfo(ctx, token)
+		if err != nil {
+			b.log.Error("error reading token info", "token", token, "error", err)
+			continue
+		}
+		b.mtx.Lock()
+		b.tokenInfo[token] = info
+		b.mtx.Unlock()
+	}
+}
+
+// seed reads the balances that are not known at the block.
+func (b *Balances) seed(ctx context.Context, block int) map[*ledger]*big.Int {
+	seeds := map[*ledger]*big.Int{}
+	for address, tracked := range b.addresses {
+		if !tracked.eth.seeded {
+			balance, err := b.source.GetBalance(ctx, address, block)
+			if err != nil {
+				b.log.Error("error reading balance", "address", address, "block", block, "error", err)
+			} else {
+				seeds[tracked.eth] = balance
+			}
+		}
+		for token, l := range tracked.tokens {
+			if l.seeded {
+				continue
+			}
+			balance, err := b.source.GetTokenBalance(ctx, token, address, block)
+			if err != nil {
+				b.log.Error("error reading token balance", "address", address, "token", token, "block", block, "error", err)
+				continue
+			}
+			seeds[l] = balance
+		}
+	}
+	return seeds
+}
+
+// follows reports whether a ledger is applied the changes of the block, which
+// the balances read at the block already have.
+func follows(l *ledger, seeds map[*ledger]*big.Int) bool {
+	return l != nil && l.seeded && seeds[l] == nil
+}
+
+func (b *Balances) applyTransactions(ctx context.Context, block *Block, seeds map[*ledger]*big.Int, changes map[*ledger]*big.Int, failed map[*ledger]bool) {
+	for _, tx := range block.Transactions {
+		parties := []string{}
+		for _, address := range []string{tx.From, tx.To} {
+			tracked, exists := b.addresses[address]
+			if exists && follows(tracked.eth, seeds) && (len(parties) == 0 || parties[0] != address) {
+				parties = append(parties, address)
+			}
+		}
+		if len(parties) == 0 {
+			continue
+		}
+
+		receipt, err := b.source.GetReceipt(ctx, tx.Hash)
+		if err != nil {
+			// the balances are read again at the next block
+			b.log.Error("error reading receipt", "hash", tx.Hash, "error", err)
+			for _, address := range parties {
+				failed[b.addresses[address].eth] = true
+			}
+			continue
+		}
+		for _, address := range parties {
+			addChange(changes, b.addresses[address].eth, balanceChange(address, tx, receipt))
+		}
+	}
+}
+
+// applyTransferLogs applies the Transfer logs of the tracked tokens in the
+// block. Log addresses are lower case, so holders are matched ignoring case.
+func (b *Balances) applyTransferLogs(ctx context.Context, block int, tokens []string, seeds map[*ledger]*big.Int, changes map[*ledger]*big.Int, failed map[*ledger]bool) {
+	holders := map[string]*trackedBalance{}
+	following := []*ledger{}
+	for address, tracked := range b.addresses {
+		holders[strings.ToLower(address)] = tracked
+		for _, l := range tracked.tokens {
+			if follows(l, seeds) {
+				following = append(following, l)
+			}
+		}
+	}
+	if len(following) == 0 {
+		return
+	}
+
+	logs, err := b.source.GetTransferLogs(ctx, tokens, block)
+	if err != nil {
+		b.log.Error("error reading transfer logs", "block", block, "error", err)
+		for _, l := range following {
+			failed[l] = true
+		}
+		return
+	}
+	for _, log := range logs {
+		transfer := DecodeTransferLog(log)
+		if transfer == nil || log.Removed {
+			continue
+		}
+		value := HexToBig(transfer.Value)
+		if from, exists := holders[transfer.From]; exists && follows(from.tokens[transfer.Token], seeds) {
+			addChange(changes, from.tokens[transfer.Token], new(big.Int).Neg(value))
+		}
+		if to, exists := holders[transfer.To]; exists && follows(to.tokens[transfer.Token], seeds) {
+			addChange(changes, to.tokens[transfer.Token], value)
+		}
+	}
+}
+
+func addChange(changes map[*ledger]*big.Int, l *ledger, change *big.Int) {
+	total, exists := changes[l]
+	if !exists {
+		total = new(big.Int)
+		changes[l] = total
+	}
+	total.Add(total, change)
+}
+
+// balanceChange is the wei a transaction adds to the balance of one of its
+// parties.
+func balanceChange(address string, tx *Transaction, receipt *Receipt) *big.Int {
+	change := new(big.Int)
+	success := receipt.Status != TxStatusFailed
+	if tx.From == address {
+		change.Sub(change, receipt.Fee())
+		if success {
+			change.Sub(change, HexToBig(tx.Value))
+		}
+	}
+	if tx.To == address && success {
+		change.Add(change, HexToBig(tx.Value))
+	}
+	return change
+}
+
+// update moves the ledger to the block. It expects the lock to be held.
+func (l *ledger) update(block int, seeds map[*ledger]*big.Int, changes map[*ledger]*big.Int, failed map[*ledger]bool) {
+	switch {
+	case seeds[l] != nil:
+		l.seeded = true
+		l.record(block, seeds[l], BalanceSourceSeed)
+	case failed[l]:
+		l.seeded = false
+	case !l.seeded:
+		// waits for a balance from the node
+	case changes[l] != nil:
+		l.record(block, new(big.Int).Add(l.amount, changes[l]), BalanceSourceTransactions)
+	default:
+		l.block = block
+	}
+}
+
+// record expects the lock to be held.
+func (l *ledger) record(block int, amount *big.Int, source string) {
+	change := new(big.Int).Sub(amount, l.amount)
+	l.amount = amount
+	l.block = block
+	if len(l.history) > 0 && change.Sign() == 0 {
+		return
+	}
+	if len(l.history) >= maxBalanceHistory {
+		l.history = l.history[1:]
+	}
+	l.history = append(l.history, &balancePoint{block: block, amount: amount, change: change, source: source})
+}
+
+// reconcile compares every tracked balance with the node at the last
+// processed block and takes the node balance when they differ.
+func (b *Balances) reconcile(ctx context.Context) {
+	ctx, span := tracing.Start(ctx, "Balances.reconcile")
+	defer span.End()
+
+	block := b.block
+	for address, tracked := range b.addresses {
+		if tracked.eth.seeded {
+			balance, err := b.source.GetBalance(ctx, address, block)
+			if err != nil {
+				b.log.Error("error reconciling balance", "address", address, "block", block, "error", err)
+			} else {
+				b.check(address, assetETH, tracked.eth, block, balance)
+			}
+		}
+		for token, l := range tracked.tokens {
+			if !l.seeded {
+				continue
+			}
+			balance, err := b.source.GetTokenBalance(ctx, token, address, block)
+			if err != nil {
+				b.log.Error("error reconciling token balance", "address", address, "token", token, "block", block, "error", err)
+				continue
+			}
+			b.check(address, token, l, block, balance)
+		}
+	}
+}
+
+func (b *Balances) check(address, asset string, l *ledger, block int, balance *big.Int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	l.reconciledBlock = block
+	if balance.Cmp(l.amount) == 0 {
+		return
+	}
+
+	drift := new(big.Int).Sub(balance, l.amount)
+	b.log.Warn("balance drift detected", "address", address, "asset", asset, "block", block,
+		"tracked", l.amount.String(), "node", balance.String(), "drift", drift.String())
+	balanceDrifts.With(address, asset).Inc()
+	l.drift = &BalanceDrift{
+		Block:      block,
+		Tracked:    l.amount.String(),
+		Node:       balance.String(),
+		Wei:        drift.String(),
+		DetectedAt: time.Now().UTC(),
+	}
+	l.record(block, balance, BalanceSourceReconciliation)
+}
+
+// at returns the amount after a block, or the current amount for block 0.
+// It expects the read lock to be held.
+func (l *ledger) at(block int) (*big.Int, int, error) {
+	if block == 0 {
+		return l.amount, l.block, nil
+	}
+	if block > l.block {
+		return nil, 0, fmt.Errorf("%w: block %d is after the last processed block %d", ErrInvalidRange, block, l.block)
+	}
+
+	// the last change at or before the block
+	i := sort.Search(len(l.history), func(i int) bool { return l.history[i].block > block }) - 1
+	if i < 0 {
+		return nil, 0, fmt.Errorf("%w: no balance known at block %d, history starts at %d", ErrInvalidRange, block, l.history[0].block)
+	}
+	return l.history[i].amount, block, nil
+}
+
+// points returns the changes in the blocks from..to, both included, where a
+// zero to is open. It expects the read lock to be held.
+func (l *ledger) points(from, to int) []*balancePoint {
+	points := []*balancePoint{}
+	for _, p := range l.history {
+		if p.block >= from && (to == 0 || p.block <= to) {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+func checkBlockRange(from, to int) error {
+	if from < 0 || (to != 0 && to < from) {
+		return fmt.Errorf("%w: from %d to %d", ErrInvalidRange, from, to)
+	}
+	return nil
+}
+
+// Get returns the balance of an address after a block, or the current
+// balance for block 0.
+func (b *Balances) Get(address string, block int) (*Balance, error) {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	tracked, exists := b.addresses[address]
+	if !exists || len(tracked.eth.history) == 0 {
+		return nil, ErrBalancePending
+	}
+	amount, block, err := tracked.eth.at(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Balance{
+		Address:         address,
+		Block:           block,
+		Wei:             amount.String(),
+		ETH:             FormatEther(amount),
+		ReconciledBlock: tracked.eth.reconciledBlock,
+		Drift:           tracked.eth.drift,
+	}, nil
+}
+
+// History returns the changes of the balance of an address in the blocks
+// from..to, both included, oldest first. A zero to is open.
+func (b *Balances) History(address string, from, to int) ([]*BalancePoint, error) {
+	if err := checkBlockRange(from, to); err != nil {
+		return nil, err
+	}
+
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	tracked, exists := b.addresses[address]
+	if !exists || len(tracked.eth.history) == 0 {
+		return nil, ErrBalancePending
+	}
+	points := []*BalancePoint{}
+	for _, p := range tracked.eth.points(from, to) {
+		points = append(points, &BalancePoint{
+			Block:  p.block,
+			Wei:    p.amount.String(),
+			ETH:    FormatEther(p.amount),
+			Change: p.change.String(),
+			Source: p.source,
+		})
+	}
+	return points, nil
+}
+
+// WithBalances serves the balances of subscribed addresses from b.
+func WithBalances(b *Balances) ServiceOption {
+	return func(s *Service) {
+		s.balances = b
+	}
+}
+
+// GetBalance returns the balance of a subscribed address after a block, or
+// the current balance for block 0.
+func (s *Service) GetBalance(ctx context.Context, address string, block int) (*Balance, error) {
+	address = normalizeAddress(address)
+
+	if err := s.checkBalances(ctx, address); err != nil {
+		return nil, err
+	}
+	return s.balances.Get(address, block)
+}
+
+// GetBalanceHistory returns the balance changes of a subscribed address in
+// the blocks from..to.
+func (s *Service) GetBalanceHistory(ctx context.Context, address string, from, to int) ([]*BalancePoint, error) {
+	address = normalizeAddress(address)
+
+	if err := s.checkBalances(ctx, address); err != nil {
+		return nil, err
+	}
+	return s.balances.History(address, from, to)
+}
+
+// checkBalances fails unless balances are tracked and the tenant is
+// subscribed to the address.
+func (s *Service) checkBalances(ctx context.Context, address string) error {
+	if s.balances == nil {
+		return ErrBalanceUnavailable
+	}
+	_, err := s.GetSubscription(ctx, address)
+	return err
+}
@@ -0,0 +1,241 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deshev.com/eth-address-watch/config"
+)
+
+// fakeBalanceSource has the node balance of every address, whatever the
+// block, the receipts of known transactions, token balances by token and
+// holder, and the Transfer logs of each block.
+type fakeBalanceSource struct {
+	balances      map[string]*big.Int
+	receipts      map[string]*Receipt
+	tokenBalances map[string]map[string]*big.Int
+	logs          map[int][]*Log
+}
+
+func (f *fakeBalanceSource) GetBalance(_ context.Context, address string, _ int) (*big.Int, error) {
+	balance, exists := f.balances[address]
+	if !exists {
+		return nil, errors.New("node down")
+	}
+	return new(big.Int).Set(balance), nil
+}
+
+func (f *fakeBalanceSource) GetReceipt(_ context.Context, hash string) (*Receipt, error) {
+	receipt, exists := f.receipts[hash]
+	if !exists {
+		return nil, errors.New("receipt not found")
+	}
+	return receipt, nil
+}
+
+func (f *fakeBalanceSource) GetTokenBalance(_ context.Context, token, holder string, _ int) (*big.Int, error) {
+	balance, exists := f.tokenBalances[token][holder]
+	if !exists {
+		return nil, errors.New("node down")
+	}
+	return new(big.Int).Set(balance), nil
+}
+
+func (f *fakeBalanceSource) GetTokenInfo(_ context.Context, token string) (*TokenInfo, error) {
+	return &TokenInfo{Address: token, Symbol: "USDT", Decimals: 6}, nil
+}
+
+func (f *fakeBalanceSource) GetTransferLogs(_ context.Context, _ []string, blockNumber int) ([]*Log, error) {
+	logs, exists := f.logs[blockNumber]
+	if !exists {
+		return nil, errors.New("node down")
+	}
+	return logs, nil
+}
+
+func ether(amount string) *big.Int {
+	wei, err := ParseAmount(amount + " ether")
+	if err != nil {
+		panic(err)
+	}
+	return wei
+}
+
+// transfer pays 21000 gas at 1 gwei, 0.000021 ether.
+func transfer(status string) *Receipt {
+	return &Receipt{Status: status, GasUsed: "0x5208", EffectiveGasPrice: "0x3b9aca00"}
+}
+
+func newTestBalances(source *fakeBalanceSource) *Balances {
+	log := slog.Default()
+	return NewBalances(log, config.Default(), source, NewBus(log))
+}
+
+func Test_Balances_Track(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeBalanceSource{
+		balances: map[string]*big.Int{"0x1111": ether("10")},
+		receipts: map[string]*Receipt{
+			"0xb": transfer(TxStatusSuccess),
+			"0xc": transfer(TxStatusFailed),
+			"0xd": transfer(TxStatusSuccess),
+		},
+	}
+	b := newTestBalances(source)
+
+	b.handle(ctx, &SubscriptionChanged{Address: "0x1111", Subscribed: true})
+	_, err := b.Get("0x1111", 0)
+	assert.ErrorIs(t, err, ErrBalancePending)
+
+	// the balance read at block 100 already has its transactions
+	b.processBlock(ctx, &Block{NumberParsed: 100, Transactions: []*Transaction{
+		{Hash: "0xa", From: "0x1111", To: "0x2222", Value: "0x1"},
+	}})
+	balance, err := b.Get("0x1111", 0)
+	require.NoError(t, err)
+	assert.Equal(t, &Balance{Address: "0x1111", Block: 100, Wei: "10000000000000000000", ETH: "10"}, balance)
+
+	b.processBlock(ctx, &Block{NumberParsed: 101, Transactions: []*Transaction{
+		{Hash: "0xb", From: "0x1111", To: "0x2222", Value: "0xde0b6b3a7640000"},
+		// failed, nothing arrives
+		{Hash: "0xc", From: "0x2222", To: "0x1111", Value: "0xde0b6b3a7640000"},
+		// to itself, only the fee leaves
+		{Hash: "0xd", From: "0x1111", To: "0x1111", Value: "0x1bc16d674ec80000"},
+		{Hash: "0xe", From: "0x3333", To: "0x4444", Value: "0x1"},
+	}})
+	b.processBlock(ctx, &Block{NumberParsed: 102})
+
+	balance, err = b.Get("0x1111", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 102, balance.Block)
+	assert.Equal(t, "8.999958", balance.ETH)
+
+	history, err := b.History("0x1111", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []*BalancePoint{
+		{Block: 100, Wei: "10000000000000000000", ETH: "10", Change: "10000000000000000000", Source: BalanceSourceSeed},
+		{Block: 101, Wei: "8999958000000000000", ETH: "8.999958", Change: "-1000042000000000000", Source: BalanceSourceTransactions},
+	}, history)
+	history, err = b.History("0x1111", 101, 101)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+	_, err = b.History("0x1111", 5, 4)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+
+	balance, err = b.Get("0x1111", 100)
+	require.NoError(t, err)
+	assert.Equal(t, "10", balance.ETH)
+	balance, err = b.Get("0x1111", 102)
+	require.NoError(t, err)
+	assert.Equal(t, "8.999958", balance.ETH)
+	_, err = b.Get("0x1111", 99)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+	_, err = b.Get("0x1111", 103)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+}
+
+func Test_Balances_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeBalanceSource{balances: map[string]*big.Int{"0x1111": ether("10")}}
+	b := newTestBalances(source)
+	b.handle(ctx, &SubscriptionChanged{Address: "0x1111", Subscribed: true})
+	b.processBlock(ctx, &Block{NumberParsed: 100})
+
+	b.reconcile(ctx)
+	balance, err := b.Get("0x1111", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 100, balance.ReconciledBlock)
+	assert.Nil(t, balance.Drift)
+
+	// a validator withdrawal is not a transaction
+	drifts := balanceDrifts.With("0x1111", assetETH)
+	drifted := drifts.Value()
+	source.balances["0x1111"] = ether("10.5")
+	b.processBlock(ctx, &Block{NumberParsed: 101})
+	b.reconcile(ctx)
+	assert.Equal(t, drifted+1, drifts.Value())
+
+	balance, err = b.Get("0x1111", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "10.5", balance.ETH)
+	assert.Equal(t, 101, balance.ReconciledBlock)
+	require.NotNil(t, balance.Drift)
+	assert.Equal(t, 101, balance.Drift.Block)
+	assert.Equal(t, "10000000000000000000", balance.Drift.Tracked)
+	assert.Equal(t, "500000000000000000", balance.Drift.Wei)
+
+	history, err := b.History("0x1111", 101, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, BalanceSourceReconciliation, history[0].Source)
+}
+
+func Test_Balances_Reseed(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeBalanceSource{balances: map[string]*big.Int{"0x1111": ether("10")}, receipts: map[string]*Receipt{}}
+	b := newTestBalances(source)
+	b.handle(ctx, &SubscriptionChanged{Tenant: "a", Address: "0x1111", Subscribed: true})
+	b.handle(ctx, &SubscriptionChanged{Tenant: "b", Address: "0x1111", Subscribed: true})
+	b.processBlock(ctx, &Block{NumberParsed: 100})
+
+	// without the receipt the balance is read again at the next block
+	source.balances["0x1111"] = ether("9")
+	b.processBlock(ctx, &Block{NumberParsed: 101, Transactions: []*Transaction{{Hash: "0xa", From: "0x1111", To: "0x2222", Value: "0x1"}}})
+	b.processBlock(ctx, &Block{NumberParsed: 102})
+	balance, err := b.Get("0x1111", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "9", balance.ETH)
+
+	source.balances["0x1111"] = ether("8")
+	b.handle(ctx, &ReorgDetected{BlockNumber: 103})
+	b.processBlock(ctx, &Block{NumberParsed: 103})
+	history, err := b.History("0x1111", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, BalanceSourceSeed, history[2].Source)
+	assert.Equal(t, "8", history[2].ETH)
+
+	// the address is tracked while any tenant is subscribed
+	b.handle(ctx, &SubscriptionChanged{Tenant: "a", Address: "0x1111", Subscribed: false})
+	_, err = b.Get("0x1111", 0)
+	assert.NoError(t, err)
+	b.handle(ctx, &SubscriptionChanged{Tenant: "b", Address: "0x1111", Subscribed: false})
+	_, err = b.Get("0x1111", 0)
+	assert.ErrorIs(t, err, ErrBalancePending)
+}
+
+func Test_GetBalance(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log := slog.Default()
+	bus := NewBus(log)
+
+	_, err := NewService(log, bus).GetBalance(ctx, "0x1111", 0)
+	assert.ErrorIs(t, err, ErrBalanceUnavailable)
+
+	source := &fakeBalanceSource{balances: map[string]*big.Int{"0x1111": ether("1")}}
+	b := NewBalances(log, config.Default(), source, bus)
+	s := NewService(log, bus, WithBalances(b))
+	go func() { _ = b.Start(ctx) }()
+
+	_, err = s.GetBalance(ctx, "0x1111", 0)
+	assert.ErrorIs(t, err, ErrNotSubscribed)
+	_, err = s.Subscribe(ctx, "0x1111")
+	require.NoError(t, err)
+	bus.Publish(&BlockIngested{Block: &Block{NumberParsed: 1}})
+
+	assert.Eventually(t, func() bool {
+		balance, err := s.GetBalance(ctx, "0x1111", 0)
+		return err == nil && balance.ETH == "1"
+	}, time.Second, time.Millisecond)
+	history, err := s.GetBalanceHistory(ctx, "0x1111", 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
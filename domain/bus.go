@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// OverflowPolicy decides what happens when a subscriber queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes the publisher wait until the subscriber catches up.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the event being published.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued event to make room.
+	OverflowDropOldest
+	// OverflowDisconnect closes the subscriber, which has to resubscribe.
+	OverflowDisconnect
+)
+
+// EventFilter selects the events a subscriber receives. A nil filter accepts
+// every event.
+type EventFilter func(Event) bool
+
+// Bus is an in-process publish/subscribe channel for domain events. Every
+// subscriber gets its own bounded queue, so a slow consumer only affects
+// publishers if it asked for OverflowBlock.
+type Bus struct {
+	mtx         sync.RWMutex
+	log         *slog.Logger
+	subscribers map[*Subscriber]struct{}
+}
+
+// Subscriber is a single consumer of the bus. C is closed on Close or when a
+// subscriber with OverflowDisconnect falls behind.
+type Subscriber struct {
+	C <-chan Event
+
+	name    string
+	c       chan Event
+	policy  OverflowPolicy
+	filter  EventFilter
+	bus     *Bus
+	dropped int
+
+	// mtx serializes sends with close, done unblocks waiting publishers
+	mtx       sync.Mutex
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewBus(log *slog.Logger) *Bus {
+	return &Bus{
+		log:         log,
+		subscribers: map[*Subscriber]struct{}{},
+	}
+}
+
+func (b *Bus) Subscribe(name string, size int, policy OverflowPolicy, filter EventFilter) *Subscriber {
+	c := make(chan Event, size)
+	s := &Subscriber{
+		C:      c,
+		name:   name,
+		c:      c,
+		policy: policy,
+		filter: filter,
+		bus:    b,
+		done:   make(chan struct{}),
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.subscribers[s] = struct{}{}
+	return s
+}
+
+func (b *Bus) Publish(e Event) {
+	b.mtx.RLock()
+	subscribers := make([]*Subscriber, 0, len(b.subscribers))
+	for s := range b.subscribers {
+		if s.filter == nil || s.filter(e) {
+			subscribers = append(subscribers, s)
+		}
+	}
+	b.mtx.RUnlock()
+
+	for _, s := range subscribers {
+		s.deliver(e)
+	}
+}
+
+func (s *Subscriber) Name() string {
+	return s.name
+}
+
+// Len returns the number of queued events.
+func (s *Subscriber) Len() int {
+	return len(s.c)
+}
+
+// Dropped returns the number of events lost to the overflow policy.
+func (s *Subscriber) Dropped() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.dropped
+}
+
+func (s *Subscriber) Close() {
+	if s.bus == nil {
+		return
+	}
+
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+
+	s.bus.mtx.Lock()
+	delete(s.bus.subscribers, s)
+	s.bus.mtx.Unlock()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.c)
+	}
+}
+
+func (s *Subscriber) deliver(e Event) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.c <- e:
+		return
+	default:
+	}
+
+	switch s.policy {
+	case OverflowBlock:
+		select {
+		case s.c <- e:
+		case <-s.done:
+		}
+	case OverflowDropNewest:
+		s.dropped++
+	case OverflowDropOldest:
+		select {
+		case <-s.c:
+			s.dropped++
+		default:
+		}
+		s.c <- e
+	case OverflowDisconnect:
+		s.bus.log.Warn("disconnecting slow subscriber", "subscriber", s.name)
+		s.dropped++
+		s.closeOnce.Do(func() {
+			close(s.done)
+		})
+		s.closed = true
+		close(s.c)
+		s.bus.remove(s)
+	}
+}
+
+func (b *Bus) remove(s *Subscriber) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	delete(b.subscribers, s)
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Bus_Filter(t *testing.T) {
+	bus := NewBus(slog.Default())
+	blocks := bus.Subscribe("blocks", 2, OverflowBlock, OfKind(KindBlockIngested))
+	all := bus.Subscribe("all", 2, OverflowBlock, nil)
+
+	bus.Publish(&BlockIngested{Block: &Block{NumberParsed: 1}})
+	bus.Publish(&SubscriptionChanged{Address: "0x1111", Subscribed: true})
+
+	assert.Equal(t, 1, blocks.Len())
+	assert.Equal(t, 2, all.Len())
+	assert.Equal(t, "blocks", blocks.Name())
+}
+
+func Test_Bus_OverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      OverflowPolicy
+		wantBlocks  []int
+		wantDropped int
+	}{
+		{
+			name:        "drop newest",
+			policy:      OverflowDropNewest,
+			wantBlocks:  []int{1, 2},
+			wantDropped: 1,
+		},
+		{
+			name:        "drop oldest",
+			policy:      OverflowDropOldest,
+			wantBlocks:  []int{2, 3},
+			wantDropped: 1,
+		},
+		{
+			name:        "disconnect",
+			policy:      OverflowDisconnect,
+			wantBlocks:  []int{1, 2},
+			wantDropped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewBus(slog.Default())
+			s := bus.Subscribe("test", 2, tt.policy, nil)
+
+			for i := 1; i <= 3; i++ {
+				bus.Publish(&BlockIngested{Block: &Block{NumberParsed: i}})
+			}
+			s.Close()
+
+			blocks := []int{}
+			for e := range s.C {
+				blocks = append(blocks, e.(*BlockIngested).Block.NumberParsed)
+			}
+			assert.Equal(t, tt.wantBlocks, blocks)
+			assert.Equal(t, tt.wantDropped, s.Dropped())
+		})
+	}
+}
+
+func Test_Bus_OverflowBlockWaitsForConsumer(t *testing.T) {
+	bus := NewBus(slog.Default())
+	s := bus.Subscribe("test", 1, OverflowBlock, nil)
+
+	bus.Publish(&BlockIngested{Block: &Block{NumberParsed: 1}})
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(&BlockIngested{Block: &Block{NumberParsed: 2}})
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		assert.Fail(t, "publish should wait for the consumer")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	<-s.C
+	<-published
+	assert.Equal(t, 1, s.Len())
+}
+
+func Test_Bus_CloseUnblocksPublisher(t *testing.T) {
+	bus := NewBus(slog.Default())
+	s := bus.Subscribe("test", 1, OverflowBlock, nil)
+
+	bus.Publish(&BlockIngested{Block: &Block{NumberParsed: 1}})
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(&BlockIngested{Block: &Block{NumberParsed: 2}})
+		close(published)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	s.Close()
+	<-published
+
+	bus.Publish(&BlockIngested{Block: &Block{NumberParsed: 3}})
+	assert.Empty(t, bus.subscribers)
+}
@@ -0,0 +1,351 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/tracing"
+)
+
+const (
+	// maxCachedNames bounds the primary name cache, which is emptied of
+	// expired names when full, then of any names
+	maxCachedNames = 10_000
+	// nameLookups is how many counterparties are looked up at once
+	nameLookups = 8
+	// maxNameLookups and nameLookupTimeout bound the lookups of a request,
+	// whose other counterparties are shown without names until a later
+	// request looks them up
+	maxNameLookups    = 64
+	nameLookupTimeout = 2 * time.Second
+	// failedNameTTL is how long a failed lookup gives an empty name before
+	// the address is looked up again, so a broken node or record is not asked
+	// on every request
+	failedNameTTL = time.Minute
+)
+
+var (
+	ErrNameNotFound     = errors.New("ENS name not found")
+	ErrNamesUnavailable = errors.New("ENS resolution unavailable")
+)
+
+// NameResolver resolves ENS names with the node.
+type NameResolver interface {
+	// ResolveName returns the lower case address of a name, or fails with
+	// ErrNameNotFound.
+	ResolveName(ctx context.Context, name string) (string, error)
+	// LookupAddress returns the primary name of an address, empty when it
+	// has none.
+	LookupAddress(ctx context.Context, address string) (string, error)
+}
+
+// IsName reports whether a subscription target is an ENS name like
+// vitalik.eth rather than an address.
+func IsName(target string) bool {
+	return strings.Contains(target, ".") && !strings.HasPrefix(target, "0x")
+}
+
+type cachedName struct {
+	name    string
+	expires time.Time
+}
+
+// Names resolves the ENS names of subscriptions and caches the primary names
+// of counterparties shown with transactions, including addresses without one.
+type Names struct {
+	mtx      sync.Mutex
+	log      *slog.Logger
+	resolver NameResolver
+	now      func() time.Time
+	cache    map[string]cachedName
+	ttl      time.Duration
+	// lookupTimeout bounds the lookups of one annotate call
+	lookupTimeout time.Duration
+
+	interval     time.Duration
+	reconfigured chan struct{}
+}
+
+func NewNames(log *slog.Logger, cfg *config.Config, resolver NameResolver) *Names {
+	return &Names{
+		log:           log,
+		resolver:      resolver,
+		now:           time.Now,
+		cache:         map[string]cachedName{},
+		ttl:           cfg.ENSCacheTTL,
+		lookupTimeout: nameLookupTimeout,
+		interval:      cfg.ENSResolveInterval,
+		reconfigured:  make(chan struct{}, 1),
+	}
+}
+
+// Reconfigure applies a new cache TTL to names looked up from now on and a
+// new resolve interval from the next one.
+func (n *Names) Reconfigure(cfg *config.Config) {
+	n.mtx.Lock()
+	n.ttl = cfg.ENSCacheTTL
+	n.interval = cfg.ENSResolveInterval
+	n.mtx.Unlock()
+
+	select {
+	case n.reconfigured <- struct{}{}:
+	default:
+	}
+}
+
+func (n *Names) resolveInterval() time.Duration {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	return n.interval
+}
+
+// Resolve returns the address a name points to now.
+func (n *Names) Resolve(ctx context.Context, name string) (string, error) {
+	address, err := n.resolver.ResolveName(ctx, strings.ToLower(name))
+	if err != nil && !errors.Is(err, ErrNameNotFound) {
+		return "", fmt.Errorf("%w: %w", ErrNodeUnavailable, err)
+	}
+	return address, err
+}
+
+// Lookup returns the primary name of an address, from the cache while it is
+// fresh. Failed lookups give an empty name, cached for failedNameTTL unless
+// the context ended them.
+func (n *Names) Lookup(ctx context.Context, address string) string {
+	key := strings.ToLower(address)
+	if name, exists := n.cached(key); exists {
+		return name
+	}
+
+	name, err := n.resolver.LookupAddress(ctx, key)
+	if err != nil && ctx.Err() != nil {
+		return ""
+	}
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	ttl := n.ttl
+	if err != nil {
+		n.log.Error("error looking up ENS name", "address", address, "error", err)
+		ttl = min(ttl, failedNameTTL)
+	}
+	now := n.now()
+	if len(n.cache) >= maxCachedNames {
+		for cachedAddress, cached := range n.cache {
+			if !now.Before(cached.expires) {
+				delete(n.cache, cachedAddress)
+			}
+		}
+		// without expired names any one makes room, rather than starting over
+		for cachedAddress := range n.cache {
+			if len(n.cache) < maxCachedNames {
+				break
+			}
+			delete(n.cache, cachedAddress)
+		}
+	}
+	n.cache[key] = cachedName{name: name, expires: now.Add(ttl)}
+	return name
+}
+
+// cached returns the primary name of an address while it is fresh.
+func (n *Names) cached(key string) (string, bool) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	cached, exists := n.cache[key]
+	if !exists || !n.now().Before(cached.expires) {
+		return "", false
+	}
+	return cached.name, true
+}
+
+// annotate returns copies of the transactions of an address with the primary
+// names of their counterparties. Cached names are always shown, and at most
+// maxNameLookups others are looked up within the lookup timeout.
+func (n *Names) annotate(ctx context.Context, address string, txs []*Transaction) []*Transaction {
+	ctx, span := tracing.Start(ctx, "Names.annotate")
+	defer span.End()
+
+	byAddress := map[string]string{}
+	pending := []string{}
+	for _, tx := range txs {
+		for _, party := range []string{tx.From, tx.To} {
+			if _, seen := byAddress[party]; seen || party == "" || strings.EqualFold(party, address) {
+				continue
+			}
+			name, exists := n.cached(strings.ToLower(party))
+			if !exists && len(pending) < maxNameLookups {
+				pending = append(pending, party)
+			}
+			byAddress[party] = name
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.lookupTimeout)
+	defer cancel()
+	names := make([]string, len(pending))
+	g := errgroup.Group{}
+	g.SetLimit(nameLookups)
+	for i, party := range pending {
+		g.Go(func() error {
+			names[i] = n.Lookup(ctx, party)
+			return nil
+		})
+	}
+	_ = g.Wait()
+	for i, party := range pending {
+		byAddress[party] = names[i]
+	}
+
+	annotated := make([]*Transaction, len(txs))
+	for i, tx := range txs {
+		c := *tx
+		if !strings.EqualFold(c.From, address) {
+			c.FromName = byAddress[c.From]
+		}
+		if !strings.EqualFold(c.To, address) {
+			c.ToName = byAddress[c.To]
+		}
+		annotated[i] = &c
+	}
+	return annotated
+}
+
+// WithNames resolves ENS names of subscriptions and counterparties with n.
+func WithNames(n *Names) ServiceOption {
+	return func(s *Service) {
+		s.names = n
+	}
+}
+
+// SubscribeName subscribes the address an ENS name points to. The
+// subscription follows the name when it moves, see WatchNames.
+func (s *Service) SubscribeName(ctx context.Context, name string) (*Subscription, error) {
+	if s.names == nil {
+		return nil, ErrNamesUnavailable
+	}
+	name = strings.ToLower(name)
+	address, err := s.names.Resolve(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	defer s.publishQueued()
+	defer s.mtx.Unlock()
+
+	t := s.writeTenant(ctx)
+	if err := s.checkSubscriptionQuota(t, address); err != nil {
+		return nil, err
+	}
+	s.subscribe(t, address)
+	sub := t.subscriptions[address]
+	sub.Name = name
+	return sub.snapshot(), nil
+}
+
+// WatchNames resolves the ENS names of subscriptions again every interval.
+// When a name points to a new address, the new address is subscribed with
+// the metadata and rules of the old one, which keeps its history but no
+// longer follows the name, and NameChanged is published.
+func (s *Service) WatchNames(ctx context.Context) error {
+	if s.names == nil {
+		<-ctx.Done()
+		return nil
+	}
+	s.log.Info("starting ENS name watcher")
+
+	ticker := time.NewTicker(s.names.resolveInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.names.reconfigured:
+			ticker.Reset(s.names.resolveInterval())
+		case <-ticker.C:
+			s.refreshNames(ctx)
+		}
+	}
+}
+
+type namedSubscription struct {
+	tenant  string
+	address string
+	name    string
+}
+
+func (s *Service) refreshNames(ctx context.Context) {
+	ctx, span := tracing.Start(ctx, "Service.refreshNames")
+	defer span.End()
+
+	s.mtx.RLock()
+	named := []namedSubscription{}
+	for _, t := range s.tenants {
+		for address, sub := range t.subscriptions {
+			if sub.Name != "" {
+				named = append(named, namedSubscription{tenant: t.id, address: address, name: sub.Name})
+			}
+		}
+	}
+	s.mtx.RUnlock()
+
+	resolved := map[string]string{}
+	for _, n := range named {
+		if _, exists := resolved[n.name]; exists {
+			continue
+		}
+		address, err := s.names.Resolve(ctx, n.name)
+		if err != nil {
+			// a name that lost its address keeps its last one
+			s.log.Error("error resolving ENS name", "name", n.name, "error", err)
+		}
+		resolved[n.name] = address
+	}
+
+	for _, n := range named {
+		if address := resolved[n.name]; address != "" && address != n.address {
+			s.moveName(n, address)
+		}
+	}
+}
+
+func (s *Service) moveName(n namedSubscription, address string) {
+	s.mtx.Lock()
+	defer s.publishQueued()
+	defer s.mtx.Unlock()
+
+	t, exists := s.tenants[n.tenant]
+	if !exists {
+		return
+	}
+	previous, exists := t.subscriptions[n.address]
+	if !exists || previous.Name != n.name {
+		return
+	}
+	if err := s.checkSubscriptionQuota(t, address); err != nil {
+		s.log.Warn("ENS name moved past the subscription quota", "tenant", t.id, "name", n.name, "address", address, "error", err)
+		return
+	}
+
+	if s.subscribe(t, address) {
+		next := t.subscriptions[address]
+		next.SubscriptionMetadata = previous.SubscriptionMetadata
+		next.Rules = previous.Rules
+	}
+	t.subscriptions[address].Name = n.name
+	previous.Name = ""
+	s.log.Info("ENS name moved", "tenant", t.id, "name", n.name, "address", address, "previous", n.address)
+	s.queue(&NameChanged{Tenant: t.id, Name: n.name, Address: address, Previous: n.address})
+}
@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deshev.com/eth-address-watch/config"
+)
+
+// fakeResolver has the addresses of names and the primary names of
+// addresses, and counts lookups. Lookups of 0x5105 wait for the context.
+type fakeResolver struct {
+	mtx       sync.Mutex
+	addresses map[string]string
+	names     map[string]string
+	lookups   int
+}
+
+func (f *fakeResolver) ResolveName(_ context.Context, name string) (string, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	address, exists := f.addresses[name]
+	if !exists {
+		return "", ErrNameNotFound
+	}
+	return address, nil
+}
+
+func (f *fakeResolver) LookupAddress(ctx context.Context, address string) (string, error) {
+	if address == "0x5105" {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.lookups++
+	if address == "0xdead" {
+		return "", errors.New("node down")
+	}
+	return f.names[address], nil
+}
+
+func (f *fakeResolver) point(name, address string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.addresses[name] = address
+}
+
+func newTestNames(resolver *fakeResolver) *Names {
+	return NewNames(slog.Default(), config.Default(), resolver)
+}
+
+func Test_IsName(t *testing.T) {
+	assert.True(t, IsName("vitalik.eth"))
+	assert.True(t, IsName("pay.example.xyz"))
+	assert.False(t, IsName("0xd8da6bf26964af9d7eed9e03e53415d37aa96045"))
+	assert.False(t, IsName("vitalik"))
+}
+
+func Test_SubscribeName(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	bus := NewBus(log)
+
+	_, err := NewService(log, bus).SubscribeName(ctx, "vitalik.eth")
+	assert.ErrorIs(t, err, ErrNamesUnavailable)
+
+	resolver := &fakeResolver{addresses: map[string]string{"vitalik.eth": "0x1111"}}
+	s := NewService(log, bus, WithNames(newTestNames(resolver)))
+	sub, err := s.SubscribeName(ctx, "Vitalik.eth")
+	require.NoError(t, err)
+	assert.Equal(t, "0x1111", sub.Address)
+	assert.Equal(t, "vitalik.eth", sub.Name)
+	require.NoError(t, s.SetRules(ctx, "0x1111", []*Rule{{Direction: DirectionIn}}))
+	require.NoError(t, s.SetMetadata(ctx, "0x1111", SubscriptionMetadata{Label: "vitalik"}))
+
+	_, err = s.SubscribeName(ctx, "nobody.eth")
+	assert.ErrorIs(t, err, ErrNameNotFound)
+
+	// the name moves, and the subscription follows
+	_, listener, err := s.Listen(ctx, "0x1111", 0)
+	require.NoError(t, err)
+	defer listener.Close()
+	s.refreshNames(ctx)
+	resolver.point("vitalik.eth", "0x2222")
+	s.refreshNames(ctx)
+
+	select {
+	case e := <-listener.C:
+		assert.Equal(t, &NameChanged{Name: "vitalik.eth", Address: "0x2222", Previous: "0x1111"}, e)
+	case <-time.After(time.Second):
+		t.Fatal("no name change published")
+	}
+	moved, err := s.GetSubscription(ctx, "0x2222")
+	require.NoError(t, err)
+	assert.Equal(t, "vitalik.eth", moved.Name)
+	assert.Equal(t, "vitalik", moved.Label)
+	assert.Len(t, moved.Rules, 1)
+	previous, err := s.GetSubscription(ctx, "0x1111")
+	require.NoError(t, err)
+	assert.Empty(t, previous.Name)
+
+	// a name that stops resolving keeps its address
+	delete(resolver.addresses, "vitalik.eth")
+	s.refreshNames(ctx)
+	_, err = s.GetSubscription(ctx, "0x2222")
+	assert.NoError(t, err)
+	assert.Len(t, s.ListSubscriptions(ctx, SubscriptionFilter{}), 2)
+}
+
+func Test_GetTransactions_Names(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	resolver := &fakeResolver{names: map[string]string{"0x2222": "alice.eth"}}
+	names := newTestNames(resolver)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	names.now = func() time.Time { return now }
+	s := NewService(log, NewBus(log), WithNames(names))
+
+	_, err := s.Subscribe(ctx, "0x1111")
+	require.NoError(t, err)
+	s.processBlock(ctx, &Block{NumberParsed: 1, Transactions: []*Transaction{
+		{Hash: "0xa", From: "0x2222", To: "0x1111"},
+		{Hash: "0xb", From: "0x1111", To: "0x2222"},
+		{Hash: "0xc", From: "0x1111", To: "0x3333"},
+		{Hash: "0xd", From: "0xdead", To: "0x1111"},
+	}})
+
+	txs := s.GetTransactions(ctx, "0x1111")
+	require.Len(t, txs, 4)
+	assert.Equal(t, "alice.eth", txs[0].FromName)
+	assert.Empty(t, txs[0].ToName)
+	assert.Equal(t, "alice.eth", txs[1].ToName)
+	assert.Empty(t, txs[2].ToName)
+	assert.Empty(t, txs[3].FromName)
+	assert.Equal(t, 3, resolver.lookups)
+	// the stored transactions are not changed
+	assert.Empty(t, s.readTenant(ctx).store["0x1111"][0].FromName)
+
+	// names are cached, addresses without one too, failed lookups for a
+	// shorter time
+	s.GetTransactions(ctx, "0x1111")
+	assert.Equal(t, 3, resolver.lookups)
+	now = now.Add(2 * failedNameTTL)
+	s.GetTransactions(ctx, "0x1111")
+	assert.Equal(t, 4, resolver.lookups)
+	now = now.Add(2 * time.Hour)
+	s.GetTransactions(ctx, "0x1111")
+	assert.Equal(t, 7, resolver.lookups)
+}
+
+func Test_Names_AnnotateBudget(t *testing.T) {
+	ctx := context.Background()
+	resolver := &fakeResolver{names: map[string]string{}}
+	names := newTestNames(resolver)
+	names.lookupTimeout = 10 * time.Millisecond
+
+	txs := []*Transaction{}
+	for i := range maxNameLookups + 10 {
+		txs = append(txs, &Transaction{From: fmt.Sprintf("0x%04d", i), To: "0x1111"})
+	}
+	resolver.names["0x0000"] = "alice.eth"
+	resolver.names[fmt.Sprintf("0x%04d", maxNameLookups)] = "bob.eth"
+
+	// counterparties past the budget are looked up by the next request
+	annotated := names.annotate(ctx, "0x1111", txs)
+	assert.Equal(t, maxNameLookups, resolver.lookups)
+	assert.Equal(t, "alice.eth", annotated[0].FromName)
+	assert.Empty(t, annotated[maxNameLookups].FromName)
+	annotated = names.annotate(ctx, "0x1111", txs)
+	assert.Equal(t, maxNameLookups+10, resolver.lookups)
+	assert.Equal(t, "alice.eth", annotated[0].FromName)
+	assert.Equal(t, "bob.eth", annotated[maxNameLookups].FromName)
+
+	// a slow lookup is cut off and tried again by the next request
+	slow := []*Transaction{{From: "0x5105", To: "0x1111"}}
+	annotated = names.annotate(ctx, "0x1111", slow)
+	assert.Empty(t, annotated[0].FromName)
+	_, cached := names.cached("0x5105")
+	assert.False(t, cached)
+}
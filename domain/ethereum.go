@@ -1,13 +1,26 @@
 package domain
 
+import (
+	"math/big"
+	"time"
+)
+
+const (
+	TxStatusSuccess = "0x1"
+	TxStatusFailed  = "0x0"
+)
+
 type Block struct {
 	Number       string         `json:"number"`
 	NumberParsed int            `json:"-"`
 	Hash         string         `json:"hash"`
+	ParentHash   string         `json:"parentHash"`
+	Timestamp    string         `json:"timestamp,omitempty"`
 	Transactions []*Transaction `json:"transactions"`
 }
 
 type Transaction struct {
+	Hash        string `json:"hash,omitempty"`
 	BlockNumber string `json:"blockNumber"`
 	From        string `json:"from,omitempty"`
 	To          string `json:"to,omitempty"`
@@ -15,4 +28,58 @@ type Transaction struct {
 	Gas         string `json:"gas,omitempty"`
 	GasPrice    string `json:"gasPrice,omitempty"`
 	Input       string `json:"input,omitempty"`
+	// Status comes from the transaction receipt and is empty until one is fetched.
+	Status string `json:"status,omitempty"`
+	// BlockTimestamp is copied from the block, as the node does not send it
+	// with transactions.
+	BlockTimestamp string `json:"blockTimestamp,omitempty"`
+	// FromName and ToName are the primary ENS names of the counterparty,
+	// only set in transaction lists.
+	FromName string `json:"fromName,omitempty"`
+	ToName   string `json:"toName,omitempty"`
+}
+
+// Receipt is the outcome of a mined transaction.
+type Receipt struct {
+	Status            string `json:"status"`
+	GasUsed           string `json:"gasUsed"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	// blob transactions also pay for their blobs
+	BlobGasUsed  string `json:"blobGasUsed,omitempty"`
+	BlobGasPrice string `json:"blobGasPrice,omitempty"`
+}
+
+// Log is an event emitted by a contract.
+type Log struct {
+	Address         string   `json:"address"`
+	Topics          []string `json:"topics"`
+	Data            string   `json:"data"`
+	BlockNumber     string   `json:"blockNumber"`
+	TransactionHash string   `json:"transactionHash"`
+	LogIndex        string   `json:"logIndex"`
+	// Removed is set on logs of blocks dropped by a reorg
+	Removed bool `json:"removed,omitempty"`
+}
+
+// Fee is the wei the sender paid for gas, whether the transaction succeeded
+// or not.
+func (r *Receipt) Fee() *big.Int {
+	fee := new(big.Int).Mul(HexToBig(r.GasUsed), HexToBig(r.EffectiveGasPrice))
+	return fee.Add(fee, new(big.Int).Mul(HexToBig(r.BlobGasUsed), HexToBig(r.BlobGasPrice)))
+}
+
+// Time is when the block of the transaction was produced, zero when unknown.
+func (tx *Transaction) Time() time.Time {
+	if tx.BlockTimestamp == "" {
+		return time.Time{}
+	}
+	return time.Unix(HexToBig(tx.BlockTimestamp).Int64(), 0).UTC()
+}
+
+// stampTransactions copies the block timestamp to its transactions. It must
+// be called before the block is published.
+func (b *Block) stampTransactions() {
+	for _, tx := range b.Transactions {
+		tx.BlockTimestamp = b.Timestamp
+	}
 }
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"go.opentelemetry.io/otel/trace"
+)
+
+type EventKind string
+
+const (
+	KindBlockIngested       EventKind = "blockIngested"
+	KindTransactionMatched  EventKind = "transactionMatched"
+	KindReorgDetected       EventKind = "reorgDetected"
+	KindSubscriptionChanged EventKind = "subscriptionChanged"
+	KindNameChanged         EventKind = "nameChanged"
+)
+
+type Event interface {
+	Kind() EventKind
+}
+
+// BlockIngested is published by the watcher for every block fetched from the node.
+// SpanContext is the tick that fetched the block, so processing the block
+// joins its trace.
+type BlockIngested struct {
+	Block       *Block
+	SpanContext trace.SpanContext
+}
+
+// TransactionMatched is published by the service for every transaction
+// involving a subscribed address that matches its rules. The ID is the 1-based
+// position of the transaction in the address history kept by the store, so
+// clients can resume a stream from the last ID they have seen. Replayed
+// matches were published before and come again from Replay.
+type TransactionMatched struct {
+	Tenant       string        `json:"-"`
+	ID           int           `json:"id"`
+	Address      string        `json:"address"`
+	Transaction  *Transaction  `json:"transaction"`
+	Subscription *Subscription `json:"subscription,omitempty"`
+	Replayed     bool          `json:"replayed,omitempty"`
+}
+
+// ReorgDetected is published by the watcher when a block does not build on
+// the hash of the block it saw before it.
+type ReorgDetected struct {
+	BlockNumber        int    `json:"blockNumber"`
+	ParentHash         string `json:"parentHash"`
+	ExpectedParentHash string `json:"expectedParentHash"`
+}
+
+type SubscriptionChanged struct {
+	Tenant     string `json:"-"`
+	Address    string `json:"address"`
+	Subscribed bool   `json:"subscribed"`
+}
+
+// NameChanged is published when the ENS name of a subscription points to a
+// new address, which the subscription moved to from Previous.
+type NameChanged struct {
+	Tenant   string `json:"-"`
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	Previous string `json:"previous"`
+}
+
+func (*BlockIngested) Kind() EventKind       { return KindBlockIngested }
+func (*TransactionMatched) Kind() EventKind  { return KindTransactionMatched }
+func (*ReorgDetected) Kind() EventKind       { return KindReorgDetected }
+func (*SubscriptionChanged) Kind() EventKind { return KindSubscriptionChanged }
+func (*NameChanged) Kind() EventKind         { return KindNameChanged }
+
+// OfKind accepts events of any of the given kinds.
+func OfKind(kinds ...EventKind) EventFilter {
+	return func(e Event) bool {
+		for _, kind := range kinds {
+			if e.Kind() == kind {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,200 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrGroupNotFound = errors.New("group not found")
+	ErrGroupExists   = errors.New("group already exists")
+	ErrInvalidGroup  = errors.New("invalid group")
+
+	groupNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+)
+
+// Group is a named watchlist of addresses that are subscribed and queried
+// together.
+type Group struct {
+	Name      string    `json:"name"`
+	Members   []string  `json:"members"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GroupTransaction is a transaction of a group member. Internal is set for
+// transfers between two members of the same group.
+type GroupTransaction struct {
+	*Transaction
+	Internal bool `json:"internal"`
+}
+
+func (g *Group) snapshot() *Group {
+	c := *g
+	c.Members = slices.Clone(g.Members)
+	return &c
+}
+
+func (g *Group) hasMember(address string) bool {
+	return slices.ContainsFunc(g.Members, func(member string) bool {
+		return strings.EqualFold(member, address)
+	})
+}
+
+// CreateGroup creates a group and subscribes all of its members.
+func (s *Service) CreateGroup(ctx context.Context, name string, members []string) (*Group, error) {
+	if !groupNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: name must be 1-64 letters, digits, dots, dashes or underscores", ErrInvalidGroup)
+	}
+	if err := validateMembers(members); err != nil {
+		return nil, err
+	}
+	members = normalizeAddresses(members)
+
+	s.mtx.Lock()
+	defer s.publishQueued()
+	defer s.mtx.Unlock()
+
+	t := s.writeTenant(ctx)
+	if _, exists := t.groups[name]; exists {
+		return nil, ErrGroupExists
+	}
+	if err := s.checkSubscriptionQuota(t, members...); err != nil {
+		return nil, err
+	}
+
+	group := &Group{Name: name, Members: []string{}, CreatedAt: time.Now().UTC()}
+	t.groups[name] = group
+	s.addMembers(t, group, members)
+	return group.snapshot(), nil
+}
+
+func (s *Service) DeleteGroup(ctx context.Context, name string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	t := s.readTenant(ctx)
+	if _, exists := t.groups[name]; !exists {
+		return ErrGroupNotFound
+	}
+	delete(t.groups, name)
+	return nil
+}
+
+func (s *Service) GetGroup(ctx context.Context, name string) (*Group, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	group, exists := s.readTenant(ctx).groups[name]
+	if !exists {
+		return nil, ErrGroupNotFound
+	}
+	return group.snapshot(), nil
+}
+
+// ListGroups returns all groups ordered by name.
+func (s *Service) ListGroups(ctx context.Context) []*Group {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	result := []*Group{}
+	for _, group := range s.readTenant(ctx).groups {
+		result = append(result, group.snapshot())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// UpdateGroupMembers adds and removes group members. New members are
+// subscribed; removed members stay subscribed on their own.
+func (s *Service) UpdateGroupMembers(ctx context.Context, name string, add, remove []string) (*Group, error) {
+	if err := validateMembers(add); err != nil {
+		return nil, err
+	}
+	add = normalizeAddresses(add)
+
+	s.mtx.Lock()
+	defer s.publishQueued()
+	defer s.mtx.Unlock()
+
+	t := s.readTenant(ctx)
+	group, exists := t.groups[name]
+	if !exists {
+		return nil, ErrGroupNotFound
+	}
+	if err := s.checkSubscriptionQuota(t, add...); err != nil {
+		return nil, err
+	}
+
+	group.Members = slices.DeleteFunc(group.Members, func(member string) bool {
+		return slices.ContainsFunc(remove, func(address string) bool {
+			return strings.EqualFold(member, address)
+		})
+	})
+	s.addMembers(t, group, add)
+	return group.snapshot(), nil
+}
+
+// GetGroupTransactions merges the transactions of all group members, without
+// duplicates and ordered by block.
+func (s *Service) GetGroupTransactions(ctx context.Context, name string) ([]*GroupTransaction, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	t := s.readTenant(ctx)
+	group, exists := t.groups[name]
+	if !exists {
+		return nil, ErrGroupNotFound
+	}
+
+	seen := map[any]struct{}{}
+	result := []*GroupTransaction{}
+	for _, member := range group.Members {
+		for _, tx := range t.store[member] {
+			var key any = tx
+			if tx.Hash != "" {
+				key = tx.Hash
+			}
+			if _, duplicate := seen[key]; duplicate {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			result = append(result, &GroupTransaction{
+				Transaction: tx,
+				Internal:    group.hasMember(tx.From) && group.hasMember(tx.To),
+			})
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return HexToBig(result[i].BlockNumber).Cmp(HexToBig(result[j].BlockNumber)) < 0
+	})
+	return result, nil
+}
+
+func (s *Service) addMembers(t *tenantState, group *Group, members []string) {
+	for _, member := range members {
+		if group.hasMember(member) {
+			continue
+		}
+		group.Members = append(group.Members, member)
+		s.subscribe(t, member)
+	}
+}
+
+func validateMembers(members []string) error {
+	for _, member := range members {
+		if member == "" {
+			return fmt.Errorf("%w: member address missing", ErrInvalidGroup)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CreateGroup(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+
+	group, err := s.CreateGroup(ctx, "exchange-hot-wallets", []string{"0x1111", "0x2222", "0x1111"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x1111", "0x2222"}, group.Members)
+	assert.Equal(t, 2, len(s.ListSubscriptions(ctx, SubscriptionFilter{})))
+
+	_, err = s.CreateGroup(ctx, "exchange-hot-wallets", nil)
+	assert.ErrorIs(t, err, ErrGroupExists)
+	_, err = s.CreateGroup(ctx, "no spaces", nil)
+	assert.ErrorIs(t, err, ErrInvalidGroup)
+	_, err = s.CreateGroup(ctx, "empty-member", []string{""})
+	assert.ErrorIs(t, err, ErrInvalidGroup)
+
+	group, err = s.UpdateGroupMembers(ctx, "exchange-hot-wallets", []string{"0x3333"}, []string{"0x1111"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x2222", "0x3333"}, group.Members)
+	assert.Equal(t, 3, len(s.ListSubscriptions(ctx, SubscriptionFilter{})))
+
+	_, err = s.UpdateGroupMembers(ctx, "missing", nil, nil)
+	assert.ErrorIs(t, err, ErrGroupNotFound)
+
+	assert.Equal(t, 1, len(s.ListGroups(ctx)))
+	assert.NoError(t, s.DeleteGroup(ctx, "exchange-hot-wallets"))
+	_, err = s.GetGroup(ctx, "exchange-hot-wallets")
+	assert.ErrorIs(t, err, ErrGroupNotFound)
+	assert.ErrorIs(t, s.DeleteGroup(ctx, "exchange-hot-wallets"), ErrGroupNotFound)
+}
+
+func Test_GetGroupTransactions(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+
+	_, err := s.CreateGroup(ctx, "hot", []string{"0x1111", "0x2222"})
+	assert.NoError(t, err)
+
+	s.processBlock(context.Background(), &Block{
+		NumberParsed: 0x11,
+		Transactions: []*Transaction{
+			{Hash: "0xa", BlockNumber: "0x11", From: "0x1111", To: "0x2222"},
+			{Hash: "0xb", BlockNumber: "0x11", From: "0x3333", To: "0x2222"},
+		},
+	})
+	s.processBlock(context.Background(), &Block{
+		NumberParsed: 0x12,
+		Transactions: []*Transaction{
+			{Hash: "0xc", BlockNumber: "0x12", From: "0x1111", To: "0x4444"},
+		},
+	})
+
+	txs, err := s.GetGroupTransactions(ctx, "hot")
+	assert.NoError(t, err)
+
+	hashes := []string{}
+	internal := []bool{}
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash)
+		internal = append(internal, tx.Internal)
+	}
+	assert.Equal(t, []string{"0xa", "0xb", "0xc"}, hashes)
+	assert.Equal(t, []bool{true, false, false}, internal)
+
+	_, err = s.GetGroupTransactions(ctx, "missing")
+	assert.ErrorIs(t, err, ErrGroupNotFound)
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"deshev.com/eth-address-watch/health"
+)
+
+const storePingInterval = 10 * time.Millisecond
+
+var ErrStoreUnavailable = errors.New("store unavailable")
+
+// CheckIngestion fails once lastBlock has not advanced for
+// config.ReadyMaxStaleTicks ticks in a row, as happens when the node is
+// unreachable or stuck. A limit of 0 disables the check.
+func (w *Watcher) CheckIngestion(_ context.Context) (string, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	maxStaleTicks := w.config.ReadyMaxStaleTicks
+	if maxStaleTicks > 0 && w.staleTicks >= maxStaleTicks {
+		return "", fmt.Errorf("last block %d has not advanced for %d ticks", w.fetched, w.staleTicks)
+	}
+	return fmt.Sprintf("last block %d, %d ticks without a new block", w.fetched, w.staleTicks), nil
+}
+
+// Head is the latest block number the node reported.
+func (w *Watcher) Head() int {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	return w.head
+}
+
+// CheckLag fails when the node head is more than config.ReadyMaxBlockLag
+// blocks ahead of the last block processed by the service, including before
+// the first block is processed. A limit of 0 disables the check.
+func CheckLag(w *Watcher, s *Service) health.CheckFunc {
+	return func(context.Context) (string, error) {
+		maxLag := w.settings().ReadyMaxBlockLag
+		head, processed := w.Head(), s.GetCurrentBlock()
+		if lag := head - processed; maxLag > 0 && lag > maxLag {
+			return "", fmt.Errorf("head %d is %d blocks ahead of processed block %d", head, lag, processed)
+		}
+		return fmt.Sprintf("head %d, processed block %d", head, processed), nil
+	}
+}
+
+// CheckStore fails when the store cannot be read before ctx is done. The store
+// is in memory, so this only catches a service stuck holding its lock.
+func (s *Service) CheckStore(ctx context.Context) (string, error) {
+	for !s.mtx.TryRLock() {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("%w: %w", ErrStoreUnavailable, ctx.Err())
+		case <-time.After(storePingInterval):
+		}
+	}
+	defer s.mtx.RUnlock()
+
+	return fmt.Sprintf("in memory, %d tenants", len(s.tenants)), nil
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"deshev.com/eth-address-watch/config"
+)
+
+func Test_CheckIngestion(t *testing.T) {
+	log := slog.Default()
+	client := &MockETHClient{}
+	client.On("GetLatestBlock", mock.Anything).Return(0x11, nil).Once()
+	client.On("GetBlock", mock.Anything, 0x11).Return(&Block{Number: "0x11"}, nil)
+	client.On("GetLatestBlock", mock.Anything).Return(0, errors.New("node down"))
+	cfg := config.Default()
+	cfg.ReadyMaxStaleTicks = 2
+	w := NewWatcher(log, cfg, client, NewBus(log))
+	w.lastBlock = 0x10
+
+	w.tick()
+	detail, err := w.CheckIngestion(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "last block 17, 0 ticks without a new block", detail)
+
+	w.tick()
+	_, err = w.CheckIngestion(context.Background())
+	assert.NoError(t, err)
+
+	w.tick()
+	_, err = w.CheckIngestion(context.Background())
+	assert.EqualError(t, err, "last block 17 has not advanced for 2 ticks")
+
+	disabled := *cfg
+	disabled.ReadyMaxStaleTicks = 0
+	w.Reconfigure(&disabled)
+	_, err = w.CheckIngestion(context.Background())
+	assert.NoError(t, err)
+}
+
+func Test_CheckLag(t *testing.T) {
+	log := slog.Default()
+	w := NewWatcher(log, withMaxBlockLag(10), stubClient(t, 0x20, nil), NewBus(log))
+	s := NewService(log, NewBus(log))
+	w.head = 0x20
+	check := CheckLag(w, s)
+
+	_, err := check(context.Background())
+	assert.EqualError(t, err, "head 32 is 32 blocks ahead of processed block 0")
+
+	s.processBlock(context.Background(), &Block{NumberParsed: 0x16})
+	detail, err := check(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "head 32, processed block 22", detail)
+
+	w.Reconfigure(withMaxBlockLag(5))
+	_, err = check(context.Background())
+	assert.EqualError(t, err, "head 32 is 10 blocks ahead of processed block 22")
+	w.Reconfigure(withMaxBlockLag(0))
+	_, err = check(context.Background())
+	assert.NoError(t, err)
+}
+
+func withMaxBlockLag(maxLag int) *config.Config {
+	cfg := config.Default()
+	cfg.ReadyMaxBlockLag = maxLag
+	return cfg
+}
+
+func Test_CheckStore(t *testing.T) {
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+
+	_, err := s.CheckStore(context.Background())
+	assert.NoError(t, err)
+
+	s.mtx.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = s.CheckStore(ctx)
+	s.mtx.Unlock()
+	assert.ErrorIs(t, err, ErrStoreUnavailable)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
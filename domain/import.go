@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var (
+	ErrInvalidImport = errors.New("invalid import")
+
+	addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+)
+
+// SubscriptionImport is a single row of a bulk import. Importing an address
+// that is already subscribed replaces its metadata, and its rules when the
+// row has them.
+type SubscriptionImport struct {
+	Address string `json:"address"`
+	SubscriptionMetadata
+	// Rules is nil for rows without rules, like CSV rows, and empty to
+	// remove the rules
+	Rules []*Rule `json:"rules,omitempty"`
+}
+
+type ImportResult struct {
+	Row     int    `json:"row"`
+	Address string `json:"address"`
+	Created bool   `json:"created"`
+	Error   string `json:"error,omitempty"`
+}
+
+func IsAddress(address string) bool {
+	return addressPattern.MatchString(address)
+}
+
+// ImportSubscriptions validates every row and applies the batch atomically:
+// either all rows are imported or, if any row is invalid, none of them is and
+// ErrInvalidImport is returned along with the per-row results.
+func (s *Service) ImportSubscriptions(ctx context.Context, rows []*SubscriptionImport) ([]*ImportResult, error) {
+	rows = normalizeImport(rows)
+	results, valid := validateImport(rows)
+	if !valid {
+		return results, ErrInvalidImport
+	}
+
+	s.mtx.Lock()
+	defer s.publishQueued()
+	defer s.mtx.Unlock()
+
+	t := s.writeTenant(ctx)
+	addresses := make([]string, len(rows))
+	for i, row := range rows {
+		addresses[i] = row.Address
+	}
+	if err := s.checkSubscriptionQuota(t, addresses...); err != nil {
+		return nil, err
+	}
+
+	for i, row := range rows {
+		results[i].Created = s.subscribe(t, row.Address)
+		sub := t.subscriptions[row.Address]
+		sub.SubscriptionMetadata = row.SubscriptionMetadata
+		if row.Rules != nil {
+			sub.Rules = row.Rules
+		}
+	}
+
+	s.log.Info("imported subscriptions", "count", len(rows))
+	return results, nil
+}
+
+// normalizeImport returns copies of the rows with their addresses and tags
+// in the form the service stores them. Empty rows are left for validation.
+func normalizeImport(rows []*SubscriptionImport) []*SubscriptionImport {
+	normalized := make([]*SubscriptionImport, len(rows))
+	for i, row := range rows {
+		if row == nil {
+			continue
+		}
+		c := *row
+		c.Address = normalizeAddress(c.Address)
+		c.Tags = cleanTags(c.Tags)
+		normalized[i] = &c
+	}
+	return normalized
+}
+
+func validateImport(rows []*SubscriptionImport) ([]*ImportResult, bool) {
+	results := make([]*ImportResult, len(rows))
+	seen := map[string]int{}
+	valid := true
+
+	for i, row := range rows {
+		if row == nil {
+			results[i] = &ImportResult{Row: i + 1, Error: "row is empty"}
+			valid = false
+			continue
+		}
+		results[i] = &ImportResult{Row: i + 1, Address: row.Address}
+		if err := validateImportRow(row, seen); err != nil {
+			results[i].Error = err.Error()
+			valid = false
+		}
+		seen[row.Address] = i + 1
+	}
+	return results, valid
+}
+
+func validateImportRow(row *SubscriptionImport, seen map[string]int) error {
+	if !IsAddress(row.Address) {
+		return fmt.Errorf("invalid address %q", row.Address)
+	}
+	if first, duplicate := seen[row.Address]; duplicate {
+		return fmt.Errorf("duplicate of row %d", first)
+	}
+	if len(row.Metadata) > 0 && !json.Valid(row.Metadata) {
+		return errors.New("metadata is not valid JSON")
+	}
+	for i, rule := range row.Rules {
+		if err := rule.Compile(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
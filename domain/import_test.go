@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ImportSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	s.Subscribe(ctx, "0x0000000000000000000000000000000000000001")
+	assert.NoError(t, s.SetRules(ctx, "0x0000000000000000000000000000000000000001", []*Rule{{Direction: DirectionOut}}))
+
+	results, err := s.ImportSubscriptions(ctx, []*SubscriptionImport{
+		{
+			Address:              "0x0000000000000000000000000000000000000001",
+			SubscriptionMetadata: SubscriptionMetadata{Label: "existing"},
+		},
+		{
+			Address:              "0x0000000000000000000000000000000000000002",
+			SubscriptionMetadata: SubscriptionMetadata{Tags: []string{"deposit"}},
+			Rules:                []*Rule{{Direction: DirectionIn}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*ImportResult{
+		{Row: 1, Address: "0x0000000000000000000000000000000000000001", Created: false},
+		{Row: 2, Address: "0x0000000000000000000000000000000000000002", Created: true},
+	}, results)
+
+	sub, err := s.GetSubscription(ctx, "0x0000000000000000000000000000000000000001")
+	assert.NoError(t, err)
+	assert.Equal(t, "existing", sub.Label)
+	// rows without rules keep the ones set before
+	assert.Equal(t, []*Rule{{Direction: DirectionOut}}, sub.Rules)
+	assert.Equal(t, 2, len(s.ListSubscriptions(ctx, SubscriptionFilter{})))
+	assert.Equal(t, 1, len(s.ListSubscriptions(ctx, SubscriptionFilter{Tag: "deposit"})))
+}
+
+func Test_ImportSubscriptions_RejectsWholeBatch(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+
+	results, err := s.ImportSubscriptions(ctx, []*SubscriptionImport{
+		{Address: "0x0000000000000000000000000000000000000001"},
+		{Address: "not-an-address"},
+		{Address: "0x0000000000000000000000000000000000000001"},
+		{
+			Address:              "0x0000000000000000000000000000000000000002",
+			SubscriptionMetadata: SubscriptionMetadata{Metadata: json.RawMessage(`{broken`)},
+		},
+		{Address: "0x0000000000000000000000000000000000000003", Rules: []*Rule{{Method: "transfer"}}},
+		nil,
+	})
+	assert.ErrorIs(t, err, ErrInvalidImport)
+
+	errors := []string{}
+	for _, result := range results {
+		errors = append(errors, result.Error)
+	}
+	assert.Equal(t, []string{
+		"",
+		`invalid address "not-an-address"`,
+		"duplicate of row 1",
+		"metadata is not valid JSON",
+		"rule 0: invalid rule: method must be a 4-byte hex selector like 0xa9059cbb",
+		"row is empty",
+	}, errors)
+	assert.Empty(t, s.ListSubscriptions(ctx, SubscriptionFilter{}))
+}
+
+func Test_ImportSubscriptions_EmptyRulesRemoveRules(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	s.Subscribe(ctx, "0x0000000000000000000000000000000000000001")
+	assert.NoError(t, s.SetRules(ctx, "0x0000000000000000000000000000000000000001", []*Rule{{Direction: DirectionOut}}))
+
+	_, err := s.ImportSubscriptions(ctx, []*SubscriptionImport{
+		{Address: "0x0000000000000000000000000000000000000001", Rules: []*Rule{}},
+	})
+	assert.NoError(t, err)
+
+	rules, err := s.GetRules(ctx, "0x0000000000000000000000000000000000000001")
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+func Test_ImportSubscriptions_LowersChecksummedAddresses(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+
+	rows := []*SubscriptionImport{{Address: "0xdAC17F958D2ee523a2206206994597C13D831ec7"}}
+	results, err := s.ImportSubscriptions(ctx, rows)
+	assert.NoError(t, err)
+	assert.Equal(t, "0xdac17f958d2ee523a2206206994597c13d831ec7", results[0].Address)
+	// the rows of the caller are not changed
+	assert.Equal(t, "0xdAC17F958D2ee523a2206206994597C13D831ec7", rows[0].Address)
+
+	s.processBlock(ctx, &Block{
+		NumberParsed: 0x11,
+		Transactions: []*Transaction{{From: "0x2222", To: "0xdac17f958d2ee523a2206206994597c13d831ec7"}},
+	})
+	assert.Equal(t, 1, len(s.GetTransactions(ctx, "0xdac17f958d2ee523a2206206994597c13d831ec7")))
+}
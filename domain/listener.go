@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	listenerBufferSize = 64
+)
+
+var ErrNotSubscribed = errors.New("address not subscribed")
+
+// Listen returns the transactions stored for an address after the lastID
+// position that match its alert rules and subscribes to new matches. IDs of
+// transactions evicted by the quota are skipped. Matches are queued under the
+// service lock and published after it, so the listener skips the ones already
+// in the backlog and no transaction is missed or duplicated. Listeners that fall behind are disconnected and
+// have to resume from their last seen ID. Listeners also get NameChanged
+// when the ENS name of the subscription moves to another address.
+func (s *Service) Listen(ctx context.Context, address string, lastID int) ([]*TransactionMatched, *Subscriber, error) {
+	address = normalizeAddress(address)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	t := s.readTenant(ctx)
+	txs, exists := t.store[address]
+	if !exists {
+		return nil, nil, ErrNotSubscribed
+	}
+
+	sub := t.subscriptions[address]
+	evicted := t.evicted[address]
+	backlog := []*TransactionMatched{}
+	for i := max(lastID-evicted, 0); i < len(txs); i++ {
+		if !sub.Matches(txs[i]) {
+			continue
+		}
+		backlog = append(backlog, &TransactionMatched{
+			Tenant:       t.id,
+			ID:           evicted + i + 1,
+			Address:      address,
+			Transaction:  txs[i],
+			Subscription: sub.snapshot(),
+		})
+	}
+
+	newest := evicted + len(txs)
+	listener := s.bus.Subscribe("listener:"+address, listenerBufferSize, OverflowDisconnect, func(e Event) bool {
+		switch e := e.(type) {
+		case *TransactionMatched:
+			return e.Tenant == t.id && e.Address == address && (e.ID > newest || e.Replayed)
+		case *NameChanged:
+			return e.Tenant == t.id && e.Previous == address
+		}
+		return false
+	})
+
+	return backlog, listener, nil
+}
+
+// ListenBlocks subscribes to every block ingested from the node.
+func (s *Service) ListenBlocks() *Subscriber {
+	return s.bus.Subscribe("listener:blocks", listenerBufferSize, OverflowDisconnect, OfKind(KindBlockIngested))
+}
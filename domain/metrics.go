@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"deshev.com/eth-address-watch/metrics"
+)
+
+var (
+	blocksProcessed = metrics.Default.NewCounter("eaw_blocks_processed_total",
+		"Blocks processed by the service.")
+	transactionsProcessed = metrics.Default.NewCounter("eaw_transactions_processed_total",
+		"Transactions in the blocks processed by the service.")
+	subscriptionMatches = metrics.Default.NewCounter("eaw_subscription_matches_total",
+		"Transactions matching the rules of a subscription.", "tenant", "address")
+	headBlock = metrics.Default.NewGauge("eaw_head_block",
+		"Latest block number reported by the node.")
+	processedBlock = metrics.Default.NewGauge("eaw_processed_block",
+		"Number of the last block processed by the service.")
+	balanceDrifts = metrics.Default.NewCounter("eaw_balance_drift_total",
+		"Reconciliations that found the tracked balance differing from the node.", "address", "asset")
+)
+
+func init() {
+	metrics.Default.NewGaugeFunc("eaw_block_lag", "Blocks between the node head and the last processed block.", blockLag)
+}
+
+// blockLag stays at zero until both the head and a processed block are known.
+func blockLag() float64 {
+	head, processed := headBlock.With().Value(), processedBlock.With().Value()
+	if head == 0 || processed == 0 || processed > head {
+		return 0
+	}
+	return head - processed
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"deshev.com/eth-address-watch/config"
+)
+
+func Test_Metrics_ProcessBlock(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	s.Subscribe(ctx, "0x1111")
+	s.Subscribe(ctx, "0x2222")
+	s.SetRules(ctx, "0x2222", []*Rule{{MinValue: "1000"}})
+
+	blocks := blocksProcessed.With().Value()
+	txs := transactionsProcessed.With().Value()
+	matches := subscriptionMatches.With(DefaultTenant, "0x1111")
+	matched := matches.Value()
+	filtered := subscriptionMatches.With(DefaultTenant, "0x2222").Value()
+
+	s.processBlock(context.Background(), &Block{NumberParsed: 0x11, Transactions: []*Transaction{
+		{From: "0x1111", To: "0x2222", Value: "0x1"},
+		{From: "0x3333", To: "0x1111"},
+	}})
+
+	assert.Equal(t, blocks+1, blocksProcessed.With().Value())
+	assert.Equal(t, txs+2, transactionsProcessed.With().Value())
+	assert.Equal(t, matched+2, matches.Value())
+	// below the minimum value of its rules
+	assert.Equal(t, filtered, subscriptionMatches.With(DefaultTenant, "0x2222").Value())
+	assert.Equal(t, float64(0x11), processedBlock.With().Value())
+
+	assert.NoError(t, s.Unsubscribe(ctx, "0x1111"))
+	assert.Equal(t, float64(0), subscriptionMatches.With(DefaultTenant, "0x1111").Value())
+}
+
+func Test_Metrics_HeadBlock(t *testing.T) {
+	log := slog.Default()
+	client := stubClient(t, 0x11, []*Block{{Number: "0x11"}})
+	w := NewWatcher(log, config.Default(), client, NewBus(log))
+	w.lastBlock = 0x10
+
+	w.tick()
+
+	assert.Equal(t, float64(0x11), headBlock.With().Value())
+}
+
+func Test_BlockLag(t *testing.T) {
+	tests := []struct {
+		name      string
+		head      float64
+		processed float64
+		want      float64
+	}{
+		{name: "behind", head: 0x15, processed: 0x11, want: 4},
+		{name: "caught up", head: 0x11, processed: 0x11, want: 0},
+		{name: "nothing processed yet", head: 0x11, processed: 0, want: 0},
+		{name: "stale head", head: 0x10, processed: 0x11, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headBlock.With().Set(tt.head)
+			processedBlock.With().Set(tt.processed)
+			assert.Equal(t, tt.want, blockLag())
+		})
+	}
+}
+
+func Test_BlockQueueDepth(t *testing.T) {
+	log := slog.Default()
+	bus := NewBus(log)
+	s := NewService(log, bus)
+
+	bus.Publish(&BlockIngested{Block: &Block{NumberParsed: 0x11}})
+	bus.Publish(&BlockIngested{Block: &Block{NumberParsed: 0x12}})
+	assert.Equal(t, 2, s.BlockQueueDepth())
+}
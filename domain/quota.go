@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota caps the subscriptions and stored transactions of every tenant.
+// Zero values mean unlimited.
+type Quota struct {
+	MaxSubscriptions int
+	MaxTransactions  int
+}
+
+func (s *Service) SetQuota(quota Quota) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.quota = quota
+}
+
+// checkSubscriptionQuota expects the lock to be held and fails if subscribing
+// the addresses would take the tenant over its subscription quota.
+func (s *Service) checkSubscriptionQuota(t *tenantState, addresses ...string) error {
+	if s.quota.MaxSubscriptions == 0 {
+		return nil
+	}
+
+	added := map[string]bool{}
+	for _, address := range addresses {
+		if _, exists := t.subscriptions[address]; !exists {
+			added[address] = true
+		}
+	}
+	if len(t.subscriptions)+len(added) > s.quota.MaxSubscriptions {
+		return fmt.Errorf("%w: a tenant can have at most %d subscriptions", ErrQuotaExceeded, s.quota.MaxSubscriptions)
+	}
+	return nil
+}
+
+// storeTransaction expects the lock to be held. Once a tenant reaches its
+// transaction quota, every new transaction evicts the oldest stored one.
+func (s *Service) storeTransaction(t *tenantState, address string, tx *Transaction) {
+	t.store[address] = append(t.store[address], tx)
+	t.order = append(t.order, address)
+	if s.quota.MaxTransactions == 0 || len(t.order) <= s.quota.MaxTransactions {
+		return
+	}
+
+	oldest := t.order[0]
+	t.order[0] = ""
+	t.order = t.order[1:]
+	if txs, exists := t.store[oldest]; exists && len(txs) > 0 {
+		txs[0] = nil
+		t.store[oldest] = txs[1:]
+		t.evicted[oldest]++
+	}
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SubscriptionQuota(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	s.SetQuota(Quota{MaxSubscriptions: 2})
+
+	_, err := s.Subscribe(ctx, "0x1111")
+	assert.NoError(t, err)
+	_, err = s.CreateGroup(ctx, "hot", []string{"0x1111", "0x2222", "0x3333"})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = s.CreateGroup(ctx, "hot", []string{"0x1111", "0x2222"})
+	assert.NoError(t, err)
+
+	// resubscribing does not count
+	_, err = s.Subscribe(ctx, "0x2222")
+	assert.NoError(t, err)
+	_, err = s.Subscribe(ctx, "0x3333")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = s.UpdateGroupMembers(ctx, "hot", []string{"0x3333"}, nil)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = s.ImportSubscriptions(ctx, []*SubscriptionImport{{Address: "0x0000000000000000000000000000000000000003"}})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// quotas are per tenant
+	_, err = s.Subscribe(WithTenant(ctx, "team-b"), "0x3333")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(s.ListSubscriptions(ctx, SubscriptionFilter{})))
+}
+
+func Test_TransactionQuota(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	s.SetQuota(Quota{MaxTransactions: 3})
+
+	s.Subscribe(ctx, "0x1111")
+	s.Subscribe(ctx, "0x2222")
+	_, listener, err := s.Listen(ctx, "0x1111", 0)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	s.processBlock(context.Background(), &Block{
+		NumberParsed: 1,
+		Transactions: []*Transaction{
+			{Hash: "0xa", From: "0x1111", To: "0x3333"},
+			{Hash: "0xb", From: "0x2222", To: "0x3333"},
+			{Hash: "0xc", From: "0x1111", To: "0x3333"},
+			{Hash: "0xd", From: "0x1111", To: "0x3333"},
+		},
+	})
+
+	// the oldest transaction was evicted and IDs keep counting
+	assert.Equal(t, []string{"0xc", "0xd"}, hashes(s.GetTransactions(ctx, "0x1111")))
+	assert.Equal(t, []string{"0xb"}, hashes(s.GetTransactions(ctx, "0x2222")))
+	for _, wantID := range []int{1, 2, 3} {
+		e := <-listener.C
+		assert.Equal(t, wantID, e.(*TransactionMatched).ID)
+	}
+
+	backlog, resumed, err := s.Listen(ctx, "0x1111", 0)
+	assert.NoError(t, err)
+	defer resumed.Close()
+	assert.Equal(t, 2, len(backlog))
+	assert.Equal(t, 2, backlog[0].ID)
+	assert.Equal(t, "0xc", backlog[0].Transaction.Hash)
+
+	backlog, again, err := s.Listen(ctx, "0x1111", 2)
+	assert.NoError(t, err)
+	defer again.Close()
+	assert.Equal(t, 1, len(backlog))
+	assert.Equal(t, 3, backlog[0].ID)
+}
+
+func hashes(txs []*Transaction) []string {
+	result := []string{}
+	for _, tx := range txs {
+		result = append(result, tx.Hash)
+	}
+	return result
+}
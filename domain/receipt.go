@@ -0,0 +1,70 @@
+package domain
+
+import "context"
+
+// ReceiptSource reads the receipts of mined transactions from the node.
+type ReceiptSource interface {
+	GetReceipt(ctx context.Context, hash string) (*Receipt, error)
+}
+
+// WithReceiptSource fills the Status of stored transactions from their
+// receipts, which failedOnly rules and the failed and status variables of
+// expressions match on.
+func WithReceiptSource(source ReceiptSource) ServiceOption {
+	return func(s *Service) {
+		s.receiptSource = source
+	}
+}
+
+// withStatus returns copies of the transactions with the status of their
+// receipts. The node is called without the lock, and transactions whose
+// receipt cannot be read keep an empty status.
+func (s *Service) withStatus(ctx context.Context, txs []*Transaction) map[*Transaction]*Transaction {
+	copies := map[*Transaction]*Transaction{}
+	if s.receiptSource == nil {
+		return copies
+	}
+	for _, tx := range txs {
+		if _, exists := copies[tx]; exists {
+			continue
+		}
+		receipt, err := s.receiptSource.GetReceipt(ctx, tx.Hash)
+		if err != nil {
+			s.log.Error("error reading receipt", "hash", tx.Hash, "error", err)
+			continue
+		}
+		c := *tx
+		c.Status = receipt.Status
+		copies[tx] = &c
+	}
+	return copies
+}
+
+// subscribedTransactions returns the transactions of the block from or to a
+// subscribed address of any tenant.
+func (s *Service) subscribedTransactions(block *Block) []*Transaction {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	txs := []*Transaction{}
+	for _, tx := range block.Transactions {
+		for _, t := range s.tenants {
+			_, from := t.store[tx.From]
+			_, to := t.store[tx.To]
+			if from || to {
+				txs = append(txs, tx)
+				break
+			}
+		}
+	}
+	return txs
+}
+
+// copyOf is the transaction to store, the copy with the receipt status when
+// there is one.
+func copyOf(tx *Transaction, copies map[*Transaction]*Transaction) *Transaction {
+	if c, exists := copies[tx]; exists {
+		return c
+	}
+	return tx
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+)
+
+// Replay runs the stored transactions of a subscribed address through its
+// current rules, as after the rules changed, and publishes every match again
+// with its original ID and marked as replayed. It returns the number of
+// matches.
+func (s *Service) Replay(ctx context.Context, address string) (int, error) {
+	address = normalizeAddress(address)
+
+	s.mtx.Lock()
+	defer s.publishQueued()
+	defer s.mtx.Unlock()
+
+	t := s.readTenant(ctx)
+	txs, exists := t.store[address]
+	if !exists {
+		return 0, ErrNotSubscribed
+	}
+
+	sub := t.subscriptions[address]
+	evicted := t.evicted[address]
+	matched := 0
+	for i, tx := range txs {
+		if !sub.Matches(tx) {
+			continue
+		}
+		matched++
+		s.queue(&TransactionMatched{
+			Tenant:       t.id,
+			ID:           evicted + i + 1,
+			Address:      address,
+			Transaction:  tx,
+			Subscription: sub.snapshot(),
+			Replayed:     true,
+		})
+	}
+	s.log.Info("replayed stored transactions", "address", address, "transactions", len(txs), "matched", matched)
+	return matched, nil
+}
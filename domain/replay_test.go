@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Replay(t *testing.T) {
+	log := slog.Default()
+	ctx := context.Background()
+	bus := NewBus(log)
+	s := NewService(log, bus)
+
+	_, err := s.Replay(ctx, "0x1111")
+	assert.ErrorIs(t, err, ErrNotSubscribed)
+
+	_, err = s.Subscribe(ctx, "0x1111")
+	require.NoError(t, err)
+	require.NoError(t, s.SetRules(ctx, "0x1111", []*Rule{{MinValue: "1000"}}))
+	s.processBlock(ctx, &Block{NumberParsed: 0x11, Transactions: []*Transaction{
+		{Hash: "0xa", From: "0x1111", Value: "0x1"},
+		{Hash: "0xb", From: "0x1111", Value: "0x10"},
+	}})
+
+	matches := bus.Subscribe("test", 2, OverflowBlock, OfKind(KindTransactionMatched))
+	require.NoError(t, s.SetRules(ctx, "0x1111", []*Rule{{MinValue: "16"}}))
+	matched, err := s.Replay(ctx, "0x1111")
+	require.NoError(t, err)
+	assert.Equal(t, 1, matched)
+
+	m, ok := (<-matches.C).(*TransactionMatched)
+	require.True(t, ok)
+	assert.Equal(t, 2, m.ID)
+	assert.Equal(t, "0xb", m.Transaction.Hash)
+	assert.True(t, m.Replayed)
+	assert.Equal(t, 0, matches.Len())
+}
@@ -0,0 +1,256 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"deshev.com/eth-address-watch/expr"
+)
+
+var ErrInvalidRule = errors.New("invalid rule")
+
+type Direction string
+
+const (
+	DirectionAny Direction = ""
+	DirectionIn  Direction = "in"
+	DirectionOut Direction = "out"
+)
+
+var (
+	units = map[string]*big.Int{
+		"wei":   big.NewInt(1),
+		"gwei":  big.NewInt(1_000_000_000),
+		"ether": big.NewInt(1_000_000_000_000_000_000),
+	}
+	selectorPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{8}$`)
+
+	// ExpressionVariables are the transaction fields rule expressions can use.
+	// `self` is the subscribed address.
+	ExpressionVariables = map[string]expr.Type{
+		"value":          expr.TypeNumber,
+		"gas":            expr.TypeNumber,
+		"gasPrice":       expr.TypeNumber,
+		"blockNumber":    expr.TypeNumber,
+		"from":           expr.TypeString,
+		"to":             expr.TypeString,
+		"self":           expr.TypeString,
+		"input":          expr.TypeString,
+		"input.selector": expr.TypeString,
+		"status":         expr.TypeString,
+		"failed":         expr.TypeBool,
+	}
+)
+
+// Rule narrows down the transactions of a subscription that trigger
+// notifications. Every field that is set has to match. Values are amounts
+// like "1.5 ether", "20 gwei" or "1000" (wei). Expression is a condition in
+// the expr language, like `value > 10 ether && to == self`.
+type Rule struct {
+	MinValue   string    `json:"minValue,omitempty"`
+	MaxValue   string    `json:"maxValue,omitempty"`
+	Direction  Direction `json:"direction,omitempty"`
+	Allow      []string  `json:"allow,omitempty"`
+	Deny       []string  `json:"deny,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	FailedOnly bool      `json:"failedOnly,omitempty"`
+	Expression string    `json:"expression,omitempty"`
+
+	minValue   *big.Int
+	maxValue   *big.Int
+	expression *expr.Expr
+}
+
+// Compile validates the rule and prepares it for matching.
+func (r *Rule) Compile() error {
+	var err error
+	if r.MinValue != "" {
+		if r.minValue, err = ParseAmount(r.MinValue); err != nil {
+			return fmt.Errorf("%w: minValue: %w", ErrInvalidRule, err)
+		}
+	}
+	if r.MaxValue != "" {
+		if r.maxValue, err = ParseAmount(r.MaxValue); err != nil {
+			return fmt.Errorf("%w: maxValue: %w", ErrInvalidRule, err)
+		}
+	}
+	if r.minValue != nil && r.maxValue != nil && r.minValue.Cmp(r.maxValue) > 0 {
+		return fmt.Errorf("%w: minValue is greater than maxValue", ErrInvalidRule)
+	}
+
+	switch r.Direction {
+	case DirectionAny, DirectionIn, DirectionOut:
+	default:
+		return fmt.Errorf("%w: direction must be %q or %q", ErrInvalidRule, DirectionIn, DirectionOut)
+	}
+
+	if r.Method != "" && !selectorPattern.MatchString(r.Method) {
+		return fmt.Errorf("%w: method must be a 4-byte hex selector like 0xa9059cbb", ErrInvalidRule)
+	}
+
+	if r.Expression != "" {
+		if r.expression, err = expr.Compile(r.Expression, ExpressionVariables); err != nil {
+			return fmt.Errorf("%w: expression: %w", ErrInvalidRule, err)
+		}
+	}
+	return nil
+}
+
+// Matches checks a transaction of the subscribed address against the rule.
+func (r *Rule) Matches(address string, tx *Transaction) bool {
+	incoming := strings.EqualFold(tx.To, address)
+	outgoing := strings.EqualFold(tx.From, address)
+
+	switch {
+	case r.Direction == DirectionIn && !incoming:
+		return false
+	case r.Direction == DirectionOut && !outgoing:
+		return false
+	case r.FailedOnly && tx.Status != TxStatusFailed:
+		return false
+	case r.Method != "" && !strings.EqualFold(Selector(tx.Input), r.Method):
+		return false
+	}
+
+	if r.minValue != nil || r.maxValue != nil {
+		value := HexToBig(tx.Value)
+		if r.minValue != nil && value.Cmp(r.minValue) < 0 {
+			return false
+		}
+		if r.maxValue != nil && value.Cmp(r.maxValue) > 0 {
+			return false
+		}
+	}
+
+	counterparties := []string{}
+	if incoming {
+		counterparties = append(counterparties, tx.From)
+	}
+	if outgoing {
+		counterparties = append(counterparties, tx.To)
+	}
+	if len(r.Allow) > 0 && !containsAny(r.Allow, counterparties) {
+		return false
+	}
+	if containsAny(r.Deny, counterparties) {
+		return false
+	}
+
+	if r.expression != nil {
+		// variables always match the declared types, so evaluation cannot fail
+		matched, err := r.expression.Eval(expressionVars(address, tx))
+		return err == nil && matched
+	}
+	return true
+}
+
+func expressionVars(address string, tx *Transaction) map[string]any {
+	return map[string]any{
+		"value":          HexToBig(tx.Value),
+		"gas":            HexToBig(tx.Gas),
+		"gasPrice":       HexToBig(tx.GasPrice),
+		"blockNumber":    HexToBig(tx.BlockNumber),
+		"from":           tx.From,
+		"to":             tx.To,
+		"self":           address,
+		"input":          tx.Input,
+		"input.selector": Selector(tx.Input),
+		"status":         tx.Status,
+		"failed":         tx.Status == TxStatusFailed,
+	}
+}
+
+// ParseAmount parses a value with an optional wei, gwei or ether unit.
+func ParseAmount(amount string) (*big.Int, error) {
+	fields := strings.Fields(amount)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	unit := units["wei"]
+	if len(fields) == 2 {
+		var ok bool
+		if unit, ok = units[strings.ToLower(fields[1])]; !ok {
+			return nil, fmt.Errorf("unknown unit %q", fields[1])
+		}
+	}
+
+	if strings.HasPrefix(fields[0], "0x") {
+		value, ok := new(big.Int).SetString(fields[0][2:], 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %q", amount)
+		}
+		return value.Mul(value, unit), nil
+	}
+
+	value, ok := new(big.Rat).SetString(fields[0])
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+	value.Mul(value, new(big.Rat).SetInt(unit))
+	if !value.IsInt() {
+		return nil, fmt.Errorf("amount %q is not a whole number of wei", amount)
+	}
+	return value.Num(), nil
+}
+
+// HexToBig parses a hex quantity as returned by the node, treating anything
+// unparsable as zero.
+func HexToBig(hex string) *big.Int {
+	value, ok := new(big.Int).SetString(strings.TrimPrefix(hex, "0x"), 16)
+	if !ok {
+		return new(big.Int)
+	}
+	return value
+}
+
+// FormatEther formats an amount of wei as an exact decimal number of ether,
+// like "1.5".
+func FormatEther(wei *big.Int) string {
+	return FormatUnits(wei, 18)
+}
+
+// FormatUnits formats an amount in the smallest unit of a currency with the
+// given number of decimals, without rounding or trailing zeros.
+func FormatUnits(value *big.Int, decimals int) string {
+	sign := ""
+	if value.Sign() < 0 {
+		sign = "-"
+		value = new(big.Int).Neg(value)
+	}
+	digits := value.String()
+	if decimals <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+// Selector returns the 4-byte method selector of the transaction input.
+func Selector(input string) string {
+	const selectorLength = len("0x") + 8
+	if len(input) < selectorLength {
+		return ""
+	}
+	return input[:selectorLength]
+}
+
+func containsAny(list, values []string) bool {
+	for _, item := range list {
+		for _, value := range values {
+			if strings.EqualFold(item, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseAmount(t *testing.T) {
+	tests := []struct {
+		amount  string
+		want    string
+		wantErr bool
+	}{
+		{amount: "1000", want: "1000"},
+		{amount: "1.5 ether", want: "1500000000000000000"},
+		{amount: "20 gwei", want: "20000000000"},
+		{amount: "0x10 wei", want: "16"},
+		{amount: "1.5 wei", wantErr: true},
+		{amount: "-1 ether", wantErr: true},
+		{amount: "1 apple", wantErr: true},
+		{amount: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			value, err := ParseAmount(tt.amount)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, value.String())
+		})
+	}
+}
+
+func Test_FormatUnits(t *testing.T) {
+	assert.Equal(t, "1.5", FormatEther(HexToBig("0x14d1120d7b160000")))
+	assert.Equal(t, "0.000000000000000001", FormatEther(big.NewInt(1)))
+	assert.Equal(t, "0", FormatEther(new(big.Int)))
+	assert.Equal(t, "1000", FormatEther(HexToBig("0x3635c9adc5dea00000")))
+	assert.Equal(t, "-12.34", FormatUnits(big.NewInt(-1234), 2))
+	assert.Equal(t, "1234", FormatUnits(big.NewInt(1234), 0))
+}
+
+func Test_Rule_Compile(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "empty", rule: Rule{}},
+		{name: "full", rule: Rule{MinValue: "1 ether", MaxValue: "2 ether", Direction: DirectionOut, Method: "0xa9059cbb"}},
+		{name: "min above max", rule: Rule{MinValue: "2 ether", MaxValue: "1 ether"}, wantErr: true},
+		{name: "bad direction", rule: Rule{Direction: "sideways"}, wantErr: true},
+		{name: "bad method", rule: Rule{Method: "transfer"}, wantErr: true},
+		{name: "bad value", rule: Rule{MinValue: "lots"}, wantErr: true},
+		{name: "expression", rule: Rule{Expression: "value > 10 ether && to == self"}},
+		{name: "bad expression", rule: Rule{Expression: "value > "}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Compile()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRule)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_Rule_Matches(t *testing.T) {
+	oneEther := "0x" + big.NewInt(1_000_000_000_000_000_000).Text(16)
+	incoming := &Transaction{From: "0xaaaa", To: "0x1111", Value: oneEther, Input: "0xa9059cbb0000"}
+	outgoing := &Transaction{From: "0x1111", To: "0xbbbb", Value: "0x1", Status: TxStatusFailed}
+
+	tests := []struct {
+		name string
+		rule Rule
+		tx   *Transaction
+		want bool
+	}{
+		{name: "no conditions", rule: Rule{}, tx: incoming, want: true},
+		{name: "min value met", rule: Rule{MinValue: "1 ether"}, tx: incoming, want: true},
+		{name: "min value missed", rule: Rule{MinValue: "1 ether"}, tx: outgoing, want: false},
+		{name: "max value exceeded", rule: Rule{MaxValue: "0.5 ether"}, tx: incoming, want: false},
+		{name: "direction in", rule: Rule{Direction: DirectionIn}, tx: incoming, want: true},
+		{name: "direction out", rule: Rule{Direction: DirectionOut}, tx: incoming, want: false},
+		{name: "allowed counterparty", rule: Rule{Allow: []string{"0xAAAA"}}, tx: incoming, want: true},
+		{name: "not allowed counterparty", rule: Rule{Allow: []string{"0xaaaa"}}, tx: outgoing, want: false},
+		{name: "denied counterparty", rule: Rule{Deny: []string{"0xbbbb"}}, tx: outgoing, want: false},
+		{name: "method", rule: Rule{Method: "0xA9059CBB"}, tx: incoming, want: true},
+		{name: "method mismatch", rule: Rule{Method: "0x23b872dd"}, tx: incoming, want: false},
+		{name: "failed only", rule: Rule{FailedOnly: true}, tx: outgoing, want: true},
+		{name: "failed only skips unknown status", rule: Rule{FailedOnly: true}, tx: incoming, want: false},
+		{
+			name: "expression",
+			rule: Rule{Expression: "value >= 1 ether && to == self && input.selector == 0xa9059cbb"},
+			tx:   incoming,
+			want: true,
+		},
+		{name: "expression mismatch", rule: Rule{Expression: "from == self"}, tx: incoming, want: false},
+		{name: "expression and fields", rule: Rule{Direction: DirectionOut, Expression: "failed"}, tx: outgoing, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, tt.rule.Compile())
+			assert.Equal(t, tt.want, tt.rule.Matches("0x1111", tt.tx))
+		})
+	}
+}
+
+func Test_SetRules_FiltersNotifications(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+
+	err := s.SetRules(ctx, "0x1111", []*Rule{})
+	assert.ErrorIs(t, err, ErrNotSubscribed)
+
+	s.Subscribe(ctx, "0x1111")
+	err = s.SetRules(ctx, "0x1111", []*Rule{{Direction: "sideways"}})
+	assert.ErrorIs(t, err, ErrInvalidRule)
+
+	err = s.SetRules(ctx, "0x1111", []*Rule{{Direction: DirectionIn}})
+	assert.NoError(t, err)
+	rules, err := s.GetRules(ctx, "0x1111")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rules))
+
+	_, l, err := s.Listen(ctx, "0x1111", 0)
+	assert.NoError(t, err)
+	s.processBlock(context.Background(), &Block{
+		NumberParsed: 0x11,
+		Transactions: []*Transaction{
+			{From: "0x1111", To: "0x2222"},
+			{From: "0x2222", To: "0x1111"},
+		},
+	})
+	l.Close()
+
+	matches := []*TransactionMatched{}
+	for e := range l.C {
+		matches = append(matches, e.(*TransactionMatched))
+	}
+	assert.Equal(t, 1, len(matches))
+	assert.Equal(t, 2, matches[0].ID)
+	assert.Equal(t, 2, len(s.GetTransactions(ctx, "0x1111")))
+}
+
+func Test_FailedOnly_MatchesReceiptStatus(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	source := &fakeBalanceSource{receipts: map[string]*Receipt{
+		"0xa": transfer(TxStatusFailed),
+		"0xb": transfer(TxStatusSuccess),
+	}}
+	s := NewService(log, NewBus(log), WithReceiptSource(source))
+
+	s.Subscribe(ctx, "0x1111")
+	assert.NoError(t, s.SetRules(ctx, "0x1111", []*Rule{{FailedOnly: true}}))
+	_, l, err := s.Listen(ctx, "0x1111", 0)
+	assert.NoError(t, err)
+	s.processBlock(ctx, &Block{
+		NumberParsed: 0x11,
+		Transactions: []*Transaction{
+			{Hash: "0xa", From: "0x1111", To: "0x2222"},
+			{Hash: "0xb", From: "0x1111", To: "0x2222"},
+			// without a receipt the status stays unknown
+			{Hash: "0xc", From: "0x2222", To: "0x1111"},
+		},
+	})
+	l.Close()
+
+	matches := []*TransactionMatched{}
+	for e := range l.C {
+		matches = append(matches, e.(*TransactionMatched))
+	}
+	assert.Equal(t, 1, len(matches))
+	assert.Equal(t, "0xa", matches[0].Transaction.Hash)
+	assert.Equal(t, TxStatusFailed, matches[0].Transaction.Status)
+
+	txs := s.GetTransactions(ctx, "0x1111")
+	assert.Equal(t, 3, len(txs))
+	assert.Equal(t, TxStatusSuccess, txs[1].Status)
+	assert.Equal(t, "", txs[2].Status)
+}
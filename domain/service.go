@@ -3,7 +3,19 @@ package domain
 import (
 	"context"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"deshev.com/eth-address-watch/tracing"
+)
+
+const (
+	blockQueueSize = 10
 )
 
 type TransactionStore = map[string][]*Transaction
@@ -11,19 +23,49 @@ type TransactionStore = map[string][]*Transaction
 type Service struct {
 	mtx                sync.RWMutex
 	log                *slog.Logger
-	blockInput         <-chan *Block
+	bus                *Bus
+	blockInput         *Subscriber
+	blockBufferSize    int
+	blockSource        ETHClient
+	receiptSource      ReceiptSource
+	balances           *Balances
+	names              *Names
 	currentBlockNumber int
 
-	store TransactionStore
+	tenants map[string]*tenantState
+	apiKeys map[string]*APIKey
+	quota   Quota
+
+	// queued has the events of changes made under the lock, which
+	// publishQueued publishes in order once the lock is released
+	queued     []Event
+	publishMtx sync.Mutex
+}
+
+type ServiceOption func(*Service)
+
+// WithBlockBufferSize sets how many ingested blocks can wait for the service
+// before the watcher blocks.
+func WithBlockBufferSize(size int) ServiceOption {
+	return func(s *Service) {
+		s.blockBufferSize = size
+	}
 }
 
-func NewService(log *slog.Logger, blockInput <-chan *Block) *Service {
-	return &Service{
+func NewService(log *slog.Logger, bus *Bus, opts ...ServiceOption) *Service {
+	s := &Service{
 		log:                log,
-		blockInput:         blockInput,
+		bus:                bus,
+		blockBufferSize:    blockQueueSize,
 		currentBlockNumber: 0,
-		store:              TransactionStore{},
+		tenants:            map[string]*tenantState{},
+		apiKeys:            map[string]*APIKey{},
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.blockInput = bus.Subscribe("service", s.blockBufferSize, OverflowBlock, OfKind(KindBlockIngested))
+	return s
 }
 
 // last parsed block
@@ -35,53 +77,243 @@ func (s *Service) GetCurrentBlock() int {
 }
 
 // add address to observer
-func (s *Service) Subscribe(address string) bool {
+func (s *Service) Subscribe(ctx context.Context, address string) (bool, error) {
+	address = normalizeAddress(address)
+
 	s.mtx.Lock()
+	defer s.publishQueued()
 	defer s.mtx.Unlock()
 
-	_, exists := s.store[address]
-	if !exists {
-		s.store[address] = []*Transaction{}
-	} else {
+	t := s.writeTenant(ctx)
+	if err := s.checkSubscriptionQuota(t, address); err != nil {
+		return false, err
+	}
+	if !s.subscribe(t, address) {
 		s.log.Info("address already subscribed", "address", address)
 	}
+	return true, nil
+}
+
+// queue expects the lock to be held and publishes e after it is released.
+func (s *Service) queue(e Event) {
+	s.queued = append(s.queued, e)
+}
+
+// publishQueued publishes the queued events. Writers defer it before
+// unlocking, so a subscriber that blocks the bus never holds up readers of
+// the service, and events are still published in the order of the changes.
+func (s *Service) publishQueued() {
+	s.publishMtx.Lock()
+	defer s.publishMtx.Unlock()
+
+	s.mtx.Lock()
+	events := s.queued
+	s.queued = nil
+	s.mtx.Unlock()
+
+	for _, e := range events {
+		s.bus.Publish(e)
+	}
+}
+
+// normalizeAddress is the form addresses are stored and looked up in, the
+// lower case the node sends, so a checksummed address is the same
+// subscription.
+func normalizeAddress(address string) string {
+	return strings.ToLower(address)
+}
+
+func normalizeAddresses(addresses []string) []string {
+	normalized := make([]string, len(addresses))
+	for i, address := range addresses {
+		normalized[i] = normalizeAddress(address)
+	}
+	return normalized
+}
+
+// subscribe expects the lock to be held and reports whether the address is new.
+func (s *Service) subscribe(t *tenantState, address string) bool {
+	if _, exists := t.subscriptions[address]; exists {
+		return false
+	}
+
+	t.store[address] = []*Transaction{}
+	t.subscriptions[address] = &Subscription{Address: address, CreatedAt: time.Now().UTC()}
+	s.queue(&SubscriptionChanged{Tenant: t.id, Address: address, Subscribed: true})
 	return true
 }
 
-// list of inbound or outbound transactions for an address
-func (s *Service) GetTransactions(address string) []*Transaction {
+// Unsubscribe stops observing an address, drops its transactions and removes
+// it from every group.
+func (s *Service) Unsubscribe(ctx context.Context, address string) error {
+	address = normalizeAddress(address)
+
+	s.mtx.Lock()
+	defer s.publishQueued()
+	defer s.mtx.Unlock()
+
+	t := s.readTenant(ctx)
+	if _, exists := t.subscriptions[address]; !exists {
+		return ErrNotSubscribed
+	}
+
+	delete(t.subscriptions, address)
+	delete(t.store, address)
+	delete(t.evicted, address)
+	subscriptionMatches.Delete(t.id, address)
+	t.order = slices.DeleteFunc(t.order, func(stored string) bool {
+		return stored == address
+	})
+	for _, group := range t.groups {
+		group.Members = slices.DeleteFunc(group.Members, func(member string) bool {
+			return strings.EqualFold(member, address)
+		})
+	}
+	s.queue(&SubscriptionChanged{Tenant: t.id, Address: address, Subscribed: false})
+	return nil
+}
+
+// list of inbound or outbound transactions for an address, with the primary
+// ENS names of the counterparties when names are resolved
+func (s *Service) GetTransactions(ctx context.Context, address string) []*Transaction {
+	address = normalizeAddress(address)
+
+	s.mtx.RLock()
+	txs := s.readTenant(ctx).store[address]
+	s.mtx.RUnlock()
+
+	if s.names == nil || txs == nil {
+		return txs
+	}
+	return s.names.annotate(ctx, address, txs)
+}
+
+// IndexedTransaction is a stored transaction with its ID in the address
+// history, the same ID streams use.
+type IndexedTransaction struct {
+	ID int `json:"id"`
+	*Transaction
+}
+
+// PageTransactions returns up to limit transactions of an address with an ID
+// below before, newest first, along with the number of stored transactions.
+// A before of 0 starts with the newest transaction.
+func (s *Service) PageTransactions(ctx context.Context, address string, before, limit int) ([]*IndexedTransaction, int, error) {
+	address = normalizeAddress(address)
+
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
 
-	return s.store[address]
+	t := s.readTenant(ctx)
+	txs, exists := t.store[address]
+	if !exists {
+		return nil, 0, ErrNotSubscribed
+	}
+
+	evicted := t.evicted[address]
+	end := len(txs)
+	if before > 0 {
+		end = min(max(before-evicted-1, 0), len(txs))
+	}
+	page := []*IndexedTransaction{}
+	for i := end - 1; i >= 0 && len(page) < limit; i-- {
+		page = append(page, &IndexedTransaction{ID: evicted + i + 1, Transaction: txs[i]})
+	}
+	return page, len(txs), nil
+}
+
+// ScanTransactions returns up to limit transactions of an address with an ID
+// after afterID, oldest first, to go through a long history in batches
+// without holding the lock.
+func (s *Service) ScanTransactions(ctx context.Context, address string, afterID, limit int) ([]*IndexedTransaction, error) {
+	address = normalizeAddress(address)
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	t := s.readTenant(ctx)
+	txs, exists := t.store[address]
+	if !exists {
+		return nil, ErrNotSubscribed
+	}
+
+	evicted := t.evicted[address]
+	batch := []*IndexedTransaction{}
+	for i := max(afterID-evicted, 0); i < len(txs) && len(batch) < limit; i++ {
+		batch = append(batch, &IndexedTransaction{ID: evicted + i + 1, Transaction: txs[i]})
+	}
+	return batch, nil
+}
+
+// BlockQueueDepth is the number of ingested blocks waiting to be processed.
+func (s *Service) BlockQueueDepth() int {
+	return s.blockInput.Len()
 }
 
 func (s *Service) Start(ctx context.Context) error {
 	s.log.Info("starting notification service")
+	defer s.blockInput.Close()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case block := <-s.blockInput:
-			s.processBlock(block)
+		case e, ok := <-s.blockInput.C:
+			if !ok {
+				return nil
+			}
+			if ingested, ok := e.(*BlockIngested); ok {
+				s.processBlock(trace.ContextWithSpanContext(ctx, ingested.SpanContext), ingested.Block)
+			}
 		}
 	}
 }
 
-func (s *Service) processBlock(block *Block) {
+func (s *Service) processBlock(ctx context.Context, block *Block) {
+	ctx, span := tracing.Start(ctx, "Service.processBlock", trace.WithAttributes(
+		attribute.Int("block.number", block.NumberParsed),
+		attribute.Int("block.transactions", len(block.Transactions)),
+	))
+	defer span.End()
+
+	copies := s.withStatus(ctx, s.subscribedTransactions(block))
+
 	s.mtx.Lock()
+	defer s.publishQueued()
 	defer s.mtx.Unlock()
 
 	s.log.Info("service processing block", "block", block.NumberParsed, "transactions", len(block.Transactions))
 	s.currentBlockNumber = block.NumberParsed
+	blocksProcessed.With().Inc()
+	transactionsProcessed.With().Add(float64(len(block.Transactions)))
+	processedBlock.With().Set(float64(block.NumberParsed))
 
-	for _, tx := range block.Transactions {
-		if _, exists := s.store[tx.From]; exists {
-			s.store[tx.From] = append(s.store[tx.From], tx)
-		}
-		if _, exists := s.store[tx.To]; exists {
-			s.store[tx.To] = append(s.store[tx.To], tx)
+	for _, t := range s.tenants {
+		for _, tx := range block.Transactions {
+			tx := copyOf(tx, copies)
+			if _, exists := t.store[tx.From]; exists {
+				s.storeTransaction(t, tx.From, tx)
+				s.publishMatch(t, tx.From, tx)
+			}
+			if _, exists := t.store[tx.To]; exists {
+				s.storeTransaction(t, tx.To, tx)
+				s.publishMatch(t, tx.To, tx)
+			}
 		}
 	}
 }
+
+func (s *Service) publishMatch(t *tenantState, address string, tx *Transaction) {
+	sub := t.subscriptions[address]
+	if !sub.Matches(tx) {
+		return
+	}
+	subscriptionMatches.With(t.id, address).Inc()
+	s.queue(&TransactionMatched{
+		Tenant:       t.id,
+		ID:           t.evicted[address] + len(t.store[address]),
+		Address:      address,
+		Transaction:  tx,
+		Subscription: sub.snapshot(),
+	})
+}
@@ -4,17 +4,19 @@ import (
 	"context"
 	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_Service_Start(t *testing.T) {
+	ctx := context.Background()
 	log := slog.Default()
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	blockC := make(chan *Block)
-	s := NewService(log, blockC)
+	s := NewService(log, NewBus(log))
 	end := make(chan struct{})
 	var err error
 	go func() {
@@ -29,24 +31,23 @@ func Test_Service_Start(t *testing.T) {
 
 func Test_GetCurrentBlock(t *testing.T) {
 	log := slog.Default()
-	blockC := make(chan *Block)
-	s := NewService(log, blockC)
+	s := NewService(log, NewBus(log))
 
 	b := &Block{
 		Number:       "0x11",
 		NumberParsed: 0x11,
 	}
-	s.processBlock(b)
+	s.processBlock(context.Background(), b)
 
 	assert.Equal(t, 0x11, s.GetCurrentBlock())
 }
 
 func Test_Subscribe(t *testing.T) {
+	ctx := context.Background()
 	log := slog.Default()
-	blockC := make(chan *Block)
-	s := NewService(log, blockC)
+	s := NewService(log, NewBus(log))
 
-	s.Subscribe("0x1111")
+	s.Subscribe(ctx, "0x1111")
 
 	b := &Block{
 		Number:       "0x11",
@@ -57,10 +58,298 @@ func Test_Subscribe(t *testing.T) {
 			{From: "0x2111", To: "0x2112"},
 		},
 	}
-	s.processBlock(b)
+	s.processBlock(context.Background(), b)
 
-	subscribedTxs := s.GetTransactions("0x1111")
+	subscribedTxs := s.GetTransactions(ctx, "0x1111")
 	assert.Equal(t, 2, len(subscribedTxs))
-	otherTxs := s.GetTransactions("0x2111")
+	otherTxs := s.GetTransactions(ctx, "0x2111")
 	assert.Equal(t, 0, len(otherTxs))
 }
+
+func Test_Subscribe_ChecksummedAddress(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	checksummed := "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	lower := "0xdac17f958d2ee523a2206206994597c13d831ec7"
+
+	_, err := s.Subscribe(ctx, checksummed)
+	require.NoError(t, err)
+	results, err := s.ImportSubscriptions(ctx, []*SubscriptionImport{{Address: lower}})
+	require.NoError(t, err)
+	assert.False(t, results[0].Created)
+	_, err = s.CreateGroup(ctx, "exchange", []string{checksummed})
+	require.NoError(t, err)
+	assert.Len(t, s.ListSubscriptions(ctx, SubscriptionFilter{}), 1)
+
+	s.processBlock(ctx, &Block{NumberParsed: 0x11, Transactions: []*Transaction{{From: "0x2222", To: lower}}})
+	assert.Len(t, s.GetTransactions(ctx, checksummed), 1)
+	txs, err := s.GetGroupTransactions(ctx, "exchange")
+	require.NoError(t, err)
+	assert.Len(t, txs, 1)
+}
+
+func Test_Unsubscribe(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	bus := NewBus(log)
+	s := NewService(log, bus)
+	changes := bus.Subscribe("test", 10, OverflowDropNewest, OfKind(KindSubscriptionChanged))
+
+	s.Subscribe(ctx, "0x1111")
+	s.Subscribe(ctx, "0x2222")
+	_, err := s.CreateGroup(ctx, "wallets", []string{"0x1111", "0x2222"})
+	assert.NoError(t, err)
+	s.processBlock(context.Background(), &Block{Transactions: []*Transaction{{From: "0x1111", To: "0x2222"}}})
+
+	assert.NoError(t, s.Unsubscribe(ctx, "0x1111"))
+	assert.ErrorIs(t, s.Unsubscribe(ctx, "0x1111"), ErrNotSubscribed)
+
+	assert.Empty(t, s.GetTransactions(ctx, "0x1111"))
+	assert.Len(t, s.GetTransactions(ctx, "0x2222"), 1)
+	_, err = s.GetSubscription(ctx, "0x1111")
+	assert.ErrorIs(t, err, ErrNotSubscribed)
+	group, err := s.GetGroup(ctx, "wallets")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0x2222"}, group.Members)
+	assert.Equal(t, []string{"0x2222"}, s.tenants[DefaultTenant].order)
+
+	s.processBlock(context.Background(), &Block{Transactions: []*Transaction{{From: "0x1111", To: "0x3333"}}})
+	assert.Empty(t, s.GetTransactions(ctx, "0x1111"))
+
+	var last Event
+	for len(changes.C) > 0 {
+		last = <-changes.C
+	}
+	assert.Equal(t, &SubscriptionChanged{Address: "0x1111", Subscribed: false}, last)
+}
+
+func Test_PageTransactions(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	s.SetQuota(Quota{MaxTransactions: 4})
+
+	_, _, err := s.PageTransactions(ctx, "0x1111", 0, 10)
+	assert.ErrorIs(t, err, ErrNotSubscribed)
+
+	s.Subscribe(ctx, "0x1111")
+	for _, hash := range []string{"0xa", "0xb", "0xc", "0xd", "0xe"} {
+		s.processBlock(context.Background(), &Block{Transactions: []*Transaction{{Hash: hash, From: "0x1111"}}})
+	}
+
+	ids := func(page []*IndexedTransaction) []int {
+		result := []int{}
+		for _, tx := range page {
+			result = append(result, tx.ID)
+		}
+		return result
+	}
+
+	// the first transaction was evicted, IDs stay stable
+	page, total, err := s.PageTransactions(ctx, "0x1111", 0, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Equal(t, []int{5, 4, 3}, ids(page))
+	assert.Equal(t, "0xe", page[0].Hash)
+
+	page, _, err = s.PageTransactions(ctx, "0x1111", 3, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, ids(page))
+
+	page, _, err = s.PageTransactions(ctx, "0x1111", 1, 3)
+	assert.NoError(t, err)
+	assert.Empty(t, page)
+}
+
+func Test_ScanTransactions(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	s.SetQuota(Quota{MaxTransactions: 4})
+
+	_, err := s.ScanTransactions(ctx, "0x1111", 0, 10)
+	assert.ErrorIs(t, err, ErrNotSubscribed)
+
+	s.Subscribe(ctx, "0x1111")
+	for _, hash := range []string{"0xa", "0xb", "0xc", "0xd", "0xe"} {
+		s.processBlock(context.Background(), &Block{Transactions: []*Transaction{{Hash: hash, From: "0x1111"}}})
+	}
+
+	// the first transaction was evicted, so the scan starts at ID 2
+	batch, err := s.ScanTransactions(ctx, "0x1111", 0, 2)
+	assert.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, 2, batch[0].ID)
+	assert.Equal(t, "0xb", batch[0].Hash)
+	assert.Equal(t, 3, batch[1].ID)
+
+	batch, err = s.ScanTransactions(ctx, "0x1111", 3, 10)
+	assert.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, 4, batch[0].ID)
+	assert.Equal(t, "0xe", batch[1].Hash)
+
+	batch, err = s.ScanTransactions(ctx, "0x1111", 5, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, batch)
+}
+
+func Test_Listen(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+
+	_, _, err := s.Listen(ctx, "0x1111", 0)
+	assert.ErrorIs(t, err, ErrNotSubscribed)
+
+	s.Subscribe(ctx, "0x1111")
+	s.processBlock(context.Background(), &Block{
+		NumberParsed: 0x11,
+		Transactions: []*Transaction{{From: "0x1111", To: "0x1112"}},
+	})
+
+	backlog, l, err := s.Listen(ctx, "0x1111", 0)
+	assert.NoError(t, err)
+	defer l.Close()
+	assert.Equal(t, 1, len(backlog))
+	assert.Equal(t, 1, backlog[0].ID)
+
+	resumed, resumedListener, err := s.Listen(ctx, "0x1111", 1)
+	assert.NoError(t, err)
+	resumedListener.Close()
+	assert.Equal(t, 0, len(resumed))
+
+	s.processBlock(context.Background(), &Block{
+		NumberParsed: 0x12,
+		Transactions: []*Transaction{{From: "0x1112", To: "0x1111"}},
+	})
+
+	n, ok := (<-l.C).(*TransactionMatched)
+	assert.True(t, ok)
+	assert.Equal(t, 2, n.ID)
+	assert.Equal(t, "0x1112", n.Transaction.From)
+}
+
+func Test_Listen_SkipsQueuedBacklog(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	s.Subscribe(ctx, "0x1111")
+
+	// a match stored but not yet published when the listener subscribes
+	s.mtx.Lock()
+	tx := &Transaction{Hash: "0xa", From: "0x1111"}
+	s.storeTransaction(s.readTenant(ctx), "0x1111", tx)
+	s.publishMatch(s.readTenant(ctx), "0x1111", tx)
+	s.mtx.Unlock()
+
+	backlog, l, err := s.Listen(ctx, "0x1111", 0)
+	require.NoError(t, err)
+	defer l.Close()
+	require.Len(t, backlog, 1)
+	s.publishQueued()
+
+	s.processBlock(ctx, &Block{Transactions: []*Transaction{{Hash: "0xb", From: "0x1111"}}})
+	n, ok := (<-l.C).(*TransactionMatched)
+	require.True(t, ok)
+	assert.Equal(t, 2, n.ID)
+}
+
+func Test_Publish_AfterUnlock(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	bus := NewBus(log)
+	s := NewService(log, bus)
+	blocked := bus.Subscribe("blocked", 0, OverflowBlock, OfKind(KindSubscriptionChanged))
+	defer blocked.Close()
+
+	subscribed := make(chan struct{})
+	go func() {
+		defer close(subscribed)
+		s.Subscribe(ctx, "0x1111")
+	}()
+
+	// readers are not held up while the change waits for the subscriber
+	assert.Eventually(t, func() bool {
+		return len(s.ListSubscriptions(ctx, SubscriptionFilter{})) == 1
+	}, time.Second, time.Millisecond)
+	e, ok := (<-blocked.C).(*SubscriptionChanged)
+	require.True(t, ok)
+	assert.Equal(t, "0x1111", e.Address)
+	<-subscribed
+}
+
+func Test_Listen_DropsSlowListener(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	s.Subscribe(ctx, "0x1111")
+
+	_, l, err := s.Listen(ctx, "0x1111", 0)
+	assert.NoError(t, err)
+
+	txs := []*Transaction{}
+	for range listenerBufferSize + 1 {
+		txs = append(txs, &Transaction{From: "0x1111", To: "0x1112"})
+	}
+	s.processBlock(context.Background(), &Block{NumberParsed: 0x11, Transactions: txs})
+
+	received := 0
+	for range l.C {
+		received++
+	}
+	assert.Equal(t, listenerBufferSize, received)
+	assert.Equal(t, 1, l.Dropped())
+
+	l.Close()
+}
+
+func Test_ListenBlocks(t *testing.T) {
+	log := slog.Default()
+	bus := NewBus(log)
+	s := NewService(log, bus)
+
+	l := s.ListenBlocks()
+	bus.Publish(&BlockIngested{Block: &Block{NumberParsed: 0x11}})
+
+	e, ok := (<-l.C).(*BlockIngested)
+	assert.True(t, ok)
+	assert.Equal(t, 0x11, e.Block.NumberParsed)
+
+	l.Close()
+	_, open := <-l.C
+	assert.False(t, open)
+}
+
+func Test_Service_ConsumesIngestedBlocks(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := NewBus(log)
+	s := NewService(log, bus)
+	s.Subscribe(ctx, "0x1111")
+	matches := bus.Subscribe("test", 1, OverflowBlock, OfKind(KindTransactionMatched))
+	go func() {
+		_ = s.Start(ctx)
+	}()
+
+	bus.Publish(&BlockIngested{Block: &Block{
+		NumberParsed: 0x11,
+		Transactions: []*Transaction{{From: "0x1111", To: "0x1112"}},
+	}})
+
+	m, ok := (<-matches.C).(*TransactionMatched)
+	assert.True(t, ok)
+	assert.Equal(t, "0x1111", m.Address)
+	assert.Equal(t, 0x11, s.GetCurrentBlock())
+}
+
+func Test_WithBlockBufferSize(t *testing.T) {
+	log := slog.Default()
+
+	assert.Equal(t, blockQueueSize, cap(NewService(log, NewBus(log)).blockInput.C))
+	assert.Equal(t, 3, cap(NewService(log, NewBus(log), WithBlockBufferSize(3)).blockInput.C))
+}
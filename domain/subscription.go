@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SubscriptionMetadata describes who a subscribed address belongs to.
+// Metadata holds arbitrary JSON for the caller's own use.
+type SubscriptionMetadata struct {
+	Label    string          `json:"label,omitempty"`
+	Tags     []string        `json:"tags,omitempty"`
+	Owner    string          `json:"owner,omitempty"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+type Subscription struct {
+	Address string `json:"address"`
+	// Name is the ENS name the subscription follows, if subscribed by name
+	Name string `json:"name,omitempty"`
+	SubscriptionMetadata
+	CreatedAt time.Time `json:"createdAt"`
+	Rules     []*Rule   `json:"rules,omitempty"`
+}
+
+// SubscriptionFilter selects subscriptions by tag and owner. Empty fields
+// match everything.
+type SubscriptionFilter struct {
+	Tag   string
+	Owner string
+}
+
+// Matches reports whether a transaction should trigger a notification.
+// Subscriptions without rules notify on every transaction.
+func (s *Subscription) Matches(tx *Transaction) bool {
+	if len(s.Rules) == 0 {
+		return true
+	}
+
+	for _, rule := range s.Rules {
+		if rule.Matches(s.Address, tx) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasTag compares tags case-insensitively.
+func (s *Subscription) HasTag(tag string) bool {
+	return slices.ContainsFunc(s.Tags, func(t string) bool {
+		return strings.EqualFold(t, tag)
+	})
+}
+
+// GetRules returns the alert rules of a subscribed address.
+func (s *Service) GetRules(ctx context.Context, address string) ([]*Rule, error) {
+	address = normalizeAddress(address)
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	sub, exists := s.readTenant(ctx).subscriptions[address]
+	if !exists {
+		return nil, ErrNotSubscribed
+	}
+	return sub.Rules, nil
+}
+
+// SetRules validates and replaces the alert rules of a subscribed address.
+func (s *Service) SetRules(ctx context.Context, address string, rules []*Rule) error {
+	address = normalizeAddress(address)
+
+	for i, rule := range rules {
+		if err := rule.Compile(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	sub, exists := s.readTenant(ctx).subscriptions[address]
+	if !exists {
+		return ErrNotSubscribed
+	}
+	sub.Rules = rules
+	return nil
+}
+
+// SetMetadata replaces the label, tags, owner and metadata of a subscribed address.
+func (s *Service) SetMetadata(ctx context.Context, address string, meta SubscriptionMetadata) error {
+	address = normalizeAddress(address)
+
+	meta.Tags = cleanTags(meta.Tags)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	sub, exists := s.readTenant(ctx).subscriptions[address]
+	if !exists {
+		return ErrNotSubscribed
+	}
+	sub.SubscriptionMetadata = meta
+	return nil
+}
+
+// GetSubscription returns a copy of a subscription.
+func (s *Service) GetSubscription(ctx context.Context, address string) (*Subscription, error) {
+	address = normalizeAddress(address)
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	sub, exists := s.readTenant(ctx).subscriptions[address]
+	if !exists {
+		return nil, ErrNotSubscribed
+	}
+	return sub.snapshot(), nil
+}
+
+// ListSubscriptions returns copies of the matching subscriptions, ordered by address.
+func (s *Service) ListSubscriptions(ctx context.Context, filter SubscriptionFilter) []*Subscription {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	result := []*Subscription{}
+	for _, sub := range s.readTenant(ctx).subscriptions {
+		if filter.Tag != "" && !sub.HasTag(filter.Tag) {
+			continue
+		}
+		if filter.Owner != "" && sub.Owner != filter.Owner {
+			continue
+		}
+		result = append(result, sub.snapshot())
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+	return result
+}
+
+func cleanTags(tags []string) []string {
+	result := []string{}
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// snapshot is safe to hand out since updates replace fields instead of
+// mutating them in place.
+func (s *Subscription) snapshot() *Subscription {
+	c := *s
+	return &c
+}
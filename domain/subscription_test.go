@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SetMetadata(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+
+	err := s.SetMetadata(ctx, "0x1111", SubscriptionMetadata{Label: "hot wallet"})
+	assert.ErrorIs(t, err, ErrNotSubscribed)
+
+	s.Subscribe(ctx, "0x1111")
+	err = s.SetMetadata(ctx, "0x1111", SubscriptionMetadata{
+		Label:    "hot wallet",
+		Tags:     []string{" exchange ", ""},
+		Owner:    "team-a",
+		Metadata: json.RawMessage(`{"customer":42}`),
+	})
+	assert.NoError(t, err)
+
+	sub, err := s.GetSubscription(ctx, "0x1111")
+	assert.NoError(t, err)
+	assert.Equal(t, "hot wallet", sub.Label)
+	assert.Equal(t, []string{"exchange"}, sub.Tags)
+	assert.Equal(t, "team-a", sub.Owner)
+	assert.JSONEq(t, `{"customer":42}`, string(sub.Metadata))
+	assert.False(t, sub.CreatedAt.IsZero())
+}
+
+func Test_ListSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+
+	s.Subscribe(ctx, "0x3333")
+	s.Subscribe(ctx, "0x1111")
+	s.Subscribe(ctx, "0x2222")
+	assert.NoError(t, s.SetMetadata(ctx, "0x1111", SubscriptionMetadata{Tags: []string{"Exchange"}, Owner: "team-a"}))
+	assert.NoError(t, s.SetMetadata(ctx, "0x3333", SubscriptionMetadata{Tags: []string{"exchange"}, Owner: "team-b"}))
+
+	addresses := func(subs []*Subscription) []string {
+		result := []string{}
+		for _, sub := range subs {
+			result = append(result, sub.Address)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"0x1111", "0x2222", "0x3333"}, addresses(s.ListSubscriptions(ctx, SubscriptionFilter{})))
+	assert.Equal(t, []string{"0x1111", "0x3333"}, addresses(s.ListSubscriptions(ctx, SubscriptionFilter{Tag: "exchange"})))
+	assert.Equal(t, []string{"0x3333"}, addresses(s.ListSubscriptions(ctx, SubscriptionFilter{Tag: "exchange", Owner: "team-b"})))
+}
+
+func Test_Notification_IncludesSubscription(t *testing.T) {
+	ctx := context.Background()
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	s.Subscribe(ctx, "0x1111")
+	assert.NoError(t, s.SetMetadata(ctx, "0x1111", SubscriptionMetadata{Label: "hot wallet"}))
+
+	_, l, err := s.Listen(ctx, "0x1111", 0)
+	assert.NoError(t, err)
+	s.processBlock(context.Background(), &Block{
+		NumberParsed: 0x11,
+		Transactions: []*Transaction{{From: "0x1111", To: "0x2222"}},
+	})
+	l.Close()
+
+	m, ok := (<-l.C).(*TransactionMatched)
+	assert.True(t, ok)
+	assert.Equal(t, "hot wallet", m.Subscription.Label)
+}
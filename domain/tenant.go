@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+)
+
+// DefaultTenant owns all data when API authentication is disabled.
+const DefaultTenant = ""
+
+type tenantContextKey struct{}
+
+// WithTenant scopes service calls made with the returned context to a tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// tenantState keeps the subscriptions, transactions and groups of a single
+// tenant, so tenants watching the same address get independent views.
+type tenantState struct {
+	id            string
+	store         TransactionStore
+	subscriptions map[string]*Subscription
+	groups        map[string]*Group
+
+	// order has the address of every stored transaction, oldest first, and
+	// evicted counts the transactions of an address dropped to stay within
+	// the quota, so transaction IDs stay stable after eviction.
+	order   []string
+	evicted map[string]int
+}
+
+func newTenantState(id string) *tenantState {
+	return &tenantState{
+		id:            id,
+		store:         TransactionStore{},
+		subscriptions: map[string]*Subscription{},
+		groups:        map[string]*Group{},
+		evicted:       map[string]int{},
+	}
+}
+
+// readTenant expects at least the read lock to be held. Unknown tenants get
+// an empty state that is not registered.
+func (s *Service) readTenant(ctx context.Context) *tenantState {
+	id := TenantFromContext(ctx)
+	if t, exists := s.tenants[id]; exists {
+		return t
+	}
+	return newTenantState(id)
+}
+
+// writeTenant expects the write lock to be held and registers the tenant on
+// first use.
+func (s *Service) writeTenant(ctx context.Context) *tenantState {
+	id := TenantFromContext(ctx)
+	t, exists := s.tenants[id]
+	if !exists {
+		t = newTenantState(id)
+		s.tenants[id] = t
+	}
+	return t
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TenantIsolation(t *testing.T) {
+	log := slog.Default()
+	s := NewService(log, NewBus(log))
+	teamA := WithTenant(context.Background(), "team-a")
+	teamB := WithTenant(context.Background(), "team-b")
+
+	s.Subscribe(teamA, "0x1111")
+	s.Subscribe(teamB, "0x1111")
+	assert.NoError(t, s.SetRules(teamB, "0x1111", []*Rule{{Direction: DirectionOut}}))
+	assert.NoError(t, s.SetMetadata(teamA, "0x1111", SubscriptionMetadata{Label: "a"}))
+	_, err := s.CreateGroup(teamA, "hot", []string{"0x2222"})
+	assert.NoError(t, err)
+
+	_, listenerA, err := s.Listen(teamA, "0x1111", 0)
+	assert.NoError(t, err)
+	defer listenerA.Close()
+	_, listenerB, err := s.Listen(teamB, "0x1111", 0)
+	assert.NoError(t, err)
+	defer listenerB.Close()
+
+	s.processBlock(context.Background(), &Block{
+		NumberParsed: 1,
+		Transactions: []*Transaction{{Hash: "0xa", From: "0x3333", To: "0x1111"}},
+	})
+
+	assert.Equal(t, 1, len(s.GetTransactions(teamA, "0x1111")))
+	assert.Equal(t, 1, len(s.GetTransactions(teamB, "0x1111")))
+	assert.Equal(t, 0, len(s.GetTransactions(context.Background(), "0x1111")))
+
+	// only team A has no rules filtering out the incoming transaction
+	e := <-listenerA.C
+	assert.Equal(t, "team-a", e.(*TransactionMatched).Tenant)
+	assert.Equal(t, 0, listenerB.Len())
+
+	subsA := s.ListSubscriptions(teamA, SubscriptionFilter{})
+	subsB := s.ListSubscriptions(teamB, SubscriptionFilter{})
+	assert.Equal(t, 2, len(subsA))
+	assert.Equal(t, 1, len(subsB))
+	assert.Equal(t, "", subsB[0].Label)
+
+	_, err = s.GetGroup(teamB, "hot")
+	assert.ErrorIs(t, err, ErrGroupNotFound)
+	_, err = s.GetRules(context.Background(), "0x1111")
+	assert.ErrorIs(t, err, ErrNotSubscribed)
+}
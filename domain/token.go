@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"strings"
+)
+
+const (
+	transferSelector     = "0xa9059cbb"
+	transferFromSelector = "0x23b872dd"
+
+	// TransferTopic is the first topic of ERC-20 and ERC-721 Transfer logs,
+	// the hash of Transfer(address,address,uint256)
+	TransferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+	// calldata arguments are 32-byte words
+	wordLength = 64
+)
+
+// TokenTransfer is an ERC-20 transfer decoded from the input of a transfer or
+// transferFrom call to the token contract, or from a Transfer log.
+type TokenTransfer struct {
+	Token string `json:"token"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	// Value is the hex amount in the smallest unit of the token.
+	Value string `json:"value"`
+}
+
+// DecodeTokenTransfer returns nil for transactions that are not ERC-20
+// transfer calls.
+func DecodeTokenTransfer(tx *Transaction) *TokenTransfer {
+	if tx.To == "" {
+		return nil
+	}
+	selector := strings.ToLower(Selector(tx.Input))
+	args := strings.TrimPrefix(tx.Input, selector)
+
+	switch selector {
+	case transferSelector:
+		words, ok := splitWords(args, 2)
+		if !ok {
+			return nil
+		}
+		return &TokenTransfer{Token: tx.To, From: tx.From, To: wordAddress(words[0]), Value: wordValue(words[1])}
+	case transferFromSelector:
+		words, ok := splitWords(args, 3)
+		if !ok {
+			return nil
+		}
+		return &TokenTransfer{Token: tx.To, From: wordAddress(words[0]), To: wordAddress(words[1]), Value: wordValue(words[2])}
+	default:
+		return nil
+	}
+}
+
+// TokenInfo describes an ERC-20 contract.
+type TokenInfo struct {
+	Address  string `json:"address"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+// DecodeTransferLog returns nil for logs that are not ERC-20 Transfer
+// events. ERC-721 transfers share the topic but index the token id as a
+// fourth topic, so they are skipped.
+func DecodeTransferLog(log *Log) *TokenTransfer {
+	if len(log.Topics) != 3 || !strings.EqualFold(log.Topics[0], TransferTopic) {
+		return nil
+	}
+	from, to := strings.TrimPrefix(log.Topics[1], "0x"), strings.TrimPrefix(log.Topics[2], "0x")
+	words, ok := splitWords(strings.TrimPrefix(log.Data, "0x"), 1)
+	if !ok || len(from) != wordLength || len(to) != wordLength {
+		return nil
+	}
+	return &TokenTransfer{Token: strings.ToLower(log.Address), From: wordAddress(from), To: wordAddress(to), Value: wordValue(words[0])}
+}
+
+func splitWords(args string, count int) ([]string, bool) {
+	if len(args) < count*wordLength {
+		return nil, false
+	}
+	words := make([]string, count)
+	for i := range words {
+		words[i] = args[i*wordLength : (i+1)*wordLength]
+	}
+	return words, true
+}
+
+func wordAddress(word string) string {
+	return "0x" + strings.ToLower(word[wordLength-40:])
+}
+
+func wordValue(word string) string {
+	return "0x" + HexToBig(word).Text(16)
+}
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var ErrUnknownToken = errors.New("token not tracked")
+
+// TokenBalance is the balance of an address in an ERC-20 token after Block.
+type TokenBalance struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+	// Symbol and Decimals are empty until read from the contract, and Value
+	// is the Amount until then
+	Symbol   string `json:"symbol,omitempty"`
+	Decimals int    `json:"decimals"`
+	Block    int    `json:"block"`
+	// Amount is in the smallest unit of the token and Value in whole tokens
+	Amount          string        `json:"amount"`
+	Value           string        `json:"value"`
+	ReconciledBlock int           `json:"reconciledBlock,omitempty"`
+	Drift           *BalanceDrift `json:"drift,omitempty"`
+}
+
+// TokenBalancePoint is the token balance after a block that changed it.
+type TokenBalancePoint struct {
+	Block  int    `json:"block"`
+	Amount string `json:"amount"`
+	Value  string `json:"value"`
+	// Change is the signed difference to the previous point in the smallest
+	// unit of the token
+	Change string `json:"change"`
+	Source string `json:"source"`
+}
+
+// formatToken expects the read lock to be held.
+func (b *Balances) formatToken(token string, amount *big.Int) (string, string, int) {
+	info, exists := b.tokenInfo[token]
+	if !exists {
+		return amount.String(), "", 0
+	}
+	return FormatUnits(amount, info.Decimals), info.Symbol, info.Decimals
+}
+
+// Tokens returns the current balances of an address in the tracked tokens
+// read from the node so far, in the configured order.
+func (b *Balances) Tokens(address string) ([]*TokenBalance, error) {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	tracked, exists := b.addresses[address]
+	if !exists {
+		return nil, ErrBalancePending
+	}
+	balances := []*TokenBalance{}
+	for _, token := range b.tokens {
+		l, exists := tracked.tokens[token]
+		if !exists || len(l.history) == 0 {
+			continue
+		}
+		value, symbol, decimals := b.formatToken(token, l.amount)
+		balances = append(balances, &TokenBalance{
+			Address:         address,
+			Token:           token,
+			Symbol:          symbol,
+			Decimals:        decimals,
+			Block:           l.block,
+			Amount:          l.amount.String(),
+			Value:           value,
+			ReconciledBlock: l.reconciledBlock,
+			Drift:           l.drift,
+		})
+	}
+	return balances, nil
+}
+
+// TokenHistory returns the changes of the balance of an address in a token
+// in the blocks from..to, both included, oldest first. A zero to is open.
+func (b *Balances) TokenHistory(address, token string, from, to int) ([]*TokenBalancePoint, error) {
+	if err := checkBlockRange(from, to); err != nil {
+		return nil, err
+	}
+	token = strings.ToLower(token)
+
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	if !b.tracksToken(token) {
+		return nil, ErrUnknownToken
+	}
+	tracked, exists := b.addresses[address]
+	if !exists || tracked.tokens[token] == nil || len(tracked.tokens[token].history) == 0 {
+		return nil, ErrBalancePending
+	}
+	points := []*TokenBalancePoint{}
+	for _, p := range tracked.tokens[token].points(from, to) {
+		value, _, _ := b.formatToken(token, p.amount)
+		points = append(points, &TokenBalancePoint{
+			Block:  p.block,
+			Amount: p.amount.String(),
+			Value:  value,
+			Change: p.change.String(),
+			Source: p.source,
+		})
+	}
+	return points, nil
+}
+
+// tracksToken expects the read lock to be held.
+func (b *Balances) tracksToken(token string) bool {
+	for _, tracked := range b.tokens {
+		if tracked == token {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTokenBalances returns the balances of a subscribed address in the
+// tracked tokens.
+func (s *Service) GetTokenBalances(ctx context.Context, address string) ([]*TokenBalance, error) {
+	address = normalizeAddress(address)
+
+	if err := s.checkBalances(ctx, address); err != nil {
+		return nil, err
+	}
+	return s.balances.Tokens(address)
+}
+
+// GetTokenBalanceHistory returns the changes of the balance of a subscribed
+// address in a tracked token in the blocks from..to.
+func (s *Service) GetTokenBalanceHistory(ctx context.Context, address, token string, from, to int) ([]*TokenBalancePoint, error) {
+	if err := s.checkBalances(ctx, address); err != nil {
+		return nil, err
+	}
+	return s.balances.TokenHistory(address, token, from, to)
+}
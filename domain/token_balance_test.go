@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deshev.com/eth-address-watch/config"
+)
+
+// holder is checksummed, as subscribed, while logs carry lower case topics.
+const holder = "0x21A31Ee1afC51d94C2eFcCAA2092aD1028285549"
+
+func transferLog(from, to string, value int64) *Log {
+	topic := func(address string) string {
+		return "0x" + strings.Repeat("0", 24) + strings.ToLower(strings.TrimPrefix(address, "0x"))
+	}
+	return &Log{
+		Address: tokenAddress,
+		Topics:  []string{TransferTopic, topic(from), topic(to)},
+		Data:    fmt.Sprintf("0x%064x", value),
+	}
+}
+
+func Test_Balances_Tokens(t *testing.T) {
+	ctx := context.Background()
+	other := "0x28c6c06298d514db089934071355e5743bf21d60"
+	source := &fakeBalanceSource{
+		balances:      map[string]*big.Int{holder: ether("1")},
+		tokenBalances: map[string]map[string]*big.Int{tokenAddress: {holder: big.NewInt(1_000_000)}},
+		logs: map[int][]*Log{
+			100: {transferLog(other, holder, 1)},
+			101: {
+				transferLog(other, holder, 500_000),
+				transferLog(holder, other, 200_000),
+				{Address: tokenAddress, Topics: []string{TransferTopic, "0x" + fromWord, "0x" + toWord, "0x" + valueWord}},
+			},
+		},
+	}
+	cfg := config.Default()
+	cfg.TokenContracts = []string{"0xdAC17F958D2ee523a2206206994597C13D831ec7"}
+	log := slog.Default()
+	b := NewBalances(log, cfg, source, NewBus(log))
+	b.handle(ctx, &SubscriptionChanged{Address: holder, Subscribed: true})
+
+	// the balance read at block 100 already has its transfers
+	b.processBlock(ctx, &Block{NumberParsed: 100})
+	b.processBlock(ctx, &Block{NumberParsed: 101})
+
+	balances, err := b.Tokens(holder)
+	require.NoError(t, err)
+	assert.Equal(t, []*TokenBalance{{
+		Address: holder, Token: tokenAddress, Symbol: "USDT", Decimals: 6, Block: 101, Amount: "1300000", Value: "1.3",
+	}}, balances)
+
+	// a rebasing token changes balances without Transfer logs
+	source.tokenBalances[tokenAddress][holder] = big.NewInt(1_400_000)
+	b.reconcile(ctx)
+	balances, err = b.Tokens(holder)
+	require.NoError(t, err)
+	assert.Equal(t, "1.4", balances[0].Value)
+	assert.Equal(t, 101, balances[0].ReconciledBlock)
+	require.NotNil(t, balances[0].Drift)
+	assert.Equal(t, "100000", balances[0].Drift.Wei)
+
+	// without the logs the balance is read again at the next block
+	source.tokenBalances[tokenAddress][holder] = big.NewInt(2_000_000)
+	source.logs[103] = []*Log{}
+	b.processBlock(ctx, &Block{NumberParsed: 102})
+	b.processBlock(ctx, &Block{NumberParsed: 103})
+
+	history, err := b.TokenHistory(holder, "0xdAC17F958D2ee523a2206206994597C13D831ec7", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []*TokenBalancePoint{
+		{Block: 100, Amount: "1000000", Value: "1", Change: "1000000", Source: BalanceSourceSeed},
+		{Block: 101, Amount: "1300000", Value: "1.3", Change: "300000", Source: BalanceSourceTransactions},
+		{Block: 101, Amount: "1400000", Value: "1.4", Change: "100000", Source: BalanceSourceReconciliation},
+		{Block: 103, Amount: "2000000", Value: "2", Change: "600000", Source: BalanceSourceSeed},
+	}, history)
+
+	_, err = b.TokenHistory(holder, "0x1111", 0, 0)
+	assert.ErrorIs(t, err, ErrUnknownToken)
+
+	// the ETH balance is kept alongside
+	balance, err := b.Get(holder, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "1", balance.ETH)
+
+	// dropped tokens are no longer tracked
+	b.Reconfigure(config.Default())
+	b.processBlock(ctx, &Block{NumberParsed: 104})
+	balances, err = b.Tokens(holder)
+	require.NoError(t, err)
+	assert.Empty(t, balances)
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	tokenAddress = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	fromWord     = "00000000000000000000000028c6c06298d514db089934071355e5743bf21d60"
+	toWord       = "00000000000000000000000021A31EE1AFC51D94C2EFCCAA2092AD1028285549"
+	valueWord    = "00000000000000000000000000000000000000000000000000000000000f4240"
+)
+
+func Test_DecodeTokenTransfer(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   *Transaction
+		want *TokenTransfer
+	}{
+		{
+			name: "transfer",
+			tx:   &Transaction{From: "0x1111", To: tokenAddress, Input: "0xa9059cbb" + toWord + valueWord},
+			want: &TokenTransfer{
+				Token: tokenAddress,
+				From:  "0x1111",
+				To:    "0x21a31ee1afc51d94c2efccaa2092ad1028285549",
+				Value: "0xf4240",
+			},
+		},
+		{
+			name: "transferFrom",
+			tx:   &Transaction{From: "0x1111", To: tokenAddress, Input: "0x23b872dd" + fromWord + toWord + valueWord},
+			want: &TokenTransfer{
+				Token: tokenAddress,
+				From:  "0x28c6c06298d514db089934071355e5743bf21d60",
+				To:    "0x21a31ee1afc51d94c2efccaa2092ad1028285549",
+				Value: "0xf4240",
+			},
+		},
+		{
+			name: "short input",
+			tx:   &Transaction{From: "0x1111", To: tokenAddress, Input: "0xa9059cbb" + toWord},
+		},
+		{
+			name: "other method",
+			tx:   &Transaction{From: "0x1111", To: tokenAddress, Input: "0x095ea7b3" + toWord + valueWord},
+		},
+		{
+			name: "plain transfer",
+			tx:   &Transaction{From: "0x1111", To: "0x2222", Value: "0x10"},
+		},
+		{
+			name: "contract creation",
+			tx:   &Transaction{From: "0x1111", Input: "0xa9059cbb" + toWord + valueWord},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DecodeTokenTransfer(tt.tx))
+		})
+	}
+}
+
+func Test_DecodeTransferLog(t *testing.T) {
+	log := &Log{
+		Address: "0xdAC17F958D2ee523a2206206994597C13D831ec7",
+		Topics:  []string{TransferTopic, "0x" + fromWord, "0x" + toWord},
+		Data:    "0x" + valueWord,
+	}
+	assert.Equal(t, &TokenTransfer{
+		Token: tokenAddress,
+		From:  "0x28c6c06298d514db089934071355e5743bf21d60",
+		To:    "0x21a31ee1afc51d94c2efccaa2092ad1028285549",
+		Value: "0xf4240",
+	}, DecodeTransferLog(log))
+
+	// ERC-721 indexes the token id
+	nft := &Log{Address: tokenAddress, Topics: []string{TransferTopic, "0x" + fromWord, "0x" + toWord, "0x" + valueWord}, Data: "0x"}
+	assert.Nil(t, DecodeTransferLog(nft))
+	approval := &Log{Address: tokenAddress, Topics: []string{"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925", "0x" + fromWord, "0x" + toWord}, Data: "0x" + valueWord}
+	assert.Nil(t, DecodeTransferLog(approval))
+	assert.Nil(t, DecodeTransferLog(&Log{Address: tokenAddress, Topics: []string{TransferTopic, "0x" + fromWord, "0x" + toWord}, Data: "0x"}))
+}
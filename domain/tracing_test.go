@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/tracing/tracingtest"
+)
+
+func Test_Tracing_TickAndProcessBlock(t *testing.T) {
+	recorder := tracingtest.Record(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log := slog.Default()
+	bus := NewBus(log)
+	s := NewService(log, bus)
+	matches := bus.Subscribe("test", 1, OverflowBlock, OfKind(KindTransactionMatched))
+	s.Subscribe(ctx, "0x1111")
+	go func() {
+		_ = s.Start(ctx)
+	}()
+
+	w := NewWatcher(log, config.Default(), stubClient(t, 0x11, []*Block{
+		{Number: "0x11", Transactions: []*Transaction{{From: "0x1111"}}},
+	}), bus)
+	w.lastBlock = 0x10
+	w.tick()
+	<-matches.C
+
+	ticks := tracingtest.Named(recorder, "Watcher.tick")
+	require.Len(t, ticks, 1)
+	assert.Equal(t, codes.Unset, ticks[0].Status().Code)
+	require.Eventually(t, func() bool {
+		return len(tracingtest.Named(recorder, "Service.processBlock")) == 1
+	}, time.Second, time.Millisecond)
+	processed := tracingtest.Named(recorder, "Service.processBlock")[0]
+	assert.Equal(t, ticks[0].SpanContext().TraceID(), processed.SpanContext().TraceID())
+	assert.Equal(t, ticks[0].SpanContext().SpanID(), processed.Parent().SpanID())
+}
+
+func Test_Tracing_TickError(t *testing.T) {
+	recorder := tracingtest.Record(t)
+	log := slog.Default()
+	client := &MockETHClient{}
+	client.On("GetLatestBlock", mock.Anything).Return(0, errors.New("node down"))
+
+	NewWatcher(log, config.Default(), client, NewBus(log)).tick()
+
+	ticks := tracingtest.Named(recorder, "Watcher.tick")
+	require.Len(t, ticks, 1)
+	assert.Equal(t, codes.Error, ticks[0].Status().Code)
+	assert.Equal(t, "node down", ticks[0].Status().Description)
+}
@@ -4,19 +4,33 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/tracing"
 )
 
 type Watcher struct {
 	log       *slog.Logger
-	config    *config.Config
 	ethClient ETHClient
 
-	blockOutput chan<- *Block
-	lastBlock   int
-	nextBlock   int
+	bus       *Bus
+	lastBlock int
+	lastHash  string
+	nextBlock int
+
+	// progress of the last tick, read by the readiness checks, and the
+	// config, replaced by Reconfigure
+	mtx        sync.Mutex
+	head       int
+	fetched    int
+	staleTicks int
+	config     *config.Config
+	// reconfigured wakes Start to reset its ticker
+	reconfigured chan struct{}
 }
 
 type ETHClient interface {
@@ -24,22 +38,39 @@ type ETHClient interface {
 	GetBlock(ctx context.Context, blockNumber int) (*Block, error)
 }
 
-const (
-	blockTickInterval = 10 * time.Second
-	tickTimeout       = 5 * time.Second
-)
-
-func NewWatcher(log *slog.Logger, cfg *config.Config, client ETHClient, blockOutput chan<- *Block) *Watcher {
+func NewWatcher(log *slog.Logger, cfg *config.Config, client ETHClient, bus *Bus) *Watcher {
 	return &Watcher{
-		log:         log,
-		config:      cfg,
-		ethClient:   client,
-		blockOutput: blockOutput,
-		lastBlock:   0,
-		nextBlock:   0,
+		log:       log,
+		config:    cfg,
+		ethClient: client,
+		bus:       bus,
+		lastBlock: 0,
+		nextBlock: 0,
+
+		reconfigured: make(chan struct{}, 1),
 	}
 }
 
+// Reconfigure applies the tick interval, tick timeout and readiness limits of
+// cfg from the next tick on.
+func (w *Watcher) Reconfigure(cfg *config.Config) {
+	w.mtx.Lock()
+	w.config = cfg
+	w.mtx.Unlock()
+
+	select {
+	case w.reconfigured <- struct{}{}:
+	default:
+	}
+}
+
+func (w *Watcher) settings() *config.Config {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	return w.config
+}
+
 func (w *Watcher) Start(ctx context.Context) error {
 	w.log.Info("starting block watcher")
 	blockNumber, err := w.ethClient.GetLatestBlock(ctx)
@@ -49,14 +80,25 @@ func (w *Watcher) Start(ctx context.Context) error {
 
 	w.nextBlock = blockNumber
 	w.lastBlock = blockNumber - 1
+	w.mtx.Lock()
+	w.head, w.fetched = blockNumber, w.lastBlock
+	w.mtx.Unlock()
+	headBlock.With().Set(float64(blockNumber))
 	w.log.Info("next block", "block", blockNumber)
 
-	ticker := time.NewTicker(blockTickInterval)
+	interval := w.settings().BlockTickInterval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
+		case <-w.reconfigured:
+			if next := w.settings().BlockTickInterval; next != interval {
+				w.log.Info("block tick interval changed", "interval", next)
+				interval = next
+				ticker.Reset(interval)
+			}
 		case <-ticker.C:
 			w.tick()
 		}
@@ -66,27 +108,67 @@ func (w *Watcher) Start(ctx context.Context) error {
 func (w *Watcher) tick() {
 	w.log.Info("ethereum watcher tick")
 
-	ctx, cancel := context.WithTimeout(context.Background(), tickTimeout)
+	ctx, span := tracing.Start(context.Background(), "Watcher.tick")
+	defer span.End()
+	defer w.recordProgress(w.lastBlock)
+	ctx, cancel := context.WithTimeout(ctx, w.settings().TickTimeout)
 	defer cancel()
 
 	blockNumber, err := w.ethClient.GetLatestBlock(ctx)
 	if err != nil {
 		w.log.Error("error getting latest block", "error", err)
+		tracing.Fail(span, err)
 		return
 	}
 
 	w.nextBlock = blockNumber
+	headBlock.With().Set(float64(blockNumber))
+	span.SetAttributes(attribute.Int("block.head", blockNumber), attribute.Int("block.from", w.lastBlock+1))
 	for i := w.lastBlock + 1; i <= w.nextBlock; i++ {
 		w.log.Info("ethereum watcher processing block", "block", i)
 
 		block, err := w.ethClient.GetBlock(ctx, i)
 		if err != nil {
 			w.log.Error("error getting block", "block", i, "error", err)
+			tracing.Fail(span, err)
 			return
 		}
 
-		w.lastBlock = i
 		block.NumberParsed = i
-		w.blockOutput <- block
+		block.stampTransactions()
+		w.checkReorg(block)
+		w.lastBlock = i
+		w.lastHash = block.Hash
+		w.bus.Publish(&BlockIngested{Block: block, SpanContext: span.SpanContext()})
+	}
+}
+
+// recordProgress counts the ticks in a row that did not advance lastBlock.
+func (w *Watcher) recordProgress(before int) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.lastBlock > before {
+		w.staleTicks = 0
+	} else {
+		w.staleTicks++
 	}
+	w.head = w.nextBlock
+	w.fetched = w.lastBlock
+}
+
+// checkReorg only detects chain reorganizations; blocks that have already
+// been published are not rolled back.
+func (w *Watcher) checkReorg(block *Block) {
+	if w.lastHash == "" || block.ParentHash == "" || block.ParentHash == w.lastHash {
+		return
+	}
+
+	w.log.Warn("chain reorganization detected", "block", block.NumberParsed,
+		"parent_hash", block.ParentHash, "expected_parent_hash", w.lastHash)
+	w.bus.Publish(&ReorgDetected{
+		BlockNumber:        block.NumberParsed,
+		ParentHash:         block.ParentHash,
+		ExpectedParentHash: w.lastHash,
+	})
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -16,14 +17,13 @@ func Test_Watcher_Start(t *testing.T) {
 	log := slog.Default()
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	cfg := &config.Config{}
+	cfg := config.Default()
 
 	client := stubClient(t, 0x11, []*Block{
 		{Number: "0x11"},
 	})
 
-	blockC := make(chan *Block)
-	w := NewWatcher(log, cfg, client, blockC)
+	w := NewWatcher(log, cfg, client, NewBus(log))
 	end := make(chan struct{})
 	var err error
 	go func() {
@@ -38,10 +38,42 @@ func Test_Watcher_Start(t *testing.T) {
 	assert.Equal(t, 0x11, w.nextBlock)
 }
 
+func Test_Watcher_Reconfigure(t *testing.T) {
+	log := slog.Default()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.Default()
+	cfg.BlockTickInterval = time.Hour
+
+	ticked := make(chan struct{}, 1)
+	client := &MockETHClient{}
+	client.On("GetLatestBlock", mock.Anything).Return(0x11, nil).Once()
+	client.On("GetLatestBlock", mock.Anything).Return(0x10, nil).Run(func(mock.Arguments) {
+		select {
+		case ticked <- struct{}{}:
+		default:
+		}
+	})
+
+	w := NewWatcher(log, cfg, client, NewBus(log))
+	go func() {
+		assert.NoError(t, w.Start(ctx))
+	}()
+
+	faster := *cfg
+	faster.BlockTickInterval = 10 * time.Millisecond
+	w.Reconfigure(&faster)
+	select {
+	case <-ticked:
+	case <-time.After(time.Second):
+		t.Fatal("the watcher did not tick at the new interval")
+	}
+}
+
 func Test_Tick_SingleBlock(t *testing.T) {
 	log := slog.Default()
 
-	cfg := &config.Config{}
+	cfg := config.Default()
 
 	client := stubClient(t, 0x11, []*Block{
 		{
@@ -52,8 +84,9 @@ func Test_Tick_SingleBlock(t *testing.T) {
 		},
 	})
 
-	blockC := make(chan *Block, 1)
-	w := NewWatcher(log, cfg, client, blockC)
+	bus := NewBus(log)
+	blockC := bus.Subscribe("test", 1, OverflowBlock, OfKind(KindBlockIngested))
+	w := NewWatcher(log, cfg, client, bus)
 	w.lastBlock = 0x10
 	w.nextBlock = 0x10
 
@@ -62,21 +95,21 @@ func Test_Tick_SingleBlock(t *testing.T) {
 	assert.Equal(t, 0x11, w.lastBlock)
 	assert.Equal(t, 0x11, w.nextBlock)
 
-	close(blockC)
-	block, hasBlock := <-blockC
+	blockC.Close()
+	block, hasBlock := nextBlock(blockC)
 	assert.True(t, hasBlock)
 	assert.Equal(t, 0x11, block.NumberParsed)
 	tx := block.Transactions[0]
 	assert.Equal(t, "0x1111", tx.From)
 	assert.Equal(t, "0x1112", tx.To)
-	_, hasBlock = <-blockC
+	_, hasBlock = nextBlock(blockC)
 	assert.False(t, hasBlock)
 }
 
 func Test_Tick_CatchupMultipleBlocks(t *testing.T) {
 	log := slog.Default()
 
-	cfg := &config.Config{}
+	cfg := config.Default()
 
 	client := stubClient(t, 0x12, []*Block{
 		{
@@ -93,8 +126,9 @@ func Test_Tick_CatchupMultipleBlocks(t *testing.T) {
 		},
 	})
 
-	blockC := make(chan *Block, 2)
-	w := NewWatcher(log, cfg, client, blockC)
+	bus := NewBus(log)
+	blockC := bus.Subscribe("test", 2, OverflowBlock, OfKind(KindBlockIngested))
+	w := NewWatcher(log, cfg, client, bus)
 	w.lastBlock = 0x10
 	w.nextBlock = 0x10
 
@@ -103,29 +137,29 @@ func Test_Tick_CatchupMultipleBlocks(t *testing.T) {
 	assert.Equal(t, 0x12, w.lastBlock)
 	assert.Equal(t, 0x12, w.nextBlock)
 
-	close(blockC)
-	block1, hasBlock := <-blockC
+	blockC.Close()
+	block1, hasBlock := nextBlock(blockC)
 	assert.True(t, hasBlock)
 	assert.Equal(t, 0x11, block1.NumberParsed)
 	tx1 := block1.Transactions[0]
 	assert.Equal(t, "0x1111", tx1.From)
 	assert.Equal(t, "0x1112", tx1.To)
 
-	block2, hasBlock := <-blockC
+	block2, hasBlock := nextBlock(blockC)
 	assert.True(t, hasBlock)
 	assert.Equal(t, 0x12, block2.NumberParsed)
 	tx2 := block2.Transactions[0]
 	assert.True(t, hasBlock)
 	assert.Equal(t, "0x2111", tx2.From)
 	assert.Equal(t, "0x2112", tx2.To)
-	_, hasBlock = <-blockC
+	_, hasBlock = nextBlock(blockC)
 	assert.False(t, hasBlock)
 }
 
 func Test_Tick_NoopIfCaughtUp(t *testing.T) {
 	log := slog.Default()
 
-	cfg := &config.Config{}
+	cfg := config.Default()
 
 	client := stubClient(t, 0x12, []*Block{
 		{
@@ -136,8 +170,9 @@ func Test_Tick_NoopIfCaughtUp(t *testing.T) {
 		},
 	})
 
-	blockC := make(chan *Block, 2)
-	w := NewWatcher(log, cfg, client, blockC)
+	bus := NewBus(log)
+	blockC := bus.Subscribe("test", 2, OverflowBlock, OfKind(KindBlockIngested))
+	w := NewWatcher(log, cfg, client, bus)
 	w.lastBlock = 0x12
 	w.nextBlock = 0x12
 
@@ -146,11 +181,50 @@ func Test_Tick_NoopIfCaughtUp(t *testing.T) {
 	assert.Equal(t, 0x12, w.lastBlock)
 	assert.Equal(t, 0x12, w.nextBlock)
 
-	close(blockC)
-	_, hasBlock := <-blockC
+	blockC.Close()
+	_, hasBlock := nextBlock(blockC)
 	assert.False(t, hasBlock)
 }
 
+func Test_Tick_DetectsReorg(t *testing.T) {
+	log := slog.Default()
+
+	cfg := config.Default()
+
+	client := stubClient(t, 0x11, []*Block{
+		{Number: "0x11", Hash: "0xb11", ParentHash: "0xother"},
+	})
+
+	bus := NewBus(log)
+	reorgs := bus.Subscribe("test", 1, OverflowBlock, OfKind(KindReorgDetected))
+	w := NewWatcher(log, cfg, client, bus)
+	w.lastBlock = 0x10
+	w.lastHash = "0xb10"
+	w.nextBlock = 0x10
+
+	w.tick()
+
+	reorgs.Close()
+	reorg, ok := (<-reorgs.C).(*ReorgDetected)
+	assert.True(t, ok)
+	assert.Equal(t, 0x11, reorg.BlockNumber)
+	assert.Equal(t, "0xother", reorg.ParentHash)
+	assert.Equal(t, "0xb10", reorg.ExpectedParentHash)
+	assert.Equal(t, "0xb11", w.lastHash)
+}
+
+func nextBlock(s *Subscriber) (*Block, bool) {
+	e, ok := <-s.C
+	if !ok {
+		return nil, false
+	}
+	ingested, ok := e.(*BlockIngested)
+	if !ok {
+		return nil, false
+	}
+	return ingested.Block, true
+}
+
 func stubClient(t *testing.T, lastBlock int, blocks []*Block) ETHClient {
 	t.Helper()
 
@@ -0,0 +1,151 @@
+// Package export writes stored transactions in formats for spreadsheets and
+// data warehouses. Every format has the columns of Row, and exports go
+// through the store in batches so memory use does not grow with the history.
+package export
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const (
+	DirectionIn   = "in"
+	DirectionOut  = "out"
+	DirectionSelf = "self"
+
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+
+	batchSize = 1000
+)
+
+// Row is a transaction from the point of view of a subscribed address.
+// Amounts are decimal strings since they do not fit 64-bit integers.
+type Row struct {
+	Address     string     `json:"address"`
+	ID          int        `json:"id"`
+	Hash        string     `json:"hash"`
+	BlockNumber int64      `json:"block_number"`
+	BlockTime   *time.Time `json:"block_time"`
+	From        string     `json:"from"`
+	To          string     `json:"to"`
+	Direction   string     `json:"direction"`
+	ValueWei    string     `json:"value_wei"`
+	ValueETH    string     `json:"value_eth"`
+	Gas         int64      `json:"gas"`
+	GasPriceWei string     `json:"gas_price_wei"`
+	Status      string     `json:"status"`
+}
+
+func NewRow(address string, tx *domain.IndexedTransaction) *Row {
+	value := domain.HexToBig(tx.Value)
+	row := &Row{
+		Address:     address,
+		ID:          tx.ID,
+		Hash:        tx.Hash,
+		BlockNumber: domain.HexToBig(tx.BlockNumber).Int64(),
+		From:        tx.From,
+		To:          tx.To,
+		Direction:   direction(address, tx.Transaction),
+		ValueWei:    value.String(),
+		ValueETH:    domain.FormatEther(value),
+		Gas:         domain.HexToBig(tx.Gas).Int64(),
+		GasPriceWei: domain.HexToBig(tx.GasPrice).String(),
+	}
+	if blockTime := tx.Time(); !blockTime.IsZero() {
+		row.BlockTime = &blockTime
+	}
+	switch tx.Status {
+	case domain.TxStatusSuccess:
+		row.Status = StatusSuccess
+	case domain.TxStatusFailed:
+		row.Status = StatusFailed
+	}
+	return row
+}
+
+func direction(address string, tx *domain.Transaction) string {
+	from, to := strings.EqualFold(tx.From, address), strings.EqualFold(tx.To, address)
+	switch {
+	case from && to:
+		return DirectionSelf
+	case to:
+		return DirectionIn
+	default:
+		return DirectionOut
+	}
+}
+
+// Filter selects transactions by block time, from included and to excluded.
+// A zero bound is open. Transactions with an unknown block time, stored before
+// block times were kept, are only exported without bounds.
+type Filter struct {
+	From time.Time
+	To   time.Time
+}
+
+func (f Filter) includes(row *Row) bool {
+	if f.From.IsZero() && f.To.IsZero() {
+		return true
+	}
+	if row.BlockTime == nil {
+		return false
+	}
+	return !row.BlockTime.Before(f.From) && (f.To.IsZero() || row.BlockTime.Before(f.To))
+}
+
+// Source pages through the stored transactions of an address, oldest first.
+type Source interface {
+	ScanTransactions(ctx context.Context, address string, afterID, limit int) ([]*domain.IndexedTransaction, error)
+}
+
+// Transactions writes the transactions of each address in turn and returns
+// the number of rows written. The store is read batchSize transactions at a
+// time. It does not close w.
+func Transactions(ctx context.Context, src Source, addresses []string, filter Filter, w Writer) (int, error) {
+	written := 0
+	for _, address := range addresses {
+		afterID := 0
+		for {
+			batch, err := src.ScanTransactions(ctx, address, afterID, batchSize)
+			if err != nil {
+				return written, fmt.Errorf("export of %s failed: %w", address, err)
+			}
+			for _, tx := range batch {
+				row := NewRow(address, tx)
+				if !filter.includes(row) {
+					continue
+				}
+				if err := w.Write(row); err != nil {
+					return written, fmt.Errorf("export write error: %w", err)
+				}
+				written++
+			}
+			if len(batch) < batchSize {
+				break
+			}
+			afterID = batch[len(batch)-1].ID
+		}
+	}
+	return written, nil
+}
+
+// ParseTime parses a Filter bound, either RFC 3339 or a YYYY-MM-DD date in
+// UTC. The empty string is an open bound.
+func ParseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not RFC 3339 or YYYY-MM-DD", value)
+	}
+	return t, nil
+}
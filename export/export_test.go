@@ -0,0 +1,205 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const (
+	address1 = "0x0000000000000000000000000000000000000001"
+	address2 = "0x0000000000000000000000000000000000000002"
+)
+
+// source is a store of transactions by address, with IDs counting from 1.
+type source map[string][]*domain.Transaction
+
+func (s source) ScanTransactions(_ context.Context, address string, afterID, limit int) ([]*domain.IndexedTransaction, error) {
+	txs, exists := s[address]
+	if !exists {
+		return nil, domain.ErrNotSubscribed
+	}
+	batch := []*domain.IndexedTransaction{}
+	for i := afterID; i < len(txs) && len(batch) < limit; i++ {
+		batch = append(batch, &domain.IndexedTransaction{ID: i + 1, Transaction: txs[i]})
+	}
+	return batch, nil
+}
+
+// stamp is the hex block timestamp of a day in January 2024.
+func stamp(day int) string {
+	return fmt.Sprintf("0x%x", time.Date(2024, 1, day, 12, 0, 0, 0, time.UTC).Unix())
+}
+
+var transfers = source{
+	address1: {
+		{Hash: "0xa", BlockNumber: "0x10", From: address2, To: address1, Value: "0x1bc16d674ec80000", Gas: "0x5208", GasPrice: "0x3b9aca00", Status: domain.TxStatusSuccess, BlockTimestamp: stamp(1)},
+		{Hash: "0xb", BlockNumber: "0x11", From: address1, To: address2, Value: "0x1", Gas: "0x5208", GasPrice: "0x3b9aca00", Status: domain.TxStatusFailed, BlockTimestamp: stamp(2)},
+		{Hash: "0xc", BlockNumber: "0x12", From: address1, To: address1, Value: "0x0", Gas: "0x5208", GasPrice: "0x3b9aca00", BlockTimestamp: stamp(3)},
+	},
+	address2: {
+		{Hash: "0xd", BlockNumber: "0x9", From: address2, To: "0x3333", Value: "0x2386f26fc10000"},
+	},
+}
+
+func Test_NewRow(t *testing.T) {
+	row := NewRow(address1, &domain.IndexedTransaction{ID: 1, Transaction: transfers[address1][0]})
+	blockTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, &Row{
+		Address:     address1,
+		ID:          1,
+		Hash:        "0xa",
+		BlockNumber: 16,
+		BlockTime:   &blockTime,
+		From:        address2,
+		To:          address1,
+		Direction:   DirectionIn,
+		ValueWei:    "2000000000000000000",
+		ValueETH:    "2",
+		Gas:         21000,
+		GasPriceWei: "1000000000",
+		Status:      StatusSuccess,
+	}, row)
+
+	row = NewRow(address2, &domain.IndexedTransaction{ID: 1, Transaction: transfers[address2][0]})
+	assert.Equal(t, DirectionOut, row.Direction)
+	assert.Equal(t, "0.01", row.ValueETH)
+	assert.Nil(t, row.BlockTime)
+	assert.Empty(t, row.Status)
+}
+
+func Test_Transactions_CSV(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWriter(&out, FormatCSV)
+	require.NoError(t, err)
+
+	written, err := Transactions(context.Background(), transfers, []string{address1, address2}, Filter{}, w)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, 4, written)
+	assert.Equal(t, "address,id,hash,block_number,block_time,from,to,direction,value_wei,value_eth,gas,gas_price_wei,status\n"+
+		address1+",1,0xa,16,2024-01-01T12:00:00Z,"+address2+","+address1+",in,2000000000000000000,2,21000,1000000000,success\n"+
+		address1+",2,0xb,17,2024-01-02T12:00:00Z,"+address1+","+address2+",out,1,0.000000000000000001,21000,1000000000,failed\n"+
+		address1+",3,0xc,18,2024-01-03T12:00:00Z,"+address1+","+address1+",self,0,0,21000,1000000000,\n"+
+		address2+",1,0xd,9,,"+address2+",0x3333,out,10000000000000000,0.01,0,0,\n", out.String())
+}
+
+func Test_Transactions_NDJSON(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWriter(&out, FormatNDJSON)
+	require.NoError(t, err)
+
+	filter := Filter{From: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)}
+	written, err := Transactions(context.Background(), transfers, []string{address1, address2}, filter, w)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, 1, written)
+	assert.JSONEq(t, `{"address":"`+address1+`","id":2,"hash":"0xb","block_number":17,"block_time":"2024-01-02T12:00:00Z",`+
+		`"from":"`+address1+`","to":"`+address2+`","direction":"out","value_wei":"1","value_eth":"0.000000000000000001",`+
+		`"gas":21000,"gas_price_wei":"1000000000","status":"failed"}`, out.String())
+}
+
+func Test_Transactions_Parquet(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWriter(&out, FormatParquet)
+	require.NoError(t, err)
+
+	written, err := Transactions(context.Background(), transfers, []string{address1, address2}, Filter{}, w)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, 4, written)
+
+	rows, err := parquet.Read[parquetRow](bytes.NewReader(out.Bytes()), int64(out.Len()))
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, parquetRow{
+		Address:     address1,
+		ID:          1,
+		Hash:        "0xa",
+		BlockNumber: 16,
+		BlockTime:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).UnixMilli(),
+		From:        address2,
+		To:          address1,
+		Direction:   DirectionIn,
+		ValueWei:    "2000000000000000000",
+		ValueETH:    "2",
+		Gas:         21000,
+		GasPriceWei: "1000000000",
+		Status:      StatusSuccess,
+	}, rows[0])
+	assert.Zero(t, rows[3].BlockTime)
+
+	file, err := parquet.OpenFile(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	require.NoError(t, err)
+	blockTime, _ := file.Schema().Lookup("block_time")
+	assert.True(t, blockTime.Node.Optional())
+	assert.Equal(t, Columns, func() []string {
+		names := []string{}
+		for _, field := range file.Schema().Fields() {
+			names = append(names, field.Name())
+		}
+		return names
+	}())
+}
+
+func Test_Transactions_Batches(t *testing.T) {
+	long := source{address1: {}}
+	for i := 0; i < batchSize*2+1; i++ {
+		long[address1] = append(long[address1], &domain.Transaction{Hash: fmt.Sprintf("0x%x", i), From: address1})
+	}
+
+	var out bytes.Buffer
+	w, err := NewWriter(&out, FormatNDJSON)
+	require.NoError(t, err)
+	written, err := Transactions(context.Background(), long, []string{address1}, Filter{}, w)
+	require.NoError(t, err)
+	assert.Equal(t, batchSize*2+1, written)
+	assert.Equal(t, batchSize*2+1, strings.Count(out.String(), "\n"))
+}
+
+func Test_Transactions_Errors(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, "xml")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	w, err := NewWriter(&bytes.Buffer{}, FormatCSV)
+	require.NoError(t, err)
+	_, err = Transactions(context.Background(), transfers, []string{"0x3333"}, Filter{}, w)
+	assert.ErrorIs(t, err, domain.ErrNotSubscribed)
+
+	_, err = Transactions(context.Background(), transfers, []string{address1}, Filter{}, failingWriter{})
+	assert.ErrorIs(t, err, errClosed)
+}
+
+var errClosed = errors.New("closed")
+
+type failingWriter struct{}
+
+func (failingWriter) Write(*Row) error { return errClosed }
+func (failingWriter) Close() error     { return nil }
+
+func Test_ParseTime(t *testing.T) {
+	day, err := ParseTime("2024-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), day)
+
+	instant, err := ParseTime("2024-01-02T03:04:05+02:00")
+	require.NoError(t, err)
+	assert.True(t, instant.Equal(time.Date(2024, 1, 2, 1, 4, 5, 0, time.UTC)))
+
+	open, err := ParseTime("")
+	require.NoError(t, err)
+	assert.True(t, open.IsZero())
+
+	_, err = ParseTime("yesterday")
+	assert.Error(t, err)
+}
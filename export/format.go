@@ -0,0 +1,181 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+
+	// rows buffered before the Parquet writer flushes a row group
+	parquetRowGroupSize = 10_000
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Writer writes rows in one format. Close writes whatever the format needs at
+// the end, like the Parquet footer, but does not close the underlying writer.
+type Writer interface {
+	Write(row *Row) error
+	Close() error
+}
+
+type format struct {
+	contentType string
+	new         func(io.Writer) Writer
+}
+
+var formats = map[string]*format{
+	FormatCSV:     {contentType: "text/csv; charset=utf-8", new: newCSVWriter},
+	FormatNDJSON:  {contentType: "application/x-ndjson", new: newNDJSONWriter},
+	FormatParquet: {contentType: "application/vnd.apache.parquet", new: newParquetWriter},
+}
+
+// NewWriter returns a writer of the format, one of FormatCSV, FormatNDJSON or
+// FormatParquet.
+func NewWriter(w io.Writer, name string) (Writer, error) {
+	f, exists := formats[name]
+	if !exists {
+		return nil, fmt.Errorf("%w %q, use csv, ndjson or parquet", ErrUnknownFormat, name)
+	}
+	return f.new(w), nil
+}
+
+// ContentType is the media type of a format.
+func ContentType(name string) string {
+	if f, exists := formats[name]; exists {
+		return f.contentType
+	}
+	return "application/octet-stream"
+}
+
+// Columns are the CSV header and the names of the NDJSON fields and Parquet
+// columns, in order.
+var Columns = []string{
+	"address", "id", "hash", "block_number", "block_time", "from", "to", "direction",
+	"value_wei", "value_eth", "gas", "gas_price_wei", "status",
+}
+
+type csvWriter struct {
+	out           *csv.Writer
+	headerWritten bool
+}
+
+func newCSVWriter(w io.Writer) Writer {
+	return &csvWriter{out: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) Write(row *Row) error {
+	if !w.headerWritten {
+		w.headerWritten = true
+		if err := w.out.Write(Columns); err != nil {
+			return err //nolint:wrapcheck // wrapped by Transactions
+		}
+	}
+	blockTime := ""
+	if row.BlockTime != nil {
+		blockTime = row.BlockTime.Format(time.RFC3339)
+	}
+	return w.out.Write([]string{ //nolint:wrapcheck // wrapped by Transactions
+		row.Address, strconv.Itoa(row.ID), row.Hash, strconv.FormatInt(row.BlockNumber, 10), blockTime,
+		row.From, row.To, row.Direction, row.ValueWei, row.ValueETH,
+		strconv.FormatInt(row.Gas, 10), row.GasPriceWei, row.Status,
+	})
+}
+
+// Close writes the header of empty exports and flushes the rows.
+func (w *csvWriter) Close() error {
+	if !w.headerWritten {
+		w.headerWritten = true
+		_ = w.out.Write(Columns)
+	}
+	w.out.Flush()
+	return w.out.Error() //nolint:wrapcheck // wrapped by the caller
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) Writer {
+	return &ndjsonWriter{encoder: json.NewEncoder(w)}
+}
+
+func (w *ndjsonWriter) Write(row *Row) error {
+	return w.encoder.Encode(row) //nolint:wrapcheck // wrapped by Transactions
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+// parquetRow is Row in the Parquet schema. BlockTime is in milliseconds,
+// where 0 writes a null.
+type parquetRow struct {
+	Address     string `parquet:"address"`
+	ID          int64  `parquet:"id"`
+	Hash        string `parquet:"hash"`
+	BlockNumber int64  `parquet:"block_number"`
+	BlockTime   int64  `parquet:"block_time,timestamp(millisecond),optional"`
+	From        string `parquet:"from"`
+	To          string `parquet:"to"`
+	Direction   string `parquet:"direction"`
+	ValueWei    string `parquet:"value_wei"`
+	ValueETH    string `parquet:"value_eth"`
+	Gas         int64  `parquet:"gas"`
+	GasPriceWei string `parquet:"gas_price_wei"`
+	Status      string `parquet:"status"`
+}
+
+type parquetWriter struct {
+	out      *parquet.GenericWriter[parquetRow]
+	buffered int
+}
+
+func newParquetWriter(w io.Writer) Writer {
+	return &parquetWriter{out: parquet.NewGenericWriter[parquetRow](w)}
+}
+
+func (w *parquetWriter) Write(row *Row) error {
+	pr := parquetRow{
+		Address:     row.Address,
+		ID:          int64(row.ID),
+		Hash:        row.Hash,
+		BlockNumber: row.BlockNumber,
+		From:        row.From,
+		To:          row.To,
+		Direction:   row.Direction,
+		ValueWei:    row.ValueWei,
+		ValueETH:    row.ValueETH,
+		Gas:         row.Gas,
+		GasPriceWei: row.GasPriceWei,
+		Status:      row.Status,
+	}
+	if row.BlockTime != nil {
+		pr.BlockTime = row.BlockTime.UnixMilli()
+	}
+	if _, err := w.out.Write([]parquetRow{pr}); err != nil {
+		return err //nolint:wrapcheck // wrapped by Transactions
+	}
+
+	w.buffered++
+	if w.buffered < parquetRowGroupSize {
+		return nil
+	}
+	w.buffered = 0
+	return w.out.Flush() //nolint:wrapcheck // wrapped by Transactions
+}
+
+func (w *parquetWriter) Close() error {
+	return w.out.Close() //nolint:wrapcheck // wrapped by the caller
+}
@@ -0,0 +1,157 @@
+package expr
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Eval evaluates the expression. Variables hold *big.Int values for numbers,
+// strings and bools, matching the types the expression was compiled with.
+// Strings compare case-insensitively, and a string compared with a number is
+// read as a hex (0x-prefixed) or decimal number.
+func (e *Expr) Eval(vars map[string]any) (bool, error) {
+	value, err := eval(e.root, vars)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, errorAt(e.root.pos(), "expression is not boolean")
+	}
+	return result, nil
+}
+
+func eval(n node, vars map[string]any) (any, error) {
+	switch n := n.(type) {
+	case *literal:
+		return n.value, nil
+	case *variable:
+		return lookup(n, vars)
+	case *unary:
+		operand, err := eval(n.operand, vars)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := operand.(bool)
+		if !ok {
+			return nil, errorAt(n.p, "operator ! needs a bool operand")
+		}
+		return !b, nil
+	case *binary:
+		return evalBinary(n, vars)
+	default:
+		return nil, errorAt(n.pos(), "unknown expression node")
+	}
+}
+
+func lookup(n *variable, vars map[string]any) (any, error) {
+	value, ok := vars[n.name]
+	if !ok {
+		return nil, errorAt(n.p, "variable %q is not set", n.name)
+	}
+
+	switch value.(type) {
+	case *big.Int:
+		if n.t == TypeNumber {
+			return value, nil
+		}
+	case string:
+		if n.t == TypeString {
+			return value, nil
+		}
+	case bool:
+		if n.t == TypeBool {
+			return value, nil
+		}
+	}
+	return nil, errorAt(n.p, "variable %q is not a %s", n.name, n.t)
+}
+
+func evalBinary(n *binary, vars map[string]any) (any, error) {
+	left, err := eval(n.left, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// short-circuit so the right side is only evaluated when needed
+	switch n.op {
+	case "&&", "||":
+		l, _ := left.(bool)
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := eval(n.right, vars)
+		if err != nil {
+			return nil, err
+		}
+		r, _ := right.(bool)
+		return r, nil
+	}
+
+	right, err := eval(n.right, vars)
+	if err != nil {
+		return nil, err
+	}
+	return compare(n.op, left, right), nil
+}
+
+func compare(op string, left, right any) bool {
+	ls, lIsString := left.(string)
+	rs, rIsString := right.(string)
+	switch {
+	case lIsString && rIsString:
+		equal := strings.EqualFold(ls, rs)
+		return (op == "==") == equal
+	case lIsString:
+		left = parseNumber(ls)
+	case rIsString:
+		right = parseNumber(rs)
+	}
+
+	if lb, ok := left.(bool); ok {
+		rb, _ := right.(bool)
+		return (op == "==") == (lb == rb)
+	}
+
+	l, _ := left.(*big.Int)
+	r, _ := right.(*big.Int)
+	if l == nil || r == nil {
+		// a string that is not a number never equals a number
+		return op == "!="
+	}
+
+	c := l.Cmp(r)
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	default:
+		return false
+	}
+}
+
+func parseNumber(s string) *big.Int {
+	if strings.HasPrefix(s, "0x") {
+		value, ok := new(big.Int).SetString(s[2:], 16)
+		if ok {
+			return value
+		}
+		return nil
+	}
+
+	value, ok := new(big.Int).SetString(s, 10)
+	if ok {
+		return value
+	}
+	return nil
+}
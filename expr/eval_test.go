@@ -0,0 +1,62 @@
+package expr
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Eval(t *testing.T) {
+	vars := map[string]any{
+		"value":          new(big.Int).Mul(big.NewInt(20), units["ether"]),
+		"to":             "0xDAC17F958D2EE523A2206206994597C13D831EC7",
+		"self":           "0xdac17f958d2ee523a2206206994597c13d831ec7",
+		"input.selector": "0xa9059cbb",
+		"failed":         false,
+	}
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{source: "value > 10 ether && to == self && input.selector == 0xa9059cbb", want: true},
+		{source: "value > 20 ether", want: false},
+		{source: "value >= 20 ether", want: true},
+		{source: "value < 0x1", want: false},
+		{source: "value != 20000000000000000000", want: false},
+		{source: "to == 0xdac17f958d2ee523a2206206994597c13d831ec7", want: true},
+		{source: "input.selector == 0x23b872dd", want: false},
+		{source: "input.selector != 0x23b872dd", want: true},
+		{source: "to == 'not a number' || failed", want: false},
+		{source: "!failed && (failed || value > 1)", want: true},
+		{source: "failed == false", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			e, err := Compile(tt.source, testVars)
+			require.NoError(t, err)
+
+			got, err := e.Eval(vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_Eval_MissingVariable(t *testing.T) {
+	e, err := Compile("failed || value > 1", testVars)
+	require.NoError(t, err)
+
+	_, err = e.Eval(map[string]any{"failed": false})
+	assert.EqualError(t, err, "position 11: variable \"value\" is not set")
+
+	got, err := e.Eval(map[string]any{"failed": true})
+	assert.NoError(t, err)
+	assert.True(t, got)
+
+	_, err = e.Eval(map[string]any{"failed": "yes"})
+	assert.EqualError(t, err, "position 1: variable \"failed\" is not a bool")
+}
@@ -0,0 +1,107 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!"}
+
+// Error is a compile or evaluation error. Pos is the 1-based character
+// position in the expression source.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+func errorAt(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+func tokenize(source string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, errorAt(i, "unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[i+1 : end]), pos: i})
+			i = end + 1
+		case unicode.IsDigit(r):
+			end := i
+			for end < len(runes) && (isIdentRune(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[i:end]), pos: i})
+			i = end
+		case isIdentRune(r):
+			end := i
+			for end < len(runes) && (isIdentRune(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[i:end]), pos: i})
+			i = end
+		default:
+			op := matchOperator(string(runes[i:]))
+			if op == "" {
+				return nil, errorAt(i, "unexpected character %q", r)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+func matchOperator(rest string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(rest, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,317 @@
+package expr
+
+import (
+	"math/big"
+	"strings"
+)
+
+const (
+	maxSourceLength = 1024
+	maxDepth        = 32
+)
+
+type Type int
+
+const (
+	TypeBool Type = iota + 1
+	TypeNumber
+	TypeString
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeBool:
+		return "bool"
+	case TypeNumber:
+		return "number"
+	case TypeString:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+var units = map[string]*big.Int{
+	"wei":   big.NewInt(1),
+	"gwei":  big.NewInt(1_000_000_000),
+	"ether": big.NewInt(1_000_000_000_000_000_000),
+}
+
+// Expr is a compiled boolean expression. The language only has literals,
+// variables, comparisons and boolean operators, so evaluation always
+// terminates and cannot reach anything outside the variables it is given.
+type Expr struct {
+	source string
+	root   node
+}
+
+type node interface {
+	pos() int
+	typ() Type
+}
+
+type literal struct {
+	p     int
+	t     Type
+	value any
+}
+
+type variable struct {
+	p    int
+	t    Type
+	name string
+}
+
+type unary struct {
+	p       int
+	op      string
+	operand node
+}
+
+type binary struct {
+	p           int
+	op          string
+	left, right node
+}
+
+func (n *literal) pos() int  { return n.p }
+func (n *literal) typ() Type { return n.t }
+
+func (n *variable) pos() int  { return n.p }
+func (n *variable) typ() Type { return n.t }
+
+func (n *unary) pos() int  { return n.p }
+func (n *unary) typ() Type { return TypeBool }
+
+func (n *binary) pos() int  { return n.p }
+func (n *binary) typ() Type { return TypeBool }
+
+// Compile parses and type-checks an expression against the declared variables.
+// Errors are *Error values with the position of the offending token.
+func Compile(source string, vars map[string]Type) (*Expr, error) {
+	if len(source) > maxSourceLength {
+		return nil, errorAt(maxSourceLength, "expression longer than %d characters", maxSourceLength)
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, vars: vars}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, errorAt(next.pos, "unexpected %q", next.text)
+	}
+	if root.typ() != TypeBool {
+		return nil, errorAt(root.pos(), "expression must be boolean, got %s", root.typ())
+	}
+
+	return &Expr{source: source, root: root}, nil
+}
+
+func (e *Expr) String() string {
+	return e.source
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	vars   map[string]Type
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) isOperator(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogical("||", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogical("&&", p.parseNot)
+}
+
+func (p *parser) parseLogical(op string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator(op) {
+		t := p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if err := expectType(left, TypeBool, op); err != nil {
+			return nil, err
+		}
+		if err := expectType(right, TypeBool, op); err != nil {
+			return nil, err
+		}
+		left = &binary{p: t.pos, op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if !p.isOperator("!") {
+		return p.parseComparison()
+	}
+
+	t := p.next()
+	if err := p.enter(t); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if err := expectType(operand, TypeBool, "!"); err != nil {
+		return nil, err
+	}
+	return &unary{p: t.pos, op: "!", operand: operand}, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOperator("==", "!=", "<", "<=", ">", ">=") {
+		return left, nil
+	}
+
+	t := p.next()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	lt, rt := left.typ(), right.typ()
+	switch t.text {
+	case "==", "!=":
+		mixed := (lt == TypeNumber && rt == TypeString) || (lt == TypeString && rt == TypeNumber)
+		if lt != rt && !mixed {
+			return nil, errorAt(t.pos, "cannot compare %s with %s", lt, rt)
+		}
+	default:
+		if lt != TypeNumber || rt != TypeNumber {
+			return nil, errorAt(t.pos, "operator %s needs numbers, got %s and %s", t.text, lt, rt)
+		}
+	}
+	return &binary{p: t.pos, op: t.text, left: left, right: right}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLParen:
+		if err := p.enter(t); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, errorAt(closing.pos, "expected \")\"")
+		}
+		return inner, nil
+	case tokenNumber:
+		return p.parseNumber(t)
+	case tokenString:
+		return &literal{p: t.pos, t: TypeString, value: t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			return &literal{p: t.pos, t: TypeBool, value: t.text == "true"}, nil
+		}
+		typ, ok := p.vars[t.text]
+		if !ok {
+			return nil, errorAt(t.pos, "unknown variable %q", t.text)
+		}
+		return &variable{p: t.pos, t: typ, name: t.text}, nil
+	case tokenEOF:
+		return nil, errorAt(t.pos, "unexpected end of expression")
+	default:
+		return nil, errorAt(t.pos, "expected operand, got %q", t.text)
+	}
+}
+
+func (p *parser) parseNumber(t token) (node, error) {
+	unit := units["wei"]
+	if next := p.peek(); next.kind == tokenIdent {
+		if u, ok := units[strings.ToLower(next.text)]; ok {
+			unit = u
+			p.next()
+		}
+	}
+
+	if strings.HasPrefix(t.text, "0x") {
+		value, ok := new(big.Int).SetString(t.text[2:], 16)
+		if !ok {
+			return nil, errorAt(t.pos, "invalid number %q", t.text)
+		}
+		return &literal{p: t.pos, t: TypeNumber, value: value.Mul(value, unit)}, nil
+	}
+
+	value, ok := new(big.Rat).SetString(t.text)
+	if !ok {
+		return nil, errorAt(t.pos, "invalid number %q", t.text)
+	}
+	value.Mul(value, new(big.Rat).SetInt(unit))
+	if !value.IsInt() {
+		return nil, errorAt(t.pos, "number %q is not a whole number of wei", t.text)
+	}
+	return &literal{p: t.pos, t: TypeNumber, value: value.Num()}, nil
+}
+
+func (p *parser) enter(t token) error {
+	p.depth++
+	if p.depth > maxDepth {
+		return errorAt(t.pos, "expression nested deeper than %d levels", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func expectType(n node, want Type, op string) error {
+	if n.typ() != want {
+		return errorAt(n.pos(), "operator %s needs %s operands, got %s", op, want, n.typ())
+	}
+	return nil
+}
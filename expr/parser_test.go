@@ -0,0 +1,56 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testVars = map[string]Type{
+	"value":          TypeNumber,
+	"to":             TypeString,
+	"self":           TypeString,
+	"input.selector": TypeString,
+	"failed":         TypeBool,
+}
+
+func Test_Compile(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr string
+	}{
+		{source: "value > 10 ether && to == self && input.selector == 0xa9059cbb"},
+		{source: "!(value <= 1.5 gwei) || failed"},
+		{source: "to != 'contract' && failed == false"},
+		{source: "value >", wantErr: "position 8: unexpected end of expression"},
+		{source: "value > 10 ether &&", wantErr: "position 20: unexpected end of expression"},
+		{source: "(value > 1", wantErr: "position 11: expected \")\""},
+		{source: "value > 1)", wantErr: "position 10: unexpected \")\""},
+		{source: "balance > 1", wantErr: "position 1: unknown variable \"balance\""},
+		{source: "value > 1.5 wei", wantErr: "position 9: number \"1.5\" is not a whole number of wei"},
+		{source: "value > 10 && to", wantErr: "position 15: operator && needs bool operands, got string"},
+		{source: "to > self", wantErr: "position 4: operator > needs numbers, got string and string"},
+		{source: "failed == 1", wantErr: "position 8: cannot compare bool with number"},
+		{source: "value", wantErr: "position 1: expression must be boolean, got number"},
+		{source: "to == \"0x1", wantErr: "position 7: unterminated string"},
+		{source: "value = 1", wantErr: "position 7: unexpected character '='"},
+		{source: "value > 1 2", wantErr: "position 11: unexpected \"2\""},
+		{source: strings.Repeat("!", 40) + "failed", wantErr: "position 33: expression nested deeper than 32 levels"},
+		{source: strings.Repeat(" ", 1025), wantErr: "position 1025: expression longer than 1024 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			e, err := Compile(tt.source, testVars)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				var exprErr *Error
+				assert.ErrorAs(t, err, &exprErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.source, e.String())
+		})
+	}
+}
@@ -18,6 +18,9 @@
             gnumake
             pre-commit
             golangci-lint
+            protobuf
+            protoc-gen-go
+            protoc-gen-go-grpc
             jq
             curl
           ]);
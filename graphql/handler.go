@@ -0,0 +1,108 @@
+// Package graphql serves a read-only GraphQL API over the domain service, so
+// clients can fetch subscriptions and their transactions in one round trip.
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+const maxRequestBytes = 1 << 20
+
+// Request is a GraphQL request as sent in a POST body.
+type Request struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+}
+
+type Handler struct {
+	log    *slog.Logger
+	schema graphql.Schema
+	limits Limits
+}
+
+func NewHandler(log *slog.Logger, service Service) *Handler {
+	schema, err := newSchema(service)
+	if err != nil {
+		panic(err)
+	}
+	return &Handler{log: log, schema: schema, limits: DefaultLimits}
+}
+
+func (h *Handler) SetLimits(limits Limits) {
+	h.limits = limits
+}
+
+// ServeHTTP takes the request from a JSON body on POST or from the query,
+// variables and operationName parameters on GET. Requests that fail to parse,
+// validate or stay within the limits are rejected with 400 before anything
+// is resolved.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	gqlReq, err := readRequest(w, req)
+	if err != nil {
+		h.writeResult(w, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(gqlReq.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		h.writeResult(w, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	if validation := graphql.ValidateDocument(&h.schema, doc, nil); !validation.IsValid {
+		h.writeResult(w, http.StatusBadRequest, &graphql.Result{Errors: validation.Errors})
+		return
+	}
+	if err := checkLimits(doc, gqlReq.OperationName, gqlReq.Variables, h.limits); err != nil {
+		h.writeResult(w, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: gqlReq.OperationName,
+		Args:          gqlReq.Variables,
+		Context:       req.Context(),
+	})
+	h.writeResult(w, http.StatusOK, result)
+}
+
+func readRequest(w http.ResponseWriter, req *http.Request) (*Request, error) {
+	var gqlReq Request
+	if req.Method == http.MethodGet {
+		query := req.URL.Query()
+		gqlReq.Query = query.Get("query")
+		gqlReq.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &gqlReq.Variables); err != nil {
+				return nil, errors.New("variables must be a JSON object")
+			}
+		}
+	} else if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxRequestBytes)).Decode(&gqlReq); err != nil {
+		return nil, errors.New("request body must be a JSON object with a query")
+	}
+
+	if gqlReq.Query == "" {
+		return nil, errors.New("query missing")
+	}
+	return &gqlReq, nil
+}
+
+func (h *Handler) writeResult(w http.ResponseWriter, code int, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.log.Error("failed to write GraphQL response", "error", err)
+	}
+}
@@ -0,0 +1,277 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const (
+	address1 = "0x0000000000000000000000000000000000000001"
+	address2 = "0x0000000000000000000000000000000000000002"
+	token    = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	// transfer(address2, 1000000)
+	transferInput = "0xa9059cbb" +
+		"0000000000000000000000000000000000000000000000000000000000000002" +
+		"00000000000000000000000000000000000000000000000000000000000f4240"
+)
+
+type result struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// newTestHandler serves the schema over a service with two subscriptions
+// and three transactions of address1.
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	log := slog.Default()
+	bus := domain.NewBus(log)
+	service := domain.NewService(log, bus)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = service.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	_, err := service.Subscribe(ctx, address1)
+	require.NoError(t, err)
+	_, err = service.Subscribe(ctx, address2)
+	require.NoError(t, err)
+	require.NoError(t, service.SetMetadata(ctx, address1, domain.SubscriptionMetadata{Label: "hot", Tags: []string{"exchange"}}))
+
+	matches := bus.Subscribe("test", 10, domain.OverflowBlock, domain.OfKind(domain.KindTransactionMatched))
+	defer matches.Close()
+	bus.Publish(&domain.BlockIngested{Block: &domain.Block{NumberParsed: 0x10, Transactions: []*domain.Transaction{
+		{Hash: "0xa", BlockNumber: "0x10", From: "0x3333", To: address1, Value: "0x1", Status: domain.TxStatusSuccess},
+		{Hash: "0xb", BlockNumber: "0x10", From: address1, To: token, Input: transferInput, Status: domain.TxStatusFailed},
+		{Hash: "0xc", BlockNumber: "0x10", From: "0x3333", To: address1},
+	}}})
+	for range 3 {
+		<-matches.C
+	}
+
+	return NewHandler(log, service)
+}
+
+func post(t *testing.T, h http.Handler, query string, variables map[string]any) (int, *result) {
+	t.Helper()
+
+	body, err := json.Marshal(Request{Query: query, Variables: variables})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	var res result
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	return w.Code, &res
+}
+
+func Test_Query(t *testing.T) {
+	h := newTestHandler(t)
+
+	code, res := post(t, h, `{
+		block { number }
+		subscription(address: "`+address1+`") {
+			label
+			tags
+			transactions(first: 2) {
+				totalCount
+				nodes { id hash block { number } receipt { status failed } tokenTransfer { token from to value } }
+				pageInfo { hasNextPage }
+			}
+		}
+		missing: subscription(address: "0x9999") { address }
+	}`, nil)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, res.Errors)
+	assert.JSONEq(t, `{
+		"block": {"number": 16},
+		"subscription": {
+			"label": "hot",
+			"tags": ["exchange"],
+			"transactions": {
+				"totalCount": 3,
+				"nodes": [
+					{"id": 3, "hash": "0xc", "block": {"number": 16}, "receipt": null, "tokenTransfer": null},
+					{"id": 2, "hash": "0xb", "block": {"number": 16}, "receipt": {"status": "0x0", "failed": true},
+						"tokenTransfer": {"token": "`+token+`", "from": "`+address1+`", "to": "`+address2+`", "value": "0xf4240"}}
+				],
+				"pageInfo": {"hasNextPage": true}
+			}
+		},
+		"missing": null
+	}`, string(res.Data))
+}
+
+func Test_Pagination(t *testing.T) {
+	h := newTestHandler(t)
+	query := `query($after: String) {
+		transactions(address: "` + address1 + `", first: 2, after: $after) {
+			nodes { id }
+			pageInfo { endCursor hasNextPage }
+		}
+	}`
+
+	var page struct {
+		Transactions struct {
+			Nodes []struct {
+				ID int `json:"id"`
+			} `json:"nodes"`
+			PageInfo struct {
+				EndCursor   string `json:"endCursor"`
+				HasNextPage bool   `json:"hasNextPage"`
+			} `json:"pageInfo"`
+		} `json:"transactions"`
+	}
+	ids := []int{}
+	variables := map[string]any{}
+	for {
+		_, res := post(t, h, query, variables)
+		require.Empty(t, res.Errors)
+		require.NoError(t, json.Unmarshal(res.Data, &page))
+		for _, node := range page.Transactions.Nodes {
+			ids = append(ids, node.ID)
+		}
+		if !page.Transactions.PageInfo.HasNextPage {
+			break
+		}
+		variables["after"] = page.Transactions.PageInfo.EndCursor
+	}
+	assert.Equal(t, []int{3, 2, 1}, ids)
+
+	_, res := post(t, h, `{ subscriptions(first: 1) { totalCount nodes { address } pageInfo { endCursor } } }`, nil)
+	require.Empty(t, res.Errors)
+	var subs struct {
+		Subscriptions struct {
+			TotalCount int `json:"totalCount"`
+			Nodes      []struct {
+				Address string `json:"address"`
+			} `json:"nodes"`
+			PageInfo struct {
+				EndCursor string `json:"endCursor"`
+			} `json:"pageInfo"`
+		} `json:"subscriptions"`
+	}
+	require.NoError(t, json.Unmarshal(res.Data, &subs))
+	assert.Equal(t, 2, subs.Subscriptions.TotalCount)
+	assert.Equal(t, address1, subs.Subscriptions.Nodes[0].Address)
+
+	_, res = post(t, h, `query($after: String) { subscriptions(after: $after) { nodes { address } } }`,
+		map[string]any{"after": subs.Subscriptions.PageInfo.EndCursor})
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"subscriptions": {"nodes": [{"address": "`+address2+`"}]}}`, string(res.Data))
+}
+
+func Test_Errors(t *testing.T) {
+	h := newTestHandler(t)
+	h.SetLimits(Limits{MaxComplexity: 500, MaxDepth: 4})
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:      "syntax error",
+			query:     `{ block { number }`,
+			wantCode:  http.StatusBadRequest,
+			wantError: "Syntax Error",
+		},
+		{
+			name:      "unknown field",
+			query:     `{ block { hash } }`,
+			wantCode:  http.StatusBadRequest,
+			wantError: `Cannot query field "hash" on type "Block".`,
+		},
+		{
+			name:      "too complex",
+			query:     `{ subscriptions(first: 100) { nodes { transactions(first: 100) { totalCount } } } }`,
+			wantCode:  http.StatusBadRequest,
+			wantError: "query complexity 10201 exceeds the limit of 500",
+		},
+		{
+			name:      "too complex with variables",
+			query:     `query($n: Int) { transactions(address: "` + address1 + `", first: $n) { nodes { hash from to value } } }`,
+			variables: map[string]any{"n": 200},
+			wantCode:  http.StatusBadRequest,
+			wantError: "query complexity 1001 exceeds the limit of 500",
+		},
+		{
+			name:      "too deep",
+			query:     `{ subscription(address: "x") { transactions { nodes { block { number } } } } }`,
+			wantCode:  http.StatusBadRequest,
+			wantError: "query depth 5 exceeds the limit of 4",
+		},
+		{
+			name:      "page too large",
+			query:     `{ subscriptions(first: 101) { totalCount } }`,
+			wantCode:  http.StatusOK,
+			wantError: "first must be between 0 and 100",
+		},
+		{
+			name:      "not subscribed",
+			query:     `{ transactions(address: "0x9999") { totalCount } }`,
+			wantCode:  http.StatusOK,
+			wantError: domain.ErrNotSubscribed.Error(),
+		},
+		{
+			name:      "invalid cursor",
+			query:     `{ transactions(address: "` + address1 + `", after: "abc") { totalCount } }`,
+			wantCode:  http.StatusOK,
+			wantError: "invalid cursor",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, res := post(t, h, tt.query, tt.variables)
+			assert.Equal(t, tt.wantCode, code)
+			require.NotEmpty(t, res.Errors)
+			assert.Contains(t, res.Errors[0].Message, tt.wantError)
+		})
+	}
+}
+
+func Test_GetRequest(t *testing.T) {
+	h := newTestHandler(t)
+
+	query := url.Values{
+		"query":     {`query($address: String!) { subscription(address: $address) { label } }`},
+		"variables": {`{"address":"` + address1 + `"}`},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/graphql?"+query.Encode(), nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"subscription":{"label":"hot"}}}`, w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/graphql", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"data":null,"errors":[{"message":"query missing","locations":[]}]}`, w.Body.String())
+}
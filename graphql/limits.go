@@ -0,0 +1,122 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits reject expensive queries before they run.
+type Limits struct {
+	// MaxComplexity caps the estimated number of resolved fields. Fields
+	// under a paginated field count once for every requested item.
+	MaxComplexity int
+	// MaxDepth caps how deeply fields are nested.
+	MaxDepth int
+}
+
+var DefaultLimits = Limits{MaxComplexity: 1000, MaxDepth: 8}
+
+// analysis walks the selected operation, inlining fragments. Fragment cycles
+// are rejected by validation, which runs first.
+type analysis struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+// checkLimits returns the first limit the operation exceeds.
+func checkLimits(doc *ast.Document, operationName string, variables map[string]any, limits Limits) error {
+	a := &analysis{fragments: map[string]*ast.FragmentDefinition{}, variables: map[string]any{}}
+	var op *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch d := definition.(type) {
+		case *ast.FragmentDefinition:
+			a.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				op = d
+			}
+		}
+	}
+	if op == nil {
+		return fmt.Errorf("unknown operation %q", operationName)
+	}
+	for _, definition := range op.VariableDefinitions {
+		if value, ok := definition.DefaultValue.(*ast.IntValue); ok {
+			if size, err := strconv.Atoi(value.Value); err == nil {
+				a.variables[definition.Variable.Name.Value] = float64(size)
+			}
+		}
+	}
+	for name, value := range variables {
+		a.variables[name] = value
+	}
+
+	complexity, depth := a.selectionSet(op.SelectionSet)
+	if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", depth, limits.MaxDepth)
+	}
+	if limits.MaxComplexity > 0 && complexity > limits.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, limits.MaxComplexity)
+	}
+	return nil
+}
+
+// selectionSet returns the complexity and depth of the fields in the set.
+func (a *analysis) selectionSet(set *ast.SelectionSet) (int, int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	complexity, depth := 0, 0
+	for _, selection := range set.Selections {
+		var c, d int
+		switch s := selection.(type) {
+		case *ast.Field:
+			c, d = a.field(s)
+		case *ast.InlineFragment:
+			c, d = a.selectionSet(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment, exists := a.fragments[s.Name.Value]; exists {
+				c, d = a.selectionSet(fragment.SelectionSet)
+			}
+		}
+		complexity += c
+		depth = max(depth, d)
+	}
+	return complexity, depth
+}
+
+func (a *analysis) field(f *ast.Field) (int, int) {
+	complexity, depth := a.selectionSet(f.SelectionSet)
+	if size, paginated := a.pageSize(f); paginated {
+		complexity *= size
+	}
+	return 1 + complexity, 1 + depth
+}
+
+// pageSize returns the first argument of paginated fields, which is
+// assumed to be the default when it is not set.
+func (a *analysis) pageSize(f *ast.Field) (int, bool) {
+	if f.Name.Value != "subscriptions" && f.Name.Value != "transactions" {
+		return 0, false
+	}
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if size, err := strconv.Atoi(value.Value); err == nil {
+				return max(size, 0), true
+			}
+		case *ast.Variable:
+			// JSON numbers decode to float64
+			if size, ok := a.variables[value.Name.Value].(float64); ok {
+				return max(int(size), 0), true
+			}
+		}
+	}
+	return defaultPageSize, true
+}
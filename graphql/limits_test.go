@@ -0,0 +1,71 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CheckLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]any
+		wantErr       string
+	}{
+		{
+			name:  "default page size",
+			query: `{ transactions(address: "a") { nodes { hash } } }`,
+			// 1 + 20 * (1 + 1)
+			wantErr: "query complexity 41 exceeds the limit of 10",
+		},
+		{
+			name:    "fragments",
+			query:   `{ transactions(address: "a", first: 3) { ...page } } fragment page on TransactionConnection { nodes { ... on Transaction { hash to value } } }`,
+			wantErr: "query complexity 13 exceeds the limit of 10",
+		},
+		{
+			name:    "variable default",
+			query:   `query($n: Int = 50) { transactions(address: "a", first: $n) { totalCount } }`,
+			wantErr: "query complexity 51 exceeds the limit of 10",
+		},
+		{
+			name:      "variable overrides default",
+			query:     `query($n: Int = 50) { transactions(address: "a", first: $n) { totalCount } }`,
+			variables: map[string]any{"n": float64(2)},
+		},
+		{
+			name:          "selected operation",
+			query:         `query small { block { number } } query large { subscriptions(first: 100) { totalCount } }`,
+			operationName: "small",
+		},
+		{
+			name:          "unknown operation",
+			query:         `query small { block { number } }`,
+			operationName: "large",
+			wantErr:       `unknown operation "large"`,
+		},
+		{
+			name:    "depth",
+			query:   `{ subscription(address: "a") { transactions(first: 1) { nodes { block { number } } } } }`,
+			wantErr: "query depth 5 exceeds the limit of 4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			require.NoError(t, err)
+
+			err = checkLimits(doc, tt.operationName, tt.variables, Limits{MaxComplexity: 10, MaxDepth: 4})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	transactionCursor  = "tx:"
+	subscriptionCursor = "sub:"
+)
+
+// Service is the part of the domain service the schema reads from.
+type Service interface {
+	GetCurrentBlock() int
+	GetSubscription(ctx context.Context, address string) (*domain.Subscription, error)
+	ListSubscriptions(ctx context.Context, filter domain.SubscriptionFilter) []*domain.Subscription
+	PageTransactions(ctx context.Context, address string, before, limit int) ([]*domain.IndexedTransaction, int, error)
+}
+
+// connection is a page of nodes in the shape of the *Connection types.
+type connection struct {
+	Nodes      any      `json:"nodes"`
+	TotalCount int      `json:"totalCount"`
+	PageInfo   pageInfo `json:"pageInfo"`
+}
+
+type pageInfo struct {
+	EndCursor   *string `json:"endCursor"`
+	HasNextPage bool    `json:"hasNextPage"`
+}
+
+type block struct {
+	Number int `json:"number"`
+}
+
+type resolver struct {
+	service Service
+}
+
+func newSchema(service Service) (graphql.Schema, error) {
+	r := &resolver{service: service}
+
+	pageArgs := graphql.FieldConfigArgument{
+		"first": {Type: graphql.Int, DefaultValue: defaultPageSize, Description: fmt.Sprintf("Page size, at most %d.", maxPageSize)},
+		"after": {Type: graphql.String, Description: "endCursor of the previous page."},
+	}
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"endCursor":   {Type: graphql.String},
+			"hasNextPage": {Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+	connectionOf := func(name string, node graphql.Output) *graphql.Object {
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name: name + "Connection",
+			Fields: graphql.Fields{
+				"nodes":      {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(node)))},
+				"totalCount": {Type: graphql.NewNonNull(graphql.Int)},
+				"pageInfo":   {Type: graphql.NewNonNull(pageInfoType)},
+			},
+		})
+	}
+
+	blockType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Block",
+		Fields: graphql.Fields{
+			"number": {Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	receiptType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Receipt",
+		Description: "Outcome of a transaction, known once its receipt is fetched.",
+		Fields: graphql.Fields{
+			"status": {Type: graphql.NewNonNull(graphql.String), Description: "0x1 for success and 0x0 for failure.", Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source, nil
+			}},
+			"failed": {Type: graphql.NewNonNull(graphql.Boolean), Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(string) == domain.TxStatusFailed, nil
+			}},
+		},
+	})
+	tokenTransferType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "TokenTransfer",
+		Description: "ERC-20 transfer decoded from a transfer or transferFrom call.",
+		Fields: graphql.Fields{
+			"token": {Type: graphql.NewNonNull(graphql.String), Description: "Address of the token contract."},
+			"from":  {Type: graphql.NewNonNull(graphql.String)},
+			"to":    {Type: graphql.NewNonNull(graphql.String)},
+			"value": {Type: graphql.NewNonNull(graphql.String), Description: "Hex amount in the smallest token unit."},
+		},
+	})
+
+	transactionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Transaction",
+		Fields: graphql.Fields{
+			"id":          {Type: graphql.NewNonNull(graphql.Int), Description: "Position in the address history, as used by streams."},
+			"hash":        txField(func(tx *domain.Transaction) any { return tx.Hash }),
+			"blockNumber": txField(func(tx *domain.Transaction) any { return tx.BlockNumber }),
+			"from":        txField(func(tx *domain.Transaction) any { return tx.From }),
+			"to":          txField(func(tx *domain.Transaction) any { return tx.To }),
+			"value":       txField(func(tx *domain.Transaction) any { return tx.Value }),
+			"gas":         txField(func(tx *domain.Transaction) any { return tx.Gas }),
+			"gasPrice":    txField(func(tx *domain.Transaction) any { return tx.GasPrice }),
+			"input":       txField(func(tx *domain.Transaction) any { return tx.Input }),
+			"block": {Type: graphql.NewNonNull(blockType), Resolve: func(p graphql.ResolveParams) (any, error) {
+				tx := p.Source.(*domain.IndexedTransaction)
+				return block{Number: int(domain.HexToBig(tx.BlockNumber).Int64())}, nil
+			}},
+			"receipt": {Type: receiptType, Resolve: func(p graphql.ResolveParams) (any, error) {
+				if status := p.Source.(*domain.IndexedTransaction).Status; status != "" {
+					return status, nil
+				}
+				return nil, nil
+			}},
+			"tokenTransfer": {Type: tokenTransferType, Resolve: func(p graphql.ResolveParams) (any, error) {
+				if transfer := domain.DecodeTokenTransfer(p.Source.(*domain.IndexedTransaction).Transaction); transfer != nil {
+					return transfer, nil
+				}
+				return nil, nil
+			}},
+		},
+	})
+	transactionConnection := connectionOf("Transaction", transactionType)
+
+	subscriptionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"address": {Type: graphql.NewNonNull(graphql.String)},
+			"label":   subscriptionField(graphql.String, func(sub *domain.Subscription) any { return sub.Label }),
+			"tags":    subscriptionField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), func(sub *domain.Subscription) any { return nonNil(sub.Tags) }),
+			"owner":   subscriptionField(graphql.String, func(sub *domain.Subscription) any { return sub.Owner }),
+			"metadata": subscriptionField(graphql.String, func(sub *domain.Subscription) any {
+				if len(sub.Metadata) == 0 {
+					return nil
+				}
+				return string(sub.Metadata)
+			}),
+			"createdAt": {Type: graphql.NewNonNull(graphql.DateTime)},
+			"transactions": {
+				Type:        graphql.NewNonNull(transactionConnection),
+				Description: "Stored transactions, newest first.",
+				Args:        pageArgs,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return r.transactions(p, p.Source.(*domain.Subscription).Address)
+				},
+			},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"block": {
+				Type:        graphql.NewNonNull(blockType),
+				Description: "Last block parsed by the service.",
+				Resolve: func(graphql.ResolveParams) (any, error) {
+					return block{Number: r.service.GetCurrentBlock()}, nil
+				},
+			},
+			"subscription": {
+				Type: subscriptionType,
+				Args: graphql.FieldConfigArgument{
+					"address": {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: r.subscription,
+			},
+			"subscriptions": {
+				Type:        graphql.NewNonNull(connectionOf("Subscription", subscriptionType)),
+				Description: "Subscriptions ordered by address.",
+				Args: graphql.FieldConfigArgument{
+					"tag":   {Type: graphql.String},
+					"owner": {Type: graphql.String},
+					"first": pageArgs["first"],
+					"after": pageArgs["after"],
+				},
+				Resolve: r.subscriptions,
+			},
+			"transactions": {
+				Type:        graphql.NewNonNull(transactionConnection),
+				Description: "Stored transactions of a subscribed address, newest first.",
+				Args: graphql.FieldConfigArgument{
+					"address": {Type: graphql.NewNonNull(graphql.String)},
+					"first":   pageArgs["first"],
+					"after":   pageArgs["after"],
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return r.transactions(p, p.Args["address"].(string))
+				},
+			},
+		},
+	})
+
+	//nolint:wrapcheck // the schema is static, errors are programming errors
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+func (r *resolver) subscription(p graphql.ResolveParams) (any, error) {
+	sub, err := r.service.GetSubscription(p.Context, p.Args["address"].(string))
+	if errors.Is(err, domain.ErrNotSubscribed) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (r *resolver) subscriptions(p graphql.ResolveParams) (any, error) {
+	first, err := pageSize(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeCursor(p.Args, subscriptionCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, _ := p.Args["tag"].(string)
+	owner, _ := p.Args["owner"].(string)
+	subs := r.service.ListSubscriptions(p.Context, domain.SubscriptionFilter{Tag: tag, Owner: owner})
+	start := 0
+	for start < len(subs) && after != "" && subs[start].Address <= after {
+		start++
+	}
+	page := subs[start:min(start+first, len(subs))]
+
+	result := connection{Nodes: page, TotalCount: len(subs)}
+	if len(page) > 0 {
+		result.PageInfo = pageInfo{
+			EndCursor:   encodeCursor(subscriptionCursor, page[len(page)-1].Address),
+			HasNextPage: start+len(page) < len(subs),
+		}
+	}
+	return result, nil
+}
+
+func (r *resolver) transactions(p graphql.ResolveParams, address string) (any, error) {
+	first, err := pageSize(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeCursor(p.Args, transactionCursor)
+	if err != nil {
+		return nil, err
+	}
+	before := 0
+	if after != "" {
+		if before, err = strconv.Atoi(after); err != nil || before < 1 {
+			return nil, errors.New("invalid cursor")
+		}
+	}
+
+	// one extra transaction tells whether there is a next page
+	page, total, err := r.service.PageTransactions(p.Context, address, before, first+1)
+	if err != nil {
+		return nil, err
+	}
+	result := connection{TotalCount: total}
+	if len(page) > first {
+		page = page[:first]
+		result.PageInfo.HasNextPage = true
+	}
+	if len(page) > 0 {
+		result.PageInfo.EndCursor = encodeCursor(transactionCursor, strconv.Itoa(page[len(page)-1].ID))
+	}
+	result.Nodes = page
+	return result, nil
+}
+
+func pageSize(args map[string]any) (int, error) {
+	first, _ := args["first"].(int)
+	if first < 0 || first > maxPageSize {
+		return 0, fmt.Errorf("first must be between 0 and %d", maxPageSize)
+	}
+	return first, nil
+}
+
+// Cursors are opaque to clients so the pagination key can change.
+func encodeCursor(prefix, key string) *string {
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(prefix + key))
+	return &cursor
+}
+
+func decodeCursor(args map[string]any, prefix string) (string, error) {
+	cursor, _ := args["after"].(string)
+	if cursor == "" {
+		return "", nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	key, found := strings.CutPrefix(string(raw), prefix)
+	if err != nil || !found {
+		return "", errors.New("invalid cursor")
+	}
+	return key, nil
+}
+
+func txField(get func(*domain.Transaction) any) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.String,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			value := get(p.Source.(*domain.IndexedTransaction).Transaction)
+			if value == "" {
+				return nil, nil
+			}
+			return value, nil
+		},
+	}
+}
+
+func subscriptionField(t graphql.Output, get func(*domain.Subscription) any) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			value := get(p.Source.(*domain.Subscription))
+			if value == "" {
+				return nil, nil
+			}
+			return value, nil
+		},
+	}
+}
+
+func nonNil(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+// apiKeyMetadata is the gRPC counterpart of the X-API-Key header. Metadata
+// keys are always lowercase.
+const apiKeyMetadata = "x-api-key"
+
+// authenticator scopes calls to the tenant of their API key, like the HTTP
+// router does. The admin key only manages keys, which is not part of the
+// gRPC API, so it is not accepted here.
+type authenticator struct {
+	service Service
+	enabled bool
+}
+
+func (a *authenticator) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &tenantStream{ServerStream: ss, ctx: ctx})
+}
+
+func (a *authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	if !a.enabled {
+		return ctx, nil
+	}
+	tenant, err := a.service.Authenticate(apiKeyFromMetadata(ctx))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return domain.WithTenant(ctx, tenant), nil
+}
+
+func apiKeyFromMetadata(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		if key, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(key)
+		}
+	}
+	if keys := md.Get(apiKeyMetadata); len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// tenantStream replaces the context of a stream with the tenant scoped one.
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context {
+	return s.ctx
+}
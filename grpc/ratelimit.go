@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"deshev.com/eth-address-watch/ratelimit"
+)
+
+// retryAfterMetadata is the trailer with the seconds until a rate limited
+// call can be retried, like the Retry-After header of the HTTP API.
+const retryAfterMetadata = "retry-after"
+
+// rateLimiter takes calls from the same buckets as HTTP requests. It runs
+// before the authenticator, and calls are limited by their API key when it
+// authenticates and by client IP otherwise, like rejected HTTP requests.
+type rateLimiter struct {
+	limiter *ratelimit.Limiter
+	auth    *authenticator
+}
+
+func (l *rateLimiter) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := l.allow(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (l *rateLimiter) stream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := l.allow(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (l *rateLimiter) allow(ctx context.Context) error {
+	allowed, wait := l.limiter.Allow(l.client(ctx))
+	if allowed {
+		return nil
+	}
+
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(retryAfterMetadata, strconv.Itoa(retryAfter)))
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %ds", retryAfter)
+}
+
+func (l *rateLimiter) client(ctx context.Context) string {
+	if key := apiKeyFromMetadata(ctx); key != "" && l.auth.enabled {
+		if _, err := l.auth.service.Authenticate(key); err == nil {
+			return ratelimit.Key(key)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return ratelimit.IP(p.Addr.String())
+	}
+	return ratelimit.IP("")
+}
@@ -0,0 +1,102 @@
+// Package grpc serves the watch.v1 gRPC API next to the HTTP API. Both share
+// the same domain service, so subscriptions made through one are visible to
+// the other.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/domain"
+	watchv1 "deshev.com/eth-address-watch/proto/watch/v1"
+	"deshev.com/eth-address-watch/ratelimit"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+type Server struct {
+	log  *slog.Logger
+	port int
+	grpc *grpc.Server
+}
+
+// NewServer serves the API of s. limiter is the rate limiter of the HTTP API,
+// nil without rate limiting.
+func NewServer(log *slog.Logger, cfg *config.Config, s *domain.Service, limiter *ratelimit.Limiter) *Server {
+	auth := &authenticator{service: s, enabled: cfg.AdminAPIKey != ""}
+	unary := []grpc.UnaryServerInterceptor{}
+	stream := []grpc.StreamServerInterceptor{}
+	// calls are limited before authentication, so guessing keys is too
+	if limiter != nil {
+		limit := &rateLimiter{limiter: limiter, auth: auth}
+		unary = append(unary, limit.unary)
+		stream = append(stream, limit.stream)
+	}
+	unary = append(unary, auth.unary)
+	stream = append(stream, auth.stream)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+	watchv1.RegisterWatchServiceServer(server, &watchServer{log: log, service: s})
+
+	return &Server{
+		log:  log,
+		port: cfg.GRPCPort,
+		grpc: server,
+	}
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	s.log.Info("starting grpc server", "port", s.port)
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return fmt.Errorf("grpc listen error: %w", err)
+	}
+	return s.serve(ctx, lis)
+}
+
+// serve blocks until the context is done or the listener fails.
+func (s *Server) serve(ctx context.Context, lis net.Listener) error {
+	errCh := make(chan error, 1)
+	go func() {
+		err := s.grpc.Serve(lis)
+		if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		s.Stop()
+		return nil
+	case err := <-errCh:
+		return fmt.Errorf("grpc serve error: %w", err)
+	}
+}
+
+// Stop lets running calls finish and cancels the ones still open after the
+// shutdown timeout, which includes every live stream.
+func (s *Server) Stop() {
+	s.log.Info("stopping grpc server")
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(shutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+	case <-timer.C:
+		s.grpc.Stop()
+	}
+}
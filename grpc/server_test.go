@@ -0,0 +1,226 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/domain"
+	watchv1 "deshev.com/eth-address-watch/proto/watch/v1"
+	"deshev.com/eth-address-watch/ratelimit"
+)
+
+const (
+	address1 = "0x0000000000000000000000000000000000000001"
+	address2 = "0x0000000000000000000000000000000000000002"
+)
+
+type testServer struct {
+	bus     *domain.Bus
+	service *domain.Service
+	client  watchv1.WatchServiceClient
+}
+
+// newTestServer serves the gRPC API on an in-memory listener on top of a
+// service that is fed blocks directly through the bus.
+func newTestServer(t *testing.T, cfg *config.Config, limiter *ratelimit.Limiter) *testServer {
+	t.Helper()
+
+	log := slog.Default()
+	bus := domain.NewBus(log)
+	service := domain.NewService(log, bus)
+	server := NewServer(log, cfg, service, limiter)
+	lis := bufconn.Listen(1 << 20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{}, 2)
+	go func() {
+		_ = service.Start(ctx)
+		done <- struct{}{}
+	}()
+	go func() {
+		_ = server.serve(ctx, lis)
+		done <- struct{}{}
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		<-done
+		<-done
+	})
+
+	return &testServer{
+		bus:     bus,
+		service: service,
+		client:  watchv1.NewWatchServiceClient(conn),
+	}
+}
+
+func (s *testServer) publishBlock(number int, txs ...*domain.Transaction) {
+	s.bus.Publish(&domain.BlockIngested{Block: &domain.Block{NumberParsed: number, Transactions: txs}})
+}
+
+func Test_WatchService(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t, &config.Config{}, nil)
+
+	_, err := s.client.Subscribe(ctx, &watchv1.SubscribeRequest{Address: address1})
+	require.NoError(t, err)
+	_, err = s.client.Subscribe(ctx, &watchv1.SubscribeRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	s.publishBlock(1, &domain.Transaction{Hash: "0xa", BlockNumber: "0x1", From: address2, To: address1, Value: "0x10"})
+	assert.Eventually(t, func() bool {
+		resp, err := s.client.GetCurrentBlock(ctx, &watchv1.GetCurrentBlockRequest{})
+		return err == nil && resp.GetBlockNumber() == 1
+	}, time.Second, time.Millisecond)
+
+	resp, err := s.client.ListTransactions(ctx, &watchv1.ListTransactionsRequest{Address: address1})
+	require.NoError(t, err)
+	require.Len(t, resp.GetTransactions(), 1)
+	assert.Equal(t, "0xa", resp.GetTransactions()[0].GetHash())
+	assert.Equal(t, address2, resp.GetTransactions()[0].GetFrom())
+	assert.Equal(t, "0x10", resp.GetTransactions()[0].GetValue())
+
+	// the HTTP API sees the same subscriptions
+	sub, err := s.service.GetSubscription(ctx, address1)
+	require.NoError(t, err)
+	assert.Equal(t, address1, sub.Address)
+
+	_, err = s.client.Unsubscribe(ctx, &watchv1.UnsubscribeRequest{Address: address1})
+	require.NoError(t, err)
+	_, err = s.client.Unsubscribe(ctx, &watchv1.UnsubscribeRequest{Address: address1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	resp, err = s.client.ListTransactions(ctx, &watchv1.ListTransactionsRequest{Address: address1})
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetTransactions())
+}
+
+func Test_WatchTransactions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newTestServer(t, &config.Config{}, nil)
+
+	stream, err := s.client.WatchTransactions(ctx, &watchv1.WatchTransactionsRequest{Address: address1})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.client.Subscribe(ctx, &watchv1.SubscribeRequest{Address: address1})
+	require.NoError(t, err)
+	s.publishBlock(1, &domain.Transaction{Hash: "0xa", From: address1, To: address2})
+	assert.Eventually(t, func() bool {
+		resp, err := s.client.ListTransactions(ctx, &watchv1.ListTransactionsRequest{Address: address1})
+		return err == nil && len(resp.GetTransactions()) == 1
+	}, time.Second, time.Millisecond)
+
+	stream, err = s.client.WatchTransactions(ctx, &watchv1.WatchTransactionsRequest{Address: address1})
+	require.NoError(t, err)
+	match, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), match.GetId())
+	assert.Equal(t, "0xa", match.GetTransaction().GetHash())
+
+	s.publishBlock(2, &domain.Transaction{Hash: "0xb", From: address2, To: address1})
+	match, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), match.GetId())
+	assert.Equal(t, address1, match.GetAddress())
+	assert.Equal(t, "0xb", match.GetTransaction().GetHash())
+
+	// resuming skips the matches already seen
+	resumed, err := s.client.WatchTransactions(ctx, &watchv1.WatchTransactionsRequest{Address: address1, LastId: 1})
+	require.NoError(t, err)
+	match, err = resumed.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), match.GetId())
+	cancel()
+}
+
+func Test_WatchService_Auth(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t, &config.Config{AdminAPIKey: "admin"}, nil)
+	_, keyA, err := s.service.CreateAPIKey("tenant-a", "a")
+	require.NoError(t, err)
+	_, keyB, err := s.service.CreateAPIKey("tenant-b", "b")
+	require.NoError(t, err)
+
+	_, err = s.client.Subscribe(ctx, &watchv1.SubscribeRequest{Address: address1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	adminCtx := metadata.AppendToOutgoingContext(ctx, apiKeyMetadata, "admin")
+	_, err = s.client.Subscribe(adminCtx, &watchv1.SubscribeRequest{Address: address1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctxA := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+keyA)
+	ctxB := metadata.AppendToOutgoingContext(ctx, apiKeyMetadata, keyB)
+	_, err = s.client.Subscribe(ctxA, &watchv1.SubscribeRequest{Address: address1})
+	require.NoError(t, err)
+
+	// tenants only see their own subscriptions
+	_, err = s.client.Unsubscribe(ctxB, &watchv1.UnsubscribeRequest{Address: address1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	stream, err := s.client.WatchTransactions(ctxB, &watchv1.WatchTransactionsRequest{Address: address1})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.client.Unsubscribe(ctxA, &watchv1.UnsubscribeRequest{Address: address1})
+	assert.NoError(t, err)
+}
+
+func Test_WatchService_RateLimit(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t, &config.Config{AdminAPIKey: "admin"}, ratelimit.New(0.5, 1))
+	_, keyA, err := s.service.CreateAPIKey("tenant-a", "a")
+	require.NoError(t, err)
+	_, keyB, err := s.service.CreateAPIKey("tenant-b", "b")
+	require.NoError(t, err)
+
+	ctxA := metadata.AppendToOutgoingContext(ctx, apiKeyMetadata, keyA)
+	ctxB := metadata.AppendToOutgoingContext(ctx, apiKeyMetadata, keyB)
+	_, err = s.client.GetCurrentBlock(ctxA, &watchv1.GetCurrentBlockRequest{})
+	require.NoError(t, err)
+
+	var trailer metadata.MD
+	_, err = s.client.GetCurrentBlock(ctxA, &watchv1.GetCurrentBlockRequest{}, grpc.Trailer(&trailer))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"2"}, trailer.Get(retryAfterMetadata))
+
+	stream, err := s.client.WatchTransactions(ctxA, &watchv1.WatchTransactionsRequest{Address: address1})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// other keys have their own bucket
+	_, err = s.client.GetCurrentBlock(ctxB, &watchv1.GetCurrentBlockRequest{})
+	assert.NoError(t, err)
+
+	// keys that do not authenticate share the bucket of the client IP
+	bogus := func(key string) error {
+		_, err := s.client.GetCurrentBlock(metadata.AppendToOutgoingContext(ctx, apiKeyMetadata, key), &watchv1.GetCurrentBlockRequest{})
+		return err
+	}
+	assert.Equal(t, codes.Unauthenticated, status.Code(bogus("bogus-1")))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(bogus("bogus-2")))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(bogus("bogus-3")))
+}
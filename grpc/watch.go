@@ -0,0 +1,149 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"deshev.com/eth-address-watch/domain"
+	watchv1 "deshev.com/eth-address-watch/proto/watch/v1"
+)
+
+// Service is the part of the domain service exposed over gRPC.
+type Service interface {
+	GetCurrentBlock() int
+	GetTransactions(ctx context.Context, address string) []*domain.Transaction
+	Subscribe(ctx context.Context, address string) (bool, error)
+	Unsubscribe(ctx context.Context, address string) error
+	Listen(ctx context.Context, address string, lastID int) ([]*domain.TransactionMatched, *domain.Subscriber, error)
+	Authenticate(key string) (string, error)
+}
+
+type watchServer struct {
+	watchv1.UnimplementedWatchServiceServer
+
+	log     *slog.Logger
+	service Service
+}
+
+func (w *watchServer) GetCurrentBlock(context.Context, *watchv1.GetCurrentBlockRequest) (*watchv1.GetCurrentBlockResponse, error) {
+	return &watchv1.GetCurrentBlockResponse{BlockNumber: int64(w.service.GetCurrentBlock())}, nil
+}
+
+func (w *watchServer) Subscribe(ctx context.Context, req *watchv1.SubscribeRequest) (*watchv1.SubscribeResponse, error) {
+	if req.GetAddress() == "" {
+		return nil, errAddressMissing
+	}
+	if _, err := w.service.Subscribe(ctx, req.GetAddress()); err != nil {
+		return nil, w.toStatus(err)
+	}
+	return &watchv1.SubscribeResponse{}, nil
+}
+
+func (w *watchServer) Unsubscribe(ctx context.Context, req *watchv1.UnsubscribeRequest) (*watchv1.UnsubscribeResponse, error) {
+	if req.GetAddress() == "" {
+		return nil, errAddressMissing
+	}
+	if err := w.service.Unsubscribe(ctx, req.GetAddress()); err != nil {
+		return nil, w.toStatus(err)
+	}
+	return &watchv1.UnsubscribeResponse{}, nil
+}
+
+func (w *watchServer) ListTransactions(ctx context.Context, req *watchv1.ListTransactionsRequest) (*watchv1.ListTransactionsResponse, error) {
+	if req.GetAddress() == "" {
+		return nil, errAddressMissing
+	}
+	txs := w.service.GetTransactions(ctx, req.GetAddress())
+	resp := &watchv1.ListTransactionsResponse{Transactions: make([]*watchv1.Transaction, 0, len(txs))}
+	for _, tx := range txs {
+		resp.Transactions = append(resp.Transactions, toTransaction(tx))
+	}
+	return resp, nil
+}
+
+// WatchTransactions works like the SSE stream: it sends the backlog after
+// last_id, then live matches, and ends with Unavailable when the client falls
+// behind so it can resume from the last ID it received.
+func (w *watchServer) WatchTransactions(req *watchv1.WatchTransactionsRequest, stream grpc.ServerStreamingServer[watchv1.TransactionMatch]) error {
+	if req.GetAddress() == "" {
+		return errAddressMissing
+	}
+	if req.GetLastId() < 0 {
+		return status.Error(codes.InvalidArgument, "last_id must not be negative")
+	}
+
+	ctx := stream.Context()
+	backlog, listener, err := w.service.Listen(ctx, req.GetAddress(), int(req.GetLastId()))
+	if err != nil {
+		return w.toStatus(err)
+	}
+	defer listener.Close()
+
+	for _, m := range backlog {
+		if err := stream.Send(toMatch(m)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-listener.C:
+			if !ok {
+				w.log.Info("grpc stream listener dropped", "address", req.GetAddress())
+				return status.Error(codes.Unavailable, "client fell behind")
+			}
+			if m, ok := e.(*domain.TransactionMatched); ok {
+				if err := stream.Send(toMatch(m)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+var errAddressMissing = status.Error(codes.InvalidArgument, "required address field missing")
+
+// toStatus maps domain errors to gRPC codes the way the HTTP API maps them to
+// status codes.
+func (w *watchServer) toStatus(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrUnauthorized):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, domain.ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, domain.ErrNotSubscribed):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		w.log.Error("grpc call failed", "error", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func toMatch(m *domain.TransactionMatched) *watchv1.TransactionMatch {
+	return &watchv1.TransactionMatch{
+		Id:          int64(m.ID),
+		Address:     m.Address,
+		Transaction: toTransaction(m.Transaction),
+	}
+}
+
+func toTransaction(tx *domain.Transaction) *watchv1.Transaction {
+	return &watchv1.Transaction{
+		Hash:        tx.Hash,
+		BlockNumber: tx.BlockNumber,
+		From:        tx.From,
+		To:          tx.To,
+		Value:       tx.Value,
+		Gas:         tx.Gas,
+		GasPrice:    tx.GasPrice,
+		Input:       tx.Input,
+		Status:      tx.Status,
+	}
+}
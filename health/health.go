@@ -0,0 +1,99 @@
+// Package health runs the readiness checks reported by /readyz.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	checkTimeout = 2 * time.Second
+)
+
+// CheckFunc returns details on the checked component, and an error when it is
+// not ready.
+type CheckFunc func(ctx context.Context) (string, error)
+
+type Check struct {
+	Name string
+	Run  CheckFunc
+}
+
+type Result struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is ready when every check passed.
+type Report struct {
+	Status string             `json:"status"`
+	Checks map[string]*Result `json:"checks"`
+}
+
+func (r *Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: checkTimeout}
+}
+
+// Run runs the checks concurrently. A check still running after the timeout
+// fails.
+func (c *Checker) Run(ctx context.Context) *Report {
+	report := &Report{Status: StatusOK, Checks: map[string]*Result{}}
+	results := make([]*Result, len(c.checks))
+
+	wg := sync.WaitGroup{}
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for i, check := range c.checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) *Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	done := make(chan *Result, 1)
+	go func() {
+		detail, err := check.Run(ctx)
+		if err != nil {
+			done <- &Result{Status: StatusFail, Detail: err.Error()}
+			return
+		}
+		done <- &Result{Status: StatusOK, Detail: detail}
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return &Result{Status: StatusFail, Detail: "check timed out"}
+		}
+		return &Result{Status: StatusFail, Detail: ctx.Err().Error()}
+	}
+}
@@ -0,0 +1,39 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Run(t *testing.T) {
+	ok := Check{Name: "ok", Run: func(context.Context) (string, error) { return "all good", nil }}
+	failing := Check{Name: "failing", Run: func(context.Context) (string, error) { return "", errors.New("broken") }}
+	slow := Check{Name: "slow", Run: func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return "finished late", nil
+	}}
+
+	report := NewChecker(ok).Run(context.Background())
+	assert.True(t, report.Ready())
+	assert.Equal(t, &Result{Status: StatusOK, Detail: "all good"}, report.Checks["ok"])
+
+	c := NewChecker(ok, failing, slow)
+	c.timeout = 10 * time.Millisecond
+	report = c.Run(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, map[string]*Result{
+		"ok":      {Status: StatusOK, Detail: "all good"},
+		"failing": {Status: StatusFail, Detail: "broken"},
+		"slow":    {Status: StatusFail, Detail: "check timed out"},
+	}, report.Checks)
+
+	report = NewChecker().Run(context.Background())
+	assert.True(t, report.Ready())
+	assert.Empty(t, report.Checks)
+}
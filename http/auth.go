@@ -0,0 +1,136 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+	// browsers cannot set headers on EventSource and WebSocket requests
+	apiKeyQueryParam = "apiKey"
+	adminPathPrefix  = "/admin/"
+)
+
+// EnableAuth requires an API key on every request and scopes the request to
+// the tenant of the key. Requests under /admin/ and to /metrics need the
+// admin key instead.
+func (r *Router) EnableAuth(adminKey string) {
+	r.adminKey = adminKey
+	r.authEnabled = true
+}
+
+// authenticate scopes the request to the tenant of its API key, or fails
+// with domain.ErrUnauthorized.
+func (r *Router) authenticate(req *http.Request) (*http.Request, error) {
+	key := apiKeyFromRequest(req)
+	if isAdminPath(req.URL.Path) {
+		if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(r.adminKey)) != 1 {
+			return nil, domain.ErrUnauthorized
+		}
+		return req, nil
+	}
+
+	tenant, err := r.service.Authenticate(key)
+	if err != nil {
+		return nil, err
+	}
+	return req.WithContext(domain.WithTenant(req.Context(), tenant)), nil
+}
+
+func isAdminPath(path string) bool {
+	return path == metricsPath || strings.HasPrefix(path, adminPathPrefix) || strings.HasPrefix(path, "/"+apiVersion+adminPathPrefix)
+}
+
+func apiKeyFromRequest(req *http.Request) string {
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		if key, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(key)
+		}
+	}
+	if key := req.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+	return req.URL.Query().Get(apiKeyQueryParam)
+}
+
+// APIKeys lists (GET), creates (POST) or revokes (DELETE) tenant API keys.
+// The plaintext key is only part of the creation response.
+func (r *Router) APIKeys(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		r.listAPIKeys(w, req)
+	case http.MethodPost:
+		r.createAPIKey(w, req)
+	case http.MethodDelete:
+		r.revokeAPIKey(w, req)
+	default:
+		r.writeJSON(Response{Message: "method not allowed", Code: http.StatusMethodNotAllowed}, w)
+	}
+}
+
+// authDisabled replies with 404 to key management requests when keys are
+// not in use.
+func (r *Router) authDisabled(w http.ResponseWriter) bool {
+	if r.authEnabled {
+		return false
+	}
+	r.writeJSON(Response{Message: "authentication is disabled", Code: http.StatusNotFound}, w)
+	return true
+}
+
+func (r *Router) listAPIKeys(w http.ResponseWriter, _ *http.Request) {
+	if r.authDisabled(w) {
+		return
+	}
+	r.writeJSON(Response{Data: r.service.ListAPIKeys()}, w)
+}
+
+func (r *Router) revokeAPIKey(w http.ResponseWriter, req *http.Request) {
+	if r.authDisabled(w) {
+		return
+	}
+
+	id := param(req, "id")
+	if id == "" {
+		r.writeJSON(Response{Message: "required id field missing", Code: http.StatusBadRequest}, w)
+		return
+	}
+	if err := r.service.RevokeAPIKey(id); err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: true}, w)
+}
+
+func (r *Router) createAPIKey(w http.ResponseWriter, req *http.Request) {
+	if r.authDisabled(w) {
+		return
+	}
+
+	var body struct {
+		Tenant string `json:"tenant"`
+		Name   string `json:"name"`
+	}
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if err != nil || body.Tenant == "" {
+		r.writeJSON(Response{Message: "invalid API key request", Code: http.StatusBadRequest}, w)
+		return
+	}
+
+	key, plaintext, err := r.service.CreateAPIKey(body.Tenant, body.Name)
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+
+	data := struct {
+		*domain.APIKey
+		Key string `json:"key"`
+	}{key, plaintext}
+	r.writeJSON(Response{Data: data, Code: http.StatusCreated}, w)
+}
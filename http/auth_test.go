@@ -0,0 +1,89 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const testAdminKey = "admin-secret"
+
+func Test_Auth(t *testing.T) {
+	log := slog.Default()
+	service := domain.NewService(log, domain.NewBus(log))
+	router := NewRouter(log, service)
+	router.EnableAuth(testAdminKey)
+
+	do := func(method, url, body string, header http.Header) (int, Response) {
+		req, _ := http.NewRequestWithContext(context.TODO(), method, url, bytes.NewBufferString(body))
+		for name, values := range header {
+			req.Header.Set(name, values[0])
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var parsedBody Response
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&parsedBody))
+		return rr.Code, parsedBody
+	}
+	createKey := func(tenant string) string {
+		code, resp := do("POST", "/admin/keys", `{"tenant":"`+tenant+`"}`, http.Header{apiKeyHeader: {testAdminKey}})
+		assert.Equal(t, http.StatusCreated, code)
+		data, _ := resp.Data.(map[string]any)
+		key, _ := data["key"].(string)
+		return key
+	}
+
+	code, _ := do("GET", "/transactions?address=0x1111", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = do("GET", "/admin/keys", "", http.Header{"Authorization": {"Bearer wrong"}})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	keyA := createKey("team-a")
+	keyB := createKey("team-b")
+	// the admin key is not a tenant key
+	code, _ = do("GET", "/block", "", http.Header{apiKeyHeader: {testAdminKey}})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = do("POST", "/subscribe", `{"address":"0x1111"}`, http.Header{"Authorization": {"Bearer " + keyA}})
+	assert.Equal(t, http.StatusOK, code)
+
+	code, resp := do("GET", "/subscriptions?apiKey="+keyA, "", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, len(resp.Data.([]any)))
+	code, resp = do("GET", "/subscriptions", "", http.Header{apiKeyHeader: {keyB}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 0, len(resp.Data.([]any)))
+
+	code, resp = do("GET", "/admin/keys", "", http.Header{apiKeyHeader: {testAdminKey}})
+	assert.Equal(t, http.StatusOK, code)
+	keys := resp.Data.([]any)
+	assert.Equal(t, 2, len(keys))
+	assert.NotContains(t, keys[0], "key")
+
+	id := keys[0].(map[string]any)["id"].(string)
+	code, _ = do("DELETE", "/admin/keys?id="+id, "", http.Header{apiKeyHeader: {testAdminKey}})
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("GET", "/subscriptions", "", http.Header{apiKeyHeader: {keyA}})
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func Test_AdminKeysWithoutAuth(t *testing.T) {
+	log := slog.Default()
+	router := NewRouter(log, &MockService{})
+
+	req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/admin/keys", http.NoBody)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, `{"message":"authentication is disabled"}`+"\n", rr.Body.String())
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetBalance returns the ETH balance of a subscribed address, currently or
+// after the block in the block query parameter.
+func (r *Router) GetBalance(w http.ResponseWriter, req *http.Request) {
+	block, err := blockParams(req.URL.Query(), "block")
+	if err != nil {
+		r.writeJSON(Response{Message: err.Error(), Code: http.StatusBadRequest}, w)
+		return
+	}
+
+	balance, err := r.service.GetBalance(req.Context(), req.PathValue("address"), block[0])
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: balance}, w)
+}
+
+// GetBalanceHistory returns the changes of the balance of a subscribed
+// address in the blocks between the from and to query parameters.
+func (r *Router) GetBalanceHistory(w http.ResponseWriter, req *http.Request) {
+	blocks, err := blockParams(req.URL.Query(), "from", "to")
+	if err != nil {
+		r.writeJSON(Response{Message: err.Error(), Code: http.StatusBadRequest}, w)
+		return
+	}
+
+	points, err := r.service.GetBalanceHistory(req.Context(), req.PathValue("address"), blocks[0], blocks[1])
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: points}, w)
+}
+
+// GetTokenBalances returns the balances of a subscribed address in the
+// tracked ERC-20 tokens.
+func (r *Router) GetTokenBalances(w http.ResponseWriter, req *http.Request) {
+	balances, err := r.service.GetTokenBalances(req.Context(), req.PathValue("address"))
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: balances}, w)
+}
+
+// GetTokenBalanceHistory returns the changes of the balance of a subscribed
+// address in a token in the blocks between the from and to query parameters.
+func (r *Router) GetTokenBalanceHistory(w http.ResponseWriter, req *http.Request) {
+	blocks, err := blockParams(req.URL.Query(), "from", "to")
+	if err != nil {
+		r.writeJSON(Response{Message: err.Error(), Code: http.StatusBadRequest}, w)
+		return
+	}
+
+	points, err := r.service.GetTokenBalanceHistory(req.Context(), req.PathValue("address"), req.PathValue("token"), blocks[0], blocks[1])
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: points}, w)
+}
+
+// blockParams parses block number query parameters, 0 when missing.
+func blockParams(query url.Values, names ...string) ([]int, error) {
+	blocks := make([]int, len(names))
+	for i, name := range names {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		block, err := strconv.Atoi(value)
+		if err != nil || block < 0 {
+			return nil, fmt.Errorf("invalid %s block %q", name, value)
+		}
+		blocks[i] = block
+	}
+	return blocks, nil
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+func Test_GetBalance(t *testing.T) {
+	mockService := &MockService{}
+	mockService.On("GetBalance", "0x1111", 0).Return(&domain.Balance{Address: "0x1111", Block: 17, Wei: "1500000000000000000", ETH: "1.5"}, nil)
+	mockService.On("GetBalance", "0x1111", 16).Return(&domain.Balance{Address: "0x1111", Block: 16, Wei: "1000000000000000000", ETH: "1"}, nil)
+	mockService.On("GetBalance", "0x2222", 0).Return(nil, domain.ErrBalancePending)
+	router := NewRouter(slog.Default(), mockService)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/addresses/0x1111/balance", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":{"address":"0x1111","block":17,"wei":"1500000000000000000","eth":"1.5"}}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/addresses/0x1111/balance?block=16", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":{"address":"0x1111","block":16,"wei":"1000000000000000000","eth":"1"}}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/addresses/0x1111/balance?block=latest", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/addresses/0x2222/balance", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Service Unavailable","status":503,`+
+		`"detail":"balance not known yet","code":"balance_pending"}`, rr.Body.String())
+}
+
+func Test_GetBalanceHistory(t *testing.T) {
+	mockService := &MockService{}
+	mockService.On("GetBalanceHistory", "0x1111", 16, 0).Return([]*domain.BalancePoint{
+		{Block: 17, Wei: "1500000000000000000", ETH: "1.5", Change: "500000000000000000", Source: domain.BalanceSourceTransactions},
+	}, nil)
+	mockService.On("GetBalanceHistory", "0x2222", 0, 0).Return(nil, domain.ErrBalanceUnavailable)
+	router := NewRouter(slog.Default(), mockService)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/addresses/0x1111/balance/history?from=16", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":[{"block":17,"wei":"1500000000000000000","eth":"1.5","change":"500000000000000000","source":"transactions"}]}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/addresses/0x1111/balance/history?to=-1", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/addresses/0x2222/balance/history", nil))
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+}
+
+func Test_GetTokenBalances(t *testing.T) {
+	mockService := &MockService{}
+	mockService.On("GetTokenBalances", "0x1111").Return([]*domain.TokenBalance{
+		{Address: "0x1111", Token: "0xdac1", Symbol: "USDT", Decimals: 6, Block: 17, Amount: "1500000", Value: "1.5"},
+	}, nil)
+	mockService.On("GetTokenBalanceHistory", "0x1111", "0xdac1", 0, 20).Return([]*domain.TokenBalancePoint{
+		{Block: 17, Amount: "1500000", Value: "1.5", Change: "1500000", Source: domain.BalanceSourceSeed},
+	}, nil)
+	mockService.On("GetTokenBalanceHistory", "0x1111", "0x2222", 0, 0).Return(nil, domain.ErrUnknownToken)
+	router := NewRouter(slog.Default(), mockService)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/addresses/0x1111/tokens", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":[{"address":"0x1111","token":"0xdac1","symbol":"USDT","decimals":6,"block":17,"amount":"1500000","value":"1.5"}]}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/addresses/0x1111/tokens/0xdac1/history?to=20", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":[{"block":17,"amount":"1500000","value":"1.5","change":"1500000","source":"seed"}]}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/addresses/0x1111/tokens/0x2222/history", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,`+
+		`"detail":"token not tracked","code":"unknown_token"}`, rr.Body.String())
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const (
+	maxImportBytes = 10 << 20
+	maxImportRows  = 10_000
+	csvTagSep      = ";"
+)
+
+var csvColumns = []string{"address", "label", "tags", "owner", "metadata", "createdAt"}
+
+// ImportSubscriptions subscribes a batch of addresses from a JSON array or a
+// CSV upload with a header row. The batch is applied atomically and the
+// response has a validation result for every row.
+func (r *Router) ImportSubscriptions(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.writeJSON(Response{Message: "method not allowed", Code: http.StatusMethodNotAllowed}, w)
+		return
+	}
+
+	body := http.MaxBytesReader(w, req.Body, maxImportBytes)
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	var rows []*domain.SubscriptionImport
+	var err error
+	if mediaType == "text/csv" {
+		rows, err = readCSVImport(body)
+	} else {
+		err = json.NewDecoder(body).Decode(&rows)
+	}
+	if err != nil || len(rows) == 0 {
+		r.writeJSON(Response{Message: "invalid import request", Code: http.StatusBadRequest}, w)
+		return
+	}
+	if len(rows) > maxImportRows {
+		msg := fmt.Sprintf("import is limited to %d rows", maxImportRows)
+		r.writeJSON(Response{Message: msg, Code: http.StatusRequestEntityTooLarge}, w)
+		return
+	}
+
+	results, err := r.service.ImportSubscriptions(req.Context(), rows)
+	if errors.Is(err, domain.ErrInvalidImport) {
+		r.writeJSON(Response{Message: err.Error(), Code: http.StatusBadRequest, Data: results, ErrorCode: "invalid_import"}, w)
+		return
+	}
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+
+	r.writeJSON(Response{Data: results}, w)
+}
+
+// ExportSubscriptions dumps every subscription with its metadata as JSON or,
+// with format=csv, in the same CSV layout the import accepts.
+func (r *Router) ExportSubscriptions(w http.ResponseWriter, req *http.Request) {
+	subs := r.service.ListSubscriptions(req.Context(), domain.SubscriptionFilter{})
+
+	switch req.URL.Query().Get("format") {
+	case "", "json":
+		r.writeJSON(Response{Data: subs}, w)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="subscriptions.csv"`)
+		if err := writeCSVExport(w, subs); err != nil {
+			r.log.Error("failed to write CSV export", "error", err)
+		}
+	default:
+		r.writeJSON(Response{Message: "unsupported export format", Code: http.StatusBadRequest}, w)
+	}
+}
+
+func readCSVImport(body io.Reader) ([]*domain.SubscriptionImport, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("csv header read error: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["address"]; !ok {
+		return nil, errors.New("csv header has no address column")
+	}
+
+	rows := []*domain.SubscriptionImport{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("csv read error: %w", err)
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := &domain.SubscriptionImport{Address: field("address")}
+		row.Label = field("label")
+		row.Owner = field("owner")
+		if tags := field("tags"); tags != "" {
+			row.Tags = strings.Split(tags, csvTagSep)
+		}
+		if metadata := field("metadata"); metadata != "" {
+			row.Metadata = json.RawMessage(metadata)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func writeCSVExport(w io.Writer, subs []*domain.Subscription) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return fmt.Errorf("csv write error: %w", err)
+	}
+
+	for _, sub := range subs {
+		record := []string{
+			sub.Address,
+			sub.Label,
+			strings.Join(sub.Tags, csvTagSep),
+			sub.Owner,
+			string(sub.Metadata),
+			sub.CreatedAt.Format(time.RFC3339),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("csv write error: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("csv write error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+func Test_ImportSubscriptions(t *testing.T) {
+	wantRows := []*domain.SubscriptionImport{
+		{
+			Address: "0xdac17f958d2ee523a2206206994597c13d831ec7",
+			SubscriptionMetadata: domain.SubscriptionMetadata{
+				Label:    "USDT",
+				Tags:     []string{"token", "stable"},
+				Metadata: json.RawMessage(`{"decimals":6}`),
+			},
+		},
+		{
+			Address: "0x0000000000000000000000000000000000000001",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		requestBody string
+		importErr   error
+		wantStatus  int
+		wantError   string
+	}{
+		{
+			name:        "json array",
+			contentType: "application/json",
+			requestBody: `[{"address":"0xdac17f958d2ee523a2206206994597c13d831ec7","label":"USDT",` +
+				`"tags":["token","stable"],"metadata":{"decimals":6}},` +
+				`{"address":"0x0000000000000000000000000000000000000001"}]`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "csv upload",
+			contentType: "text/csv; charset=utf-8",
+			requestBody: "address,label,tags,metadata\n" +
+				"0xdac17f958d2ee523a2206206994597c13d831ec7,USDT,token;stable,\"{\"\"decimals\"\":6}\"\n" +
+				"0x0000000000000000000000000000000000000001\n",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "rejected batch",
+			contentType: "application/json",
+			requestBody: `[{"address":"0xdac17f958d2ee523a2206206994597c13d831ec7","label":"USDT",` +
+				`"tags":["token","stable"],"metadata":{"decimals":6}},` +
+				`{"address":"0x0000000000000000000000000000000000000001"}]`,
+			importErr:  domain.ErrInvalidImport,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid import",
+		},
+		{
+			name:        "csv without address column",
+			contentType: "text/csv",
+			requestBody: "label\nUSDT\n",
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "invalid import request",
+		},
+		{
+			name:        "empty batch",
+			contentType: "application/json",
+			requestBody: `[]`,
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "invalid import request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := []*domain.ImportResult{
+				{Row: 1, Address: wantRows[0].Address, Created: true},
+				{Row: 2, Address: wantRows[1].Address, Created: true},
+			}
+			mockService := &MockService{}
+			mockService.On("ImportSubscriptions", wantRows).Return(results, tt.importErr)
+
+			router := NewRouter(slog.Default(), mockService)
+
+			body := bytes.NewBufferString(tt.requestBody)
+			req, _ := http.NewRequestWithContext(context.TODO(), "POST", "/subscriptions/import", body)
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			var parsedBody Response
+			err := json.NewDecoder(rr.Body).Decode(&parsedBody)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantError, parsedBody.Message)
+			if tt.wantError == "" || tt.importErr != nil {
+				rows, ok := parsedBody.Data.([]any)
+				assert.True(t, ok)
+				assert.Equal(t, 2, len(rows))
+			}
+		})
+	}
+}
+
+func Test_ImportSubscriptions_NullRow(t *testing.T) {
+	log := slog.Default()
+	router := NewRouter(log, domain.NewService(log, domain.NewBus(log)))
+
+	req, _ := http.NewRequestWithContext(context.TODO(), "POST", "/v1/subscriptions/import", bytes.NewBufferString(`[null]`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"error":"row is empty"`)
+}
+
+func Test_ExportSubscriptions(t *testing.T) {
+	mockService := &MockService{}
+	mockService.On("ListSubscriptions", mock.Anything).Return([]*domain.Subscription{
+		{
+			Address: "0xdac17f958d2ee523a2206206994597c13d831ec7",
+			SubscriptionMetadata: domain.SubscriptionMetadata{
+				Label:    "USDT",
+				Tags:     []string{"token", "stable"},
+				Owner:    "team-a",
+				Metadata: json.RawMessage(`{"decimals":6}`),
+			},
+			CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	})
+
+	router := NewRouter(slog.Default(), mockService)
+
+	req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/subscriptions/export?format=csv", http.NoBody)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "address,label,tags,owner,metadata,createdAt\n"+
+		"0xdac17f958d2ee523a2206206994597c13d831ec7,USDT,token;stable,team-a,\"{\"\"decimals\"\":6}\",2024-01-02T03:04:05Z\n",
+		rr.Body.String())
+
+	req, _ = http.NewRequestWithContext(context.TODO(), "GET", "/subscriptions/export", http.NoBody)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":[{"address":"0xdac17f958d2ee523a2206206994597c13d831ec7","label":"USDT",`+
+		`"tags":["token","stable"],"owner":"team-a","metadata":{"decimals":6},"createdAt":"2024-01-02T03:04:05Z"}]}`,
+		rr.Body.String())
+}
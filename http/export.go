@@ -0,0 +1,87 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"deshev.com/eth-address-watch/export"
+)
+
+// ExportTransactions streams the stored transactions of an address as CSV,
+// NDJSON or Parquet, see the export package for the columns.
+func (r *Router) ExportTransactions(w http.ResponseWriter, req *http.Request) {
+	address := req.PathValue("address")
+	if _, err := r.service.GetSubscription(req.Context(), address); err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.export(w, req, address, []string{address})
+}
+
+// ExportGroupTransactions streams the transactions of every group member in
+// turn, with a row per member, so transfers between members appear twice.
+func (r *Router) ExportGroupTransactions(w http.ResponseWriter, req *http.Request) {
+	name := req.PathValue("name")
+	group, err := r.service.GetGroup(req.Context(), name)
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.export(w, req, name, group.Members)
+}
+
+func (r *Router) export(w http.ResponseWriter, req *http.Request, name string, addresses []string) {
+	query := req.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	filter, err := exportFilter(query.Get("from"), query.Get("to"))
+	if err != nil {
+		r.writeJSON(Response{Message: err.Error(), Code: http.StatusBadRequest}, w)
+		return
+	}
+	out, err := export.NewWriter(w, format)
+	if err != nil {
+		r.writeJSON(Response{Message: err.Error(), Code: http.StatusBadRequest}, w)
+		return
+	}
+
+	// large exports outlive the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		r.log.Error("failed to clear export write deadline", "error", err)
+	}
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+
+	// the status is sent with the first rows, so later errors can only be
+	// logged and end the response early
+	written, err := export.Transactions(req.Context(), r.service, addresses, filter, out)
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		r.log.Error("transaction export failed", "name", name, "rows", written, "error", err)
+		return
+	}
+	r.log.Debug("exported transactions", "name", name, "format", format, "rows", written)
+}
+
+// exportFilter parses the from and to query parameters.
+func exportFilter(from, to string) (export.Filter, error) {
+	var filter export.Filter
+	var err error
+	if filter.From, err = export.ParseTime(from); err != nil {
+		return filter, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = export.ParseTime(to); err != nil {
+		return filter, fmt.Errorf("invalid to: %w", err)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, errors.New("from must be before to")
+	}
+	return filter, nil
+}
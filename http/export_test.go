@@ -0,0 +1,102 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+func Test_ExportTransactions(t *testing.T) {
+	txs := []*domain.IndexedTransaction{
+		{ID: 1, Transaction: &domain.Transaction{Hash: "0xa", BlockNumber: "0x10", From: "0x2222", To: "0x1111", Value: "0xde0b6b3a7640000", BlockTimestamp: "0x65920080"}},
+		{ID: 2, Transaction: &domain.Transaction{Hash: "0xb", BlockNumber: "0x11", From: "0x1111", To: "0x2222", Value: "0x1", BlockTimestamp: "0x65935200"}},
+	}
+	tests := []struct {
+		name            string
+		path            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "csv",
+			path:            "/v1/addresses/0x1111/transactions/export",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody: "address,id,hash,block_number,block_time,from,to,direction,value_wei,value_eth,gas,gas_price_wei,status\n" +
+				"0x1111,1,0xa,16,2024-01-01T00:00:00Z,0x2222,0x1111,in,1000000000000000000,1,0,0,\n" +
+				"0x1111,2,0xb,17,2024-01-02T00:00:00Z,0x1111,0x2222,out,1,0.000000000000000001,0,0,\n",
+		},
+		{
+			name:            "ndjson from a day",
+			path:            "/v1/addresses/0x1111/transactions/export?format=ndjson&from=2024-01-02",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-ndjson",
+			wantBody: `{"address":"0x1111","id":2,"hash":"0xb","block_number":17,"block_time":"2024-01-02T00:00:00Z",` +
+				`"from":"0x1111","to":"0x2222","direction":"out","value_wei":"1","value_eth":"0.000000000000000001",` +
+				`"gas":0,"gas_price_wei":"0","status":""}` + "\n",
+		},
+		{
+			name:            "group",
+			path:            "/v1/groups/treasury/transactions/export?to=2024-01-01T12:00:00Z",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody: "address,id,hash,block_number,block_time,from,to,direction,value_wei,value_eth,gas,gas_price_wei,status\n" +
+				"0x1111,1,0xa,16,2024-01-01T00:00:00Z,0x2222,0x1111,in,1000000000000000000,1,0,0,\n",
+		},
+		{
+			name:       "unknown format",
+			path:       "/v1/addresses/0x1111/transactions/export?format=xlsx",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid range",
+			path:       "/v1/addresses/0x1111/transactions/export?from=2024-01-02&to=2024-01-01",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid time",
+			path:       "/v1/addresses/0x1111/transactions/export?from=yesterday",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "not subscribed",
+			path:       "/v1/addresses/0x3333/transactions/export",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unknown group",
+			path:       "/v1/groups/ops/transactions/export",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockService.On("GetSubscription", "0x1111").Return(&domain.Subscription{Address: "0x1111"}, nil)
+			mockService.On("GetSubscription", "0x3333").Return(nil, domain.ErrNotSubscribed)
+			mockService.On("GetGroup", "treasury").Return(&domain.Group{Name: "treasury", Members: []string{"0x1111"}}, nil)
+			mockService.On("GetGroup", "ops").Return((*domain.Group)(nil), domain.ErrGroupNotFound)
+			mockService.On("ScanTransactions", "0x1111", 0, mock.Anything).Return(txs, nil)
+			router := NewRouter(slog.Default(), mockService)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tt.wantContentType, rr.Header().Get("Content-Type"))
+			assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+			assert.Equal(t, tt.wantBody, rr.Body.String())
+		})
+	}
+}
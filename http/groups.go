@@ -0,0 +1,118 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Groups lists groups or fetches one by name (GET), creates a group and
+// subscribes its members (POST) or deletes a group (DELETE).
+func (r *Router) Groups(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		if param(req, "name") == "" {
+			r.listGroups(w, req)
+			return
+		}
+		r.getGroup(w, req)
+	case http.MethodPost:
+		r.createGroup(w, req)
+	case http.MethodDelete:
+		r.deleteGroup(w, req)
+	default:
+		r.writeJSON(Response{Message: "method not allowed", Code: http.StatusMethodNotAllowed}, w)
+	}
+}
+
+func (r *Router) listGroups(w http.ResponseWriter, req *http.Request) {
+	r.writeJSON(Response{Data: r.service.ListGroups(req.Context())}, w)
+}
+
+func (r *Router) getGroup(w http.ResponseWriter, req *http.Request) {
+	group, err := r.service.GetGroup(req.Context(), param(req, "name"))
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: group}, w)
+}
+
+func (r *Router) deleteGroup(w http.ResponseWriter, req *http.Request) {
+	name := param(req, "name")
+	if name == "" {
+		r.writeJSON(Response{Message: "required name field missing", Code: http.StatusBadRequest}, w)
+		return
+	}
+	if err := r.service.DeleteGroup(req.Context(), name); err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: true}, w)
+}
+
+func (r *Router) createGroup(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Name    string   `json:"name"`
+		Members []string `json:"members"`
+	}
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if err != nil || body.Name == "" {
+		r.writeJSON(Response{Message: "invalid group request", Code: http.StatusBadRequest}, w)
+		return
+	}
+
+	group, err := r.service.CreateGroup(req.Context(), body.Name, body.Members)
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: group, Code: http.StatusCreated}, w)
+}
+
+// GroupMembers adds and removes members of a group in one request.
+func (r *Router) GroupMembers(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.writeJSON(Response{Message: "method not allowed", Code: http.StatusMethodNotAllowed}, w)
+		return
+	}
+	r.updateGroupMembers(w, req)
+}
+
+func (r *Router) updateGroupMembers(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Name   string   `json:"name"`
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if name := req.PathValue("name"); name != "" {
+		body.Name = name
+	}
+	if err != nil || body.Name == "" {
+		r.writeJSON(Response{Message: "invalid group members request", Code: http.StatusBadRequest}, w)
+		return
+	}
+
+	group, err := r.service.UpdateGroupMembers(req.Context(), body.Name, body.Add, body.Remove)
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: group}, w)
+}
+
+// GroupTransactions returns the merged transactions of all group members.
+func (r *Router) GroupTransactions(w http.ResponseWriter, req *http.Request) {
+	name := param(req, "name")
+	if name == "" {
+		r.writeJSON(Response{Message: "required name field missing", Code: http.StatusBadRequest}, w)
+		return
+	}
+
+	txs, err := r.service.GetGroupTransactions(req.Context(), name)
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: txs}, w)
+}
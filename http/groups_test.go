@@ -0,0 +1,121 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+func Test_Groups(t *testing.T) {
+	group := &domain.Group{
+		Name:      "exchange-hot-wallets",
+		Members:   []string{"address-1", "address-2"},
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	groupJSON := `{"name":"exchange-hot-wallets","members":["address-1","address-2"],"createdAt":"2024-01-02T03:04:05Z"}`
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "list groups",
+			method:     "GET",
+			url:        "/groups",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":[` + groupJSON + `]}`,
+		},
+		{
+			name:       "get group",
+			method:     "GET",
+			url:        "/groups?name=exchange-hot-wallets",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":` + groupJSON + `}`,
+		},
+		{
+			name:       "unknown group",
+			method:     "GET",
+			url:        "/groups?name=missing",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"message":"group not found"}`,
+		},
+		{
+			name:       "create group",
+			method:     "POST",
+			url:        "/groups",
+			body:       `{"name":"exchange-hot-wallets","members":["address-1","address-2"]}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"data":` + groupJSON + `}`,
+		},
+		{
+			name:       "create existing group",
+			method:     "POST",
+			url:        "/groups",
+			body:       `{"name":"taken","members":["address-1","address-2"]}`,
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"message":"group already exists"}`,
+		},
+		{
+			name:       "delete group",
+			method:     "DELETE",
+			url:        "/groups?name=exchange-hot-wallets",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":true}`,
+		},
+		{
+			name:       "update members",
+			method:     "POST",
+			url:        "/groups/members",
+			body:       `{"name":"exchange-hot-wallets","add":["address-2"],"remove":["address-3"]}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":` + groupJSON + `}`,
+		},
+		{
+			name:       "group transactions",
+			method:     "GET",
+			url:        "/groups/transactions?name=exchange-hot-wallets",
+			wantStatus: http.StatusOK,
+			wantBody: `{"data":[{"blockNumber":"0x1","from":"address-1","to":"address-2","internal":true},` +
+				`{"blockNumber":"0x2","from":"address-3","to":"address-1","internal":false}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockService.On("ListGroups").Return([]*domain.Group{group})
+			mockService.On("GetGroup", "exchange-hot-wallets").Return(group, nil)
+			mockService.On("GetGroup", "missing").Return((*domain.Group)(nil), domain.ErrGroupNotFound)
+			mockService.On("CreateGroup", "exchange-hot-wallets", group.Members).Return(group, nil)
+			mockService.On("CreateGroup", "taken", group.Members).Return((*domain.Group)(nil), domain.ErrGroupExists)
+			mockService.On("DeleteGroup", "exchange-hot-wallets").Return(nil)
+			mockService.On("UpdateGroupMembers", "exchange-hot-wallets", []string{"address-2"}, []string{"address-3"}).
+				Return(group, nil)
+			mockService.On("GetGroupTransactions", "exchange-hot-wallets").Return([]*domain.GroupTransaction{
+				{Transaction: &domain.Transaction{BlockNumber: "0x1", From: "address-1", To: "address-2"}, Internal: true},
+				{Transaction: &domain.Transaction{BlockNumber: "0x2", From: "address-3", To: "address-1"}},
+			}, nil)
+
+			router := NewRouter(slog.Default(), mockService)
+
+			req, _ := http.NewRequestWithContext(context.TODO(), tt.method, tt.url, bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.JSONEq(t, tt.wantBody, rr.Body.String())
+		})
+	}
+}
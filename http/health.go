@@ -0,0 +1,51 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"deshev.com/eth-address-watch/health"
+)
+
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+)
+
+// SetReadiness sets the checks behind /readyz, which reports ready without
+// any.
+func (r *Router) SetReadiness(checker *health.Checker) {
+	r.readiness = checker
+}
+
+// isProbePath matches the routes of the orchestrator probes, which come
+// without an API key and must not be rate limited.
+func isProbePath(path string) bool {
+	return path == healthzPath || path == readyzPath
+}
+
+// GetHealth reports the process is alive; it does not depend on the node or
+// the store.
+func (r *Router) GetHealth(w http.ResponseWriter, _ *http.Request) {
+	r.writeReport(w, &health.Report{Status: health.StatusOK, Checks: map[string]*health.Result{}})
+}
+
+// GetReadiness runs the readiness checks and replies with 503 when any fails.
+func (r *Router) GetReadiness(w http.ResponseWriter, req *http.Request) {
+	checker := r.readiness
+	if checker == nil {
+		checker = health.NewChecker()
+	}
+	r.writeReport(w, checker.Run(req.Context()))
+}
+
+func (r *Router) writeReport(w http.ResponseWriter, report *health.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !report.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		r.log.Error("failed to write health report", "error", err)
+	}
+}
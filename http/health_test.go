@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"deshev.com/eth-address-watch/health"
+	"deshev.com/eth-address-watch/ratelimit"
+)
+
+func Test_Health(t *testing.T) {
+	router := NewRouter(slog.Default(), &MockService{})
+	router.EnableAuth(testAdminKey)
+	router.SetRateLimiter(ratelimit.New(1, 1))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	// probes skip authentication and rate limiting
+	for range 3 {
+		rr := get(healthzPath)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"status":"ok","checks":{}}`, rr.Body.String())
+	}
+
+	rr := get(readyzPath)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"ok","checks":{}}`, rr.Body.String())
+
+	ready := true
+	router.SetReadiness(health.NewChecker(
+		health.Check{Name: "store", Run: func(context.Context) (string, error) { return "in memory", nil }},
+		health.Check{Name: "ingestion", Run: func(context.Context) (string, error) {
+			if ready {
+				return "last block 17", nil
+			}
+			return "", errors.New("last block 17 has not advanced for 3 ticks")
+		}},
+	))
+	rr = get(readyzPath)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"status":"ok","checks":{
+		"store":{"status":"ok","detail":"in memory"},
+		"ingestion":{"status":"ok","detail":"last block 17"}
+	}}`, rr.Body.String())
+
+	ready = false
+	rr = get(readyzPath)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.JSONEq(t, `{"status":"fail","checks":{
+		"store":{"status":"ok","detail":"in memory"},
+		"ingestion":{"status":"fail","detail":"last block 17 has not advanced for 3 ticks"}
+	}}`, rr.Body.String())
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Backfill stores the transactions of a subscribed address in past blocks,
+// at most domain.MaxBackfillBlocks per request.
+func (r *Router) Backfill(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		From *int `json:"from"`
+		To   *int `json:"to"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil || body.From == nil || body.To == nil {
+		r.writeJSON(Response{Message: "invalid backfill request", Code: http.StatusBadRequest}, w)
+		return
+	}
+
+	result, err := r.service.Backfill(req.Context(), req.PathValue("address"), *body.From, *body.To)
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: result}, w)
+}
+
+// Replay publishes the stored transactions of an address that match its
+// current rules again.
+func (r *Router) Replay(w http.ResponseWriter, req *http.Request) {
+	matched, err := r.service.Replay(req.Context(), req.PathValue("address"))
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: map[string]int{"matched": matched}}, w)
+}
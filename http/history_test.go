@@ -0,0 +1,88 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+func Test_Backfill(t *testing.T) {
+	tests := []struct {
+		name        string
+		requestBody string
+		result      *domain.BackfillResult
+		err         error
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "backfilled",
+			requestBody: `{"from":16,"to":17}`,
+			result:      &domain.BackfillResult{From: 16, To: 17, Stored: 2},
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"data":{"from":16,"to":17,"stored":2}}`,
+		},
+		{
+			name:        "missing bound",
+			requestBody: `{"from":16}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody: `{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"invalid backfill request","code":"invalid_request"}`,
+		},
+		{
+			name:        "range too wide",
+			requestBody: `{"from":16,"to":1000}`,
+			err:         fmt.Errorf("%w: 985 blocks, at most 100 at a time", domain.ErrInvalidRange),
+			wantStatus:  http.StatusBadRequest,
+			wantBody: `{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"invalid block range: 985 blocks, at most 100 at a time","code":"invalid_range"}`,
+		},
+		{
+			name:        "node down",
+			requestBody: `{"from":16,"to":17}`,
+			err:         fmt.Errorf("%w: block 16: connection refused", domain.ErrNodeUnavailable),
+			wantStatus:  http.StatusBadGateway,
+			wantBody: `{"type":"about:blank","title":"Bad Gateway","status":502,` +
+				`"detail":"node unavailable: block 16: connection refused","code":"node_unavailable"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockService.On("Backfill", "0x1111", 16, 17).Return(tt.result, tt.err)
+			mockService.On("Backfill", "0x1111", 16, 1000).Return(tt.result, tt.err)
+			router := NewRouter(slog.Default(), mockService)
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/v1/subscriptions/0x1111/backfill", bytes.NewBufferString(tt.requestBody))
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.JSONEq(t, tt.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func Test_Replay(t *testing.T) {
+	mockService := &MockService{}
+	mockService.On("Replay", "0x1111").Return(3, nil)
+	mockService.On("Replay", "0x2222").Return(0, domain.ErrNotSubscribed)
+	router := NewRouter(slog.Default(), mockService)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/subscriptions/0x1111/replay", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":{"matched":3}}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/subscriptions/0x2222/replay", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
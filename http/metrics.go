@@ -0,0 +1,77 @@
+package http
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"deshev.com/eth-address-watch/metrics"
+)
+
+const (
+	metricsPath = "/metrics"
+	// unmatchedRoute labels requests no route matched, to keep arbitrary
+	// paths out of the label values
+	unmatchedRoute = "unmatched"
+)
+
+var (
+	requestDuration = metrics.Default.NewHistogram("eaw_http_request_duration_seconds",
+		"Latency of HTTP requests by route.", metrics.DefaultBuckets, "route")
+	requests = metrics.Default.NewCounter("eaw_http_requests_total",
+		"HTTP requests by route and response status.", "route", "status")
+)
+
+// route is the pattern of the route matching the request, used to label
+// metrics and name spans.
+func (r *Router) route(req *http.Request) string {
+	if _, pattern := r.ServeMux.Handler(req); pattern != "" {
+		return pattern
+	}
+	return unmatchedRoute
+}
+
+// observeRequest records a served request under its route pattern. Streams
+// and websockets are observed once they end.
+func observeRequest(route string, status int, duration time.Duration) {
+	requestDuration.With(route).Observe(duration.Seconds())
+	requests.With(route, strconv.Itoa(status)).Inc()
+}
+
+// statusRecorder keeps the response status for metrics.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the flusher of streams.
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack is implemented directly since the websocket upgrader asserts
+// http.Hijacker instead of going through http.ResponseController.
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"deshev.com/eth-address-watch/domain"
+	"deshev.com/eth-address-watch/metrics"
+)
+
+func Test_RequestMetrics(t *testing.T) {
+	mockService := &MockService{}
+	mockService.On("GetCurrentBlock").Return(0x11)
+	mockService.On("GetSubscription", "0x1111").Return(nil, domain.ErrNotSubscribed)
+	router := NewRouter(slog.Default(), mockService)
+
+	tests := []struct {
+		name   string
+		path   string
+		route  string
+		status string
+	}{
+		{name: "ok", path: "/v1/block", route: "GET /v1/block", status: "200"},
+		{name: "error status", path: "/v1/subscriptions/0x1111", route: "GET /v1/subscriptions/{address}", status: "404"},
+		{name: "no route", path: "/v1/nothing/here", route: unmatchedRoute, status: "404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latency := requestDuration.With(tt.route)
+			counter := requests.With(tt.route, tt.status)
+			observed, counted := latency.Count(), counter.Value()
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, observed+1, latency.Count())
+			assert.Equal(t, counted+1, counter.Value())
+		})
+	}
+}
+
+func Test_MetricsEndpoint(t *testing.T) {
+	log := slog.Default()
+	router := NewRouter(log, domain.NewService(log, domain.NewBus(log)))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/block", nil))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, metricsPath, nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, metrics.ContentType, rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), `eaw_http_requests_total{route="GET /v1/block",status="200"}`)
+
+	router.EnableAuth(testAdminKey)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, metricsPath, nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	req := httptest.NewRequest(http.MethodGet, metricsPath, nil)
+	req.Header.Set(apiKeyHeader, testAdminKey)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
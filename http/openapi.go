@@ -0,0 +1,285 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const openAPIPath = "/openapi.json"
+
+// OpenAPI is the subset of an OpenAPI 3 document the router describes itself with.
+type OpenAPI struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components OpenAPIComponents                `json:"components"`
+	Security   []map[string][]string            `json:"security,omitempty"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	Name   string `json:"name,omitempty"`
+	In     string `json:"in,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*OpResult  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// OpResult is an operation response. It is not called Response to keep it
+// apart from the API response envelope.
+type OpResult struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Schema is the subset of JSON Schema used by the spec and the request
+// validator. A schema without a type accepts any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+}
+
+// handle registers a route and records its pattern for the spec.
+func (r *Router) handle(pattern string, h http.HandlerFunc) {
+	r.patterns = append(r.patterns, pattern)
+	r.ServeMux.HandleFunc(pattern, h)
+}
+
+// EnableRequestValidation rejects JSON request bodies that do not match the
+// schema of their operation.
+func (r *Router) EnableRequestValidation() {
+	r.validateRequests = true
+}
+
+// OpenAPI describes every registered route.
+func (r *Router) OpenAPI() *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: "eth-address-watch", Version: apiVersion},
+		Paths:   map[string]map[string]*Operation{},
+		Components: OpenAPIComponents{
+			Schemas: schemas,
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearer": {Type: "http", Scheme: "bearer"},
+				"apiKey": {Type: "apiKey", Name: apiKeyHeader, In: "header"},
+			},
+		},
+	}
+	if r.authEnabled {
+		doc.Security = []map[string][]string{{"bearer": {}}, {"apiKey": {}}}
+	}
+
+	for _, pattern := range r.patterns {
+		_, path := splitPattern(pattern)
+		for method, op := range operations[pattern] {
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = map[string]*Operation{}
+			}
+			doc.Paths[path][method] = op
+		}
+	}
+	return doc
+}
+
+// GetOpenAPI serves the OpenAPI document.
+func (r *Router) GetOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(r.OpenAPI()); err != nil {
+		r.log.Error("failed to write OpenAPI document", "error", err)
+	}
+}
+
+// splitPattern splits a mux pattern into a lowercase method, empty for
+// patterns matching every method, and the path.
+func splitPattern(pattern string) (string, string) {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		return "", pattern
+	}
+	return strings.ToLower(method), path
+}
+
+// validateRequest replies with 400 and returns false when the JSON body of
+// the request does not match the schema of its operation.
+func (r *Router) validateRequest(w http.ResponseWriter, req *http.Request) bool {
+	_, pattern := r.ServeMux.Handler(req)
+	op := operations[pattern][strings.ToLower(req.Method)]
+	if op == nil || op.RequestBody == nil {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = "application/json"
+	}
+	content := op.RequestBody.Content[mediaType]
+	if mediaType != "application/json" || content == nil {
+		return true
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxImportBytes))
+	if err != nil {
+		r.writeJSON(Response{Message: "request body too large", Code: http.StatusRequestEntityTooLarge}, w)
+		return false
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 && !op.RequestBody.Required {
+		return true
+	}
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	violations := []string{}
+	if err := decoder.Decode(&value); err != nil {
+		violations = append(violations, "body is not valid JSON")
+	} else {
+		violations = validate(content.Schema, value, "body", violations)
+	}
+	if len(violations) == 0 {
+		return true
+	}
+
+	r.writeJSON(Response{
+		Message:   "request body does not match the schema",
+		Code:      http.StatusBadRequest,
+		Data:      violations,
+		ErrorCode: "invalid_request",
+	}, w)
+	return false
+}
+
+// validate appends a message for every part of the value the schema rejects.
+func validate(s *Schema, value any, path string, violations []string) []string {
+	if s.Ref != "" {
+		s = schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	if value == nil {
+		if s.Type != "" && !s.Nullable {
+			violations = append(violations, path+": must not be null")
+		}
+		return violations
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return append(violations, path+": must be an object")
+		}
+		for _, name := range s.Required {
+			if _, exists := object[name]; !exists {
+				violations = append(violations, path+"."+name+": is required")
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, exists := s.Properties[name]
+			switch {
+			case exists:
+				violations = validate(property, object[name], path+"."+name, violations)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				violations = append(violations, path+"."+name+": is not allowed")
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return append(violations, path+": must be an array")
+		}
+		for i, item := range array {
+			violations = validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i), violations)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return append(violations, path+": must be a string")
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			violations = append(violations, fmt.Sprintf("%s: must be one of %q", path, s.Enum))
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return append(violations, path+": must be a number")
+		}
+		if _, err := number.Int64(); s.Type == "integer" && err != nil {
+			violations = append(violations, path+": must be an integer")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			violations = append(violations, path+": must be a boolean")
+		}
+	}
+	return violations
+}
+
+// specCoverage lists registered patterns and methods without an operation
+// in the spec.
+func (r *Router) specCoverage() error {
+	errs := []error{}
+	for _, pattern := range r.patterns {
+		method, _ := splitPattern(pattern)
+		ops := operations[pattern]
+		switch {
+		case len(ops) == 0:
+			errs = append(errs, fmt.Errorf("route %q is not in the spec", pattern))
+		case method != "" && ops[method] == nil:
+			errs = append(errs, fmt.Errorf("route %q has no %s operation", pattern, method))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,667 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	noExtraFields = new(bool)
+
+	str     = &Schema{Type: "string"}
+	boolean = &Schema{Type: "boolean"}
+	integer = &Schema{Type: "integer"}
+	strList = &Schema{Type: "array", Items: str, Nullable: true}
+
+	metadataProperties = map[string]*Schema{
+		"label":    str,
+		"tags":     strList,
+		"owner":    str,
+		"metadata": {Description: "Free-form JSON for the caller's own use."},
+	}
+)
+
+// schemas are the components of the OpenAPI document.
+var schemas = map[string]*Schema{
+	"Transaction": object(nil, nil, map[string]*Schema{
+		"hash":           str,
+		"blockNumber":    str,
+		"from":           str,
+		"to":             str,
+		"value":          str,
+		"gas":            str,
+		"gasPrice":       str,
+		"input":          str,
+		"status":         {Type: "string", Description: "Receipt status, 0x1 for success and 0x0 for failure."},
+		"blockTimestamp": {Type: "string", Description: "Block time in hex Unix seconds, empty when unknown."},
+		"fromName":       {Type: "string", Description: "Primary ENS name of the sender when it is the counterparty."},
+		"toName":         {Type: "string", Description: "Primary ENS name of the recipient when it is the counterparty."},
+	}),
+	"GroupTransaction": object(nil, nil, map[string]*Schema{
+		"hash":           str,
+		"blockNumber":    str,
+		"from":           str,
+		"to":             str,
+		"value":          str,
+		"gas":            str,
+		"gasPrice":       str,
+		"input":          str,
+		"status":         str,
+		"blockTimestamp": str,
+		"internal":       {Type: "boolean", Description: "Set for transfers between two members of the group."},
+	}),
+	"ExportRow": object(nil, nil, map[string]*Schema{
+		"address":       {Type: "string", Description: "Subscribed address the row is exported for."},
+		"id":            {Type: "integer", Description: "Position of the transaction in the address history."},
+		"hash":          str,
+		"block_number":  integer,
+		"block_time":    {Type: "string", Format: "date-time", Nullable: true},
+		"from":          str,
+		"to":            str,
+		"direction":     {Type: "string", Enum: []string{"in", "out", "self"}},
+		"value_wei":     {Type: "string", Description: "Decimal integer."},
+		"value_eth":     {Type: "string", Description: "Exact decimal."},
+		"gas":           integer,
+		"gas_price_wei": {Type: "string", Description: "Decimal integer."},
+		"status":        {Type: "string", Enum: []string{"success", "failed", ""}},
+	}),
+	"Rule": object(nil, noExtraFields, map[string]*Schema{
+		"minValue":   {Type: "string", Description: `Amount like "1.5 ether", "20 gwei" or wei.`},
+		"maxValue":   {Type: "string", Description: `Amount like "1.5 ether", "20 gwei" or wei.`},
+		"direction":  {Type: "string", Enum: []string{"", "in", "out"}},
+		"allow":      strList,
+		"deny":       strList,
+		"method":     {Type: "string", Description: "4-byte method selector like 0xa9059cbb."},
+		"failedOnly": boolean,
+		"expression": {Type: "string", Description: "Condition in the rule expression language."},
+	}),
+	"SubscriptionMetadata": object(nil, noExtraFields, metadataProperties),
+	"Subscription": object([]string{"address", "createdAt"}, nil, withProperties(metadataProperties, map[string]*Schema{
+		"address":   str,
+		"name":      {Type: "string", Description: "ENS name the subscription follows."},
+		"createdAt": {Type: "string", Format: "date-time"},
+		"rules":     {Type: "array", Items: ref("Rule")},
+	})),
+	"SubscriptionImport": object([]string{"address"}, nil, withProperties(metadataProperties, map[string]*Schema{
+		"address": str,
+		"rules":   {Type: "array", Items: ref("Rule"), Nullable: true},
+	})),
+	"ImportResult": object(nil, nil, map[string]*Schema{
+		"row":     integer,
+		"address": str,
+		"created": boolean,
+		"error":   str,
+	}),
+	"TransactionMatched": object(nil, nil, map[string]*Schema{
+		"id":           integer,
+		"address":      str,
+		"transaction":  ref("Transaction"),
+		"subscription": ref("Subscription"),
+		"replayed":     boolean,
+	}),
+	"Group": object(nil, nil, map[string]*Schema{
+		"name":      str,
+		"members":   {Type: "array", Items: str},
+		"createdAt": {Type: "string", Format: "date-time"},
+	}),
+	"APIKey": object(nil, nil, map[string]*Schema{
+		"id":        str,
+		"tenant":    str,
+		"name":      str,
+		"createdAt": {Type: "string", Format: "date-time"},
+		"key":       {Type: "string", Description: "Plaintext key, only returned on creation."},
+	}),
+	"Problem": object(nil, nil, map[string]*Schema{
+		"type":   str,
+		"title":  str,
+		"status": integer,
+		"detail": str,
+		"code":   str,
+		"data":   {},
+	}),
+	"Error": object(nil, nil, map[string]*Schema{
+		"message": str,
+		"data":    {},
+	}),
+	"BackfillResult": object(nil, nil, map[string]*Schema{
+		"from":   integer,
+		"to":     integer,
+		"stored": integer,
+	}),
+	"Balance": object([]string{"address", "block", "wei", "eth"}, nil, map[string]*Schema{
+		"address":         str,
+		"block":           {Type: "integer", Description: "Block the balance is after."},
+		"wei":             {Type: "string", Description: "Decimal integer."},
+		"eth":             {Type: "string", Description: "Exact decimal."},
+		"reconciledBlock": {Type: "integer", Description: "Last block the balance was checked against the node at."},
+		"drift":           ref("BalanceDrift"),
+	}),
+	"BalanceDrift": object(nil, nil, map[string]*Schema{
+		"block":      integer,
+		"tracked":    {Type: "string", Description: "Tracked wei balance."},
+		"node":       {Type: "string", Description: "Wei balance reported by the node."},
+		"wei":        {Type: "string", Description: "Node balance minus the tracked one."},
+		"detectedAt": {Type: "string", Format: "date-time"},
+	}),
+	"BalancePoint": object(nil, nil, map[string]*Schema{
+		"block":  integer,
+		"wei":    str,
+		"eth":    str,
+		"change": {Type: "string", Description: "Signed wei difference to the previous point."},
+		"source": {Type: "string", Enum: []string{"seed", "transactions", "reconciliation"}},
+	}),
+	"TokenBalance": object([]string{"address", "token", "decimals", "block", "amount", "value"}, nil, map[string]*Schema{
+		"address":         str,
+		"token":           {Type: "string", Description: "Token contract in lower case."},
+		"symbol":          str,
+		"decimals":        integer,
+		"block":           {Type: "integer", Description: "Block the balance is after."},
+		"amount":          {Type: "string", Description: "Decimal integer in the smallest unit of the token."},
+		"value":           {Type: "string", Description: "Exact decimal in whole tokens."},
+		"reconciledBlock": {Type: "integer", Description: "Last block the balance was checked against the node at."},
+		"drift":           ref("BalanceDrift"),
+	}),
+	"TokenBalancePoint": object(nil, nil, map[string]*Schema{
+		"block":  integer,
+		"amount": str,
+		"value":  str,
+		"change": {Type: "string", Description: "Signed difference to the previous point in the smallest unit of the token."},
+		"source": {Type: "string", Enum: []string{"seed", "transactions", "reconciliation"}},
+	}),
+	"HealthReport": object([]string{"status", "checks"}, nil, map[string]*Schema{
+		"status": {Type: "string", Enum: []string{"ok", "fail"}},
+		"checks": {Type: "object", Description: `Result of each check by name, like {"status": "fail", "detail": "..."}.`},
+	}),
+	"GraphQLResult": object(nil, nil, map[string]*Schema{
+		"data":   {Type: "object", Nullable: true},
+		"errors": {Type: "array", Items: object([]string{"message"}, nil, map[string]*Schema{"message": str})},
+	}),
+}
+
+var (
+	addressParam = pathParam("address")
+	nameParam    = pathParam("name")
+
+	exportParams = []*Parameter{
+		{Name: "format", In: "query", Schema: &Schema{Type: "string", Enum: []string{"csv", "ndjson", "parquet"}}},
+		{Name: "from", In: "query", Schema: &Schema{Type: "string", Description: "Included start as RFC 3339 or YYYY-MM-DD."}},
+		{Name: "to", In: "query", Schema: &Schema{Type: "string", Description: "Excluded end as RFC 3339 or YYYY-MM-DD."}},
+	}
+	exportResponses = map[string]*OpResult{
+		"200": {Description: "Rows with the ExportRow columns", Content: map[string]*MediaType{
+			"text/csv":                       {Schema: str},
+			"application/x-ndjson":           {Schema: ref("ExportRow")},
+			"application/vnd.apache.parquet": {Schema: &Schema{Type: "string", Format: "binary"}},
+		}},
+		"default": problem(),
+	}
+
+	rulesRequest = object([]string{"rules"}, noExtraFields, map[string]*Schema{
+		"address": str,
+		"rules":   {Type: "array", Items: ref("Rule")},
+	})
+	groupRequest = object([]string{"name"}, nil, map[string]*Schema{
+		"name":    str,
+		"members": strList,
+	})
+	membersRequest = object(nil, nil, map[string]*Schema{
+		"name":   str,
+		"add":    strList,
+		"remove": strList,
+	})
+	apiKeyRequest = object([]string{"tenant"}, nil, map[string]*Schema{
+		"tenant": str,
+		"name":   str,
+	})
+	importRequest = &RequestBody{
+		Required: true,
+		Content: map[string]*MediaType{
+			"application/json": {Schema: &Schema{Type: "array", Items: ref("SubscriptionImport")}},
+			"text/csv":         {Schema: str},
+		},
+	}
+
+	getBlock = &Operation{
+		OperationID: "getBlock",
+		Summary:     "Last parsed block number",
+		Responses:   ok(integer),
+	}
+	listTransactions = &Operation{
+		OperationID: "listTransactions",
+		Summary:     "Stored transactions of a subscribed address",
+		Parameters:  []*Parameter{addressParam},
+		Responses:   ok(list("Transaction")),
+	}
+	getBalance = &Operation{
+		OperationID: "getBalance",
+		Summary:     "ETH balance of a subscribed address, currently or after a block",
+		Parameters:  []*Parameter{addressParam, {Name: "block", In: "query", Schema: integer}},
+		Responses:   ok(ref("Balance")),
+	}
+	getBalanceHistory = &Operation{
+		OperationID: "getBalanceHistory",
+		Summary:     "Balance changes of a subscribed address by block",
+		Parameters: []*Parameter{
+			addressParam,
+			{Name: "from", In: "query", Schema: integer},
+			{Name: "to", In: "query", Schema: integer},
+		},
+		Responses: ok(list("BalancePoint")),
+	}
+	getTokenBalances = &Operation{
+		OperationID: "getTokenBalances",
+		Summary:     "Balances of a subscribed address in the tracked ERC-20 tokens",
+		Parameters:  []*Parameter{addressParam},
+		Responses:   ok(list("TokenBalance")),
+	}
+	getTokenBalanceHistory = &Operation{
+		OperationID: "getTokenBalanceHistory",
+		Summary:     "Token balance changes of a subscribed address by block",
+		Parameters: []*Parameter{
+			addressParam,
+			pathParam("token"),
+			{Name: "from", In: "query", Schema: integer},
+			{Name: "to", In: "query", Schema: integer},
+		},
+		Responses: ok(list("TokenBalancePoint")),
+	}
+	streamTransactions = &Operation{
+		OperationID: "streamTransactions",
+		Summary:     "Server-Sent Events stream of matched transactions",
+		Parameters:  []*Parameter{addressParam, {Name: "Last-Event-ID", In: "header", Schema: integer}},
+		Responses: map[string]*OpResult{
+			"200":     {Description: "Event stream", Content: content("text/event-stream", ref("TransactionMatched"))},
+			"default": problem(),
+		},
+	}
+	webSocket = &Operation{
+		OperationID: "webSocket",
+		Summary:     "WebSocket with live blocks and matched transactions",
+		Responses: map[string]*OpResult{
+			"101":     {Description: "Switching protocols"},
+			"default": problem(),
+		},
+	}
+	exportTransactions = &Operation{
+		OperationID: "exportTransactions",
+		Summary:     "Stream the stored transactions of an address as CSV, NDJSON or Parquet",
+		Parameters:  append([]*Parameter{addressParam}, exportParams...),
+		Responses:   exportResponses,
+	}
+	listSubscriptions = &Operation{
+		OperationID: "listSubscriptions",
+		Summary:     "Subscriptions filtered by tag and owner",
+		Parameters:  []*Parameter{queryParam("tag"), queryParam("owner")},
+		Responses:   ok(list("Subscription")),
+	}
+	getSubscription = &Operation{
+		OperationID: "getSubscription",
+		Summary:     "Subscription of an address",
+		Parameters:  []*Parameter{addressParam},
+		Responses:   ok(ref("Subscription")),
+	}
+	putSubscription = &Operation{
+		OperationID: "putSubscription",
+		Summary:     "Subscribe an address and replace its metadata if there is a body",
+		Parameters:  []*Parameter{addressParam},
+		RequestBody: body(false, ref("SubscriptionMetadata")),
+		Responses:   ok(ref("Subscription")),
+	}
+	deleteSubscription = &Operation{
+		OperationID: "deleteSubscription",
+		Summary:     "Unsubscribe an address and drop its transactions",
+		Parameters:  []*Parameter{addressParam},
+		Responses:   ok(boolean),
+	}
+	getRules = &Operation{
+		OperationID: "getRules",
+		Summary:     "Alert rules of a subscription",
+		Parameters:  []*Parameter{addressParam},
+		Responses:   ok(list("Rule")),
+	}
+	setRules = &Operation{
+		OperationID: "setRules",
+		Summary:     "Replace the alert rules of a subscription",
+		Parameters:  []*Parameter{addressParam},
+		RequestBody: body(true, rulesRequest),
+		Responses:   ok(list("Rule")),
+	}
+	backfill = &Operation{
+		OperationID: "backfill",
+		Summary:     "Store the transactions of a subscription in past blocks, at most 100 per request",
+		Parameters:  []*Parameter{addressParam},
+		RequestBody: body(true, object([]string{"from", "to"}, noExtraFields, map[string]*Schema{
+			"from": integer,
+			"to":   integer,
+		})),
+		Responses: ok(ref("BackfillResult")),
+	}
+	replay = &Operation{
+		OperationID: "replay",
+		Summary:     "Publish the stored transactions matching the current rules again",
+		Parameters:  []*Parameter{addressParam},
+		Responses:   ok(object([]string{"matched"}, nil, map[string]*Schema{"matched": integer})),
+	}
+	importSubscriptions = &Operation{
+		OperationID: "importSubscriptions",
+		Summary:     "Subscribe a batch of addresses from JSON or CSV",
+		RequestBody: importRequest,
+		Responses:   ok(list("ImportResult")),
+	}
+	exportSubscriptions = &Operation{
+		OperationID: "exportSubscriptions",
+		Summary:     "All subscriptions as JSON or CSV",
+		Parameters:  []*Parameter{{Name: "format", In: "query", Schema: &Schema{Type: "string", Enum: []string{"json", "csv"}}}},
+		Responses: map[string]*OpResult{
+			"200": {Description: "Subscriptions", Content: map[string]*MediaType{
+				"application/json": {Schema: envelope(list("Subscription"))},
+				"text/csv":         {Schema: str},
+			}},
+			"default": problem(),
+		},
+	}
+	listGroups = &Operation{
+		OperationID: "listGroups",
+		Summary:     "All groups",
+		Responses:   ok(list("Group")),
+	}
+	createGroup = &Operation{
+		OperationID: "createGroup",
+		Summary:     "Create a group and subscribe its members",
+		RequestBody: body(true, groupRequest),
+		Responses:   created(ref("Group")),
+	}
+	getGroup = &Operation{
+		OperationID: "getGroup",
+		Summary:     "Group by name",
+		Parameters:  []*Parameter{nameParam},
+		Responses:   ok(ref("Group")),
+	}
+	deleteGroup = &Operation{
+		OperationID: "deleteGroup",
+		Summary:     "Delete a group",
+		Parameters:  []*Parameter{nameParam},
+		Responses:   ok(boolean),
+	}
+	updateGroupMembers = &Operation{
+		OperationID: "updateGroupMembers",
+		Summary:     "Add and remove group members",
+		Parameters:  []*Parameter{nameParam},
+		RequestBody: body(true, membersRequest),
+		Responses:   ok(ref("Group")),
+	}
+	listGroupTransactions = &Operation{
+		OperationID: "listGroupTransactions",
+		Summary:     "Merged transactions of all group members",
+		Parameters:  []*Parameter{nameParam},
+		Responses:   ok(list("GroupTransaction")),
+	}
+	exportGroupTransactions = &Operation{
+		OperationID: "exportGroupTransactions",
+		Summary:     "Stream the transactions of all group members as CSV, NDJSON or Parquet",
+		Parameters:  append([]*Parameter{nameParam}, exportParams...),
+		Responses:   exportResponses,
+	}
+	listAPIKeys = &Operation{
+		OperationID: "listAPIKeys",
+		Summary:     "API keys of all tenants",
+		Responses:   ok(list("APIKey")),
+	}
+	createAPIKey = &Operation{
+		OperationID: "createAPIKey",
+		Summary:     "Issue an API key for a tenant",
+		RequestBody: body(true, apiKeyRequest),
+		Responses:   created(ref("APIKey")),
+	}
+	revokeAPIKey = &Operation{
+		OperationID: "revokeAPIKey",
+		Summary:     "Revoke an API key",
+		Parameters:  []*Parameter{pathParam("id")},
+		Responses:   ok(boolean),
+	}
+	reloadConfig = &Operation{
+		OperationID: "reloadConfig",
+		Summary:     "Reload the configuration, 409 when a value needs a restart",
+		Responses: ok(object([]string{"changed"}, nil, map[string]*Schema{
+			"changed": {Type: "array", Items: str, Description: "Config keys whose value changed."},
+		})),
+	}
+	getOpenAPI = &Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "This document",
+		// an empty requirement makes authentication optional
+		Security: []map[string][]string{{}},
+		Responses: map[string]*OpResult{
+			"200": {Description: "OpenAPI document", Content: content("application/json", &Schema{Type: "object"})},
+		},
+	}
+	getHealth = &Operation{
+		OperationID: "getHealth",
+		Summary:     "Liveness probe",
+		Security:    []map[string][]string{{}},
+		Responses: map[string]*OpResult{
+			"200": {Description: "The process is alive", Content: content("application/json", ref("HealthReport"))},
+		},
+	}
+	getReadiness = &Operation{
+		OperationID: "getReadiness",
+		Summary:     "Readiness probe with the result of every check",
+		Security:    []map[string][]string{{}},
+		Responses: map[string]*OpResult{
+			"200": {Description: "Every check passed", Content: content("application/json", ref("HealthReport"))},
+			"503": {Description: "At least one check failed", Content: content("application/json", ref("HealthReport"))},
+		},
+	}
+	getMetrics = &Operation{
+		OperationID: "getMetrics",
+		Summary:     "Prometheus metrics",
+		Responses: map[string]*OpResult{
+			"200": {Description: "Metrics in the Prometheus text format", Content: content("text/plain", str)},
+		},
+	}
+	graphQLResult = map[string]*OpResult{
+		"200": {Description: "Query result with any resolver errors", Content: content("application/json", ref("GraphQLResult"))},
+		"400": {Description: "Query rejected before execution", Content: content("application/json", ref("GraphQLResult"))},
+	}
+	getGraphQL = &Operation{
+		OperationID: "getGraphQL",
+		Summary:     "Run a GraphQL query passed in the query string",
+		Parameters:  []*Parameter{queryParam("query"), queryParam("variables"), queryParam("operationName")},
+		Responses:   graphQLResult,
+	}
+	postGraphQL = &Operation{
+		OperationID: "postGraphQL",
+		Summary:     "Run a GraphQL query",
+		RequestBody: body(true, object([]string{"query"}, nil, map[string]*Schema{
+			"query":         str,
+			"variables":     {Type: "object", Nullable: true},
+			"operationName": {Type: "string", Nullable: true},
+		})),
+		Responses: graphQLResult,
+	}
+)
+
+// operations describe the registered routes by mux pattern and lowercase
+// method. Patterns without a method list every method they handle.
+var operations = map[string]map[string]*Operation{
+	"GET /v1/block": {"get": getBlock},
+	"GET /v1/addresses/{address}/transactions":           {"get": listTransactions},
+	"GET /v1/addresses/{address}/transactions/export":    {"get": exportTransactions},
+	"GET /v1/addresses/{address}/balance":                {"get": getBalance},
+	"GET /v1/addresses/{address}/balance/history":        {"get": getBalanceHistory},
+	"GET /v1/addresses/{address}/tokens":                 {"get": getTokenBalances},
+	"GET /v1/addresses/{address}/tokens/{token}/history": {"get": getTokenBalanceHistory},
+	"GET /v1/addresses/{address}/stream":                 {"get": streamTransactions},
+	"GET /v1/ws":                                         {"get": webSocket},
+	"GET /v1/subscriptions":                              {"get": listSubscriptions},
+	"GET /v1/subscriptions/{address}":                    {"get": getSubscription},
+	"PUT /v1/subscriptions/{address}":                    {"put": putSubscription},
+	"DELETE /v1/subscriptions/{address}":                 {"delete": deleteSubscription},
+	"GET /v1/subscriptions/{address}/rules":              {"get": getRules},
+	"PUT /v1/subscriptions/{address}/rules":              {"put": setRules},
+	"POST /v1/subscriptions/{address}/backfill":          {"post": backfill},
+	"POST /v1/subscriptions/{address}/replay":            {"post": replay},
+	"POST /v1/subscriptions/import":                      {"post": importSubscriptions},
+	"GET /v1/subscriptions/export":                       {"get": exportSubscriptions},
+	"GET /v1/groups":                                     {"get": listGroups},
+	"POST /v1/groups":                                    {"post": createGroup},
+	"GET /v1/groups/{name}":                              {"get": getGroup},
+	"DELETE /v1/groups/{name}":                           {"delete": deleteGroup},
+	"POST /v1/groups/{name}/members":                     {"post": updateGroupMembers},
+	"GET /v1/groups/{name}/transactions":                 {"get": listGroupTransactions},
+	"GET /v1/groups/{name}/transactions/export":          {"get": exportGroupTransactions},
+	"GET /v1/admin/keys":                                 {"get": admin(listAPIKeys)},
+	"POST /v1/admin/keys":                                {"post": admin(createAPIKey)},
+	"DELETE /v1/admin/keys/{id}":                         {"delete": admin(revokeAPIKey)},
+	"POST " + reloadPath:                                 {"post": admin(reloadConfig)},
+	"GET " + openAPIPath:                                 {"get": getOpenAPI},
+	"GET /graphql":                                       {"get": getGraphQL},
+	"POST /graphql":                                      {"post": postGraphQL},
+	"GET " + metricsPath:                                 {"get": admin(getMetrics)},
+	"GET " + healthzPath:                                 {"get": getHealth},
+	"GET " + readyzPath:                                  {"get": getReadiness},
+
+	"/block":        {"get": legacy(getBlock)},
+	"/transactions": {"get": legacy(listTransactions)},
+	"/subscribe": {"post": legacy(&Operation{
+		OperationID: "subscribe",
+		Summary:     "Subscribe an address",
+		RequestBody: body(true, object([]string{"address"}, nil, withProperties(metadataProperties, map[string]*Schema{
+			"address": {Type: "string", Description: "Address or ENS name like vitalik.eth."},
+		}))),
+		Responses: ok(&Schema{Description: "true for an address, the Subscription with the resolved address for an ENS name."}),
+	})},
+	"/stream": {"get": legacy(streamTransactions)},
+	"/ws":     {"get": legacy(webSocket)},
+	"/rules": {
+		"get":  legacy(getRules),
+		"put":  legacy(setRules),
+		"post": withID(legacy(setRules), "legacySetRulesPost"),
+	},
+	"/subscriptions": {
+		"get": legacy(listSubscriptions),
+		"put": legacy(&Operation{
+			OperationID: "setMetadata",
+			Summary:     "Replace the metadata of a subscription",
+			RequestBody: body(true, object([]string{"address"}, noExtraFields, withProperties(metadataProperties, map[string]*Schema{"address": str}))),
+			Responses:   ok(ref("SubscriptionMetadata")),
+		}),
+	},
+	"/subscriptions/import": {"post": legacy(importSubscriptions)},
+	"/subscriptions/export": {"get": legacy(exportSubscriptions)},
+	"/groups": {
+		"get": legacy(&Operation{
+			OperationID: "groups",
+			Summary:     "All groups, or the group with the given name",
+			Parameters:  []*Parameter{queryParam("name")},
+			Responses:   ok(&Schema{Description: "A group, or a list of groups without a name"}),
+		}),
+		"post":   legacy(createGroup),
+		"delete": legacy(deleteGroup),
+	},
+	"/groups/members":      {"post": legacy(updateGroupMembers)},
+	"/groups/transactions": {"get": legacy(listGroupTransactions)},
+	"/admin/keys": {
+		"get":    legacy(admin(listAPIKeys)),
+		"post":   legacy(admin(createAPIKey)),
+		"delete": legacy(admin(revokeAPIKey)),
+	},
+}
+
+func object(required []string, additional *bool, properties map[string]*Schema) *Schema {
+	return &Schema{Type: "object", Required: required, AdditionalProperties: additional, Properties: properties}
+}
+
+func withProperties(base, extra map[string]*Schema) map[string]*Schema {
+	result := map[string]*Schema{}
+	for name, s := range base {
+		result[name] = s
+	}
+	for name, s := range extra {
+		result[name] = s
+	}
+	return result
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func list(name string) *Schema {
+	return &Schema{Type: "array", Items: ref(name)}
+}
+
+func envelope(data *Schema) *Schema {
+	return object(nil, nil, map[string]*Schema{"data": data})
+}
+
+func content(mediaType string, s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{mediaType: {Schema: s}}
+}
+
+func body(required bool, s *Schema) *RequestBody {
+	return &RequestBody{Required: required, Content: content("application/json", s)}
+}
+
+func pathParam(name string) *Parameter {
+	return &Parameter{Name: name, In: "path", Required: true, Schema: str}
+}
+
+func queryParam(name string) *Parameter {
+	return &Parameter{Name: name, In: "query", Schema: str}
+}
+
+func problem() *OpResult {
+	return &OpResult{Description: "Error", Content: content(problemContentType, ref("Problem"))}
+}
+
+func ok(data *Schema) map[string]*OpResult {
+	return result(http.StatusOK, data)
+}
+
+func created(data *Schema) map[string]*OpResult {
+	return result(http.StatusCreated, data)
+}
+
+func result(status int, data *Schema) map[string]*OpResult {
+	return map[string]*OpResult{
+		strconv.Itoa(status): {Description: http.StatusText(status), Content: content("application/json", envelope(data))},
+		"default":            problem(),
+	}
+}
+
+func withID(op *Operation, id string) *Operation {
+	op.OperationID = id
+	return op
+}
+
+// admin marks operations that need the admin key instead of a tenant key.
+func admin(op *Operation) *Operation {
+	c := *op
+	c.Summary += " (admin key)"
+	return &c
+}
+
+// legacy describes a deprecated unversioned route. It takes path parameters
+// from the query and returns plain JSON errors.
+func legacy(op *Operation) *Operation {
+	c := *op
+	c.OperationID = "legacy" + strings.ToUpper(op.OperationID[:1]) + op.OperationID[1:]
+	c.Deprecated = true
+	c.Parameters = nil
+	for _, p := range op.Parameters {
+		if p.In == "path" {
+			p = &Parameter{Name: p.Name, In: "query", Required: true, Schema: p.Schema}
+		}
+		c.Parameters = append(c.Parameters, p)
+	}
+	c.Responses = map[string]*OpResult{}
+	for status, r := range op.Responses {
+		if status == "default" {
+			r = &OpResult{Description: "Error", Content: content("application/json", ref("Error"))}
+		}
+		c.Responses[status] = r
+	}
+	return &c
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+func Test_OpenAPICoversRoutes(t *testing.T) {
+	router := NewRouter(slog.Default(), &MockService{})
+	assert.NoError(t, router.specCoverage())
+
+	// and the spec has nothing that is not registered
+	registered := map[string]bool{}
+	for _, pattern := range router.patterns {
+		registered[pattern] = true
+	}
+	ids := map[string]string{}
+	for pattern, ops := range operations {
+		assert.True(t, registered[pattern], "spec operation for unregistered route %q", pattern)
+		for _, op := range ops {
+			assert.Empty(t, ids[op.OperationID], "operation ID %q is used twice", op.OperationID)
+			ids[op.OperationID] = pattern
+		}
+	}
+}
+
+func Test_OpenAPIDocument(t *testing.T) {
+	mockService := &MockService{}
+	router := NewRouter(slog.Default(), mockService)
+	router.EnableAuth(testAdminKey)
+
+	// the spec does not need an API key
+	req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/openapi.json", http.NoBody)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var doc struct {
+		OpenAPI  string                               `json:"openapi"`
+		Paths    map[string]map[string]map[string]any `json:"paths"`
+		Security []map[string][]string                `json:"security"`
+	}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, "listTransactions", doc.Paths["/v1/addresses/{address}/transactions"]["get"]["operationId"])
+	assert.Equal(t, true, doc.Paths["/rules"]["post"]["deprecated"])
+	assert.Equal(t, 2, len(doc.Security))
+	mockService.AssertNotCalled(t, "Authenticate", mock.Anything)
+}
+
+func Test_RequestValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		contentType string
+		body        string
+		setup       func(m *MockService)
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:       "valid rules",
+			method:     "PUT",
+			url:        "/v1/subscriptions/address-1/rules",
+			body:       `{"rules":[{"direction":"in","minValue":"1 ether"}]}`,
+			setup:      func(m *MockService) { m.On("SetRules", "address-1", mock.Anything).Return(nil) },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid rules",
+			method:     "PUT",
+			url:        "/v1/subscriptions/address-1/rules",
+			body:       `{"rules":[{"direction":"up","failedOnly":"yes","extra":1}],"other":null}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request body does not match the schema","code":"invalid_request",` +
+				`"data":["body.other: is not allowed","body.rules[0].direction: must be one of [\"\" \"in\" \"out\"]","body.rules[0].extra: is not allowed","body.rules[0].failedOnly: must be a boolean"]}`,
+		},
+		{
+			name:       "legacy route without required field",
+			method:     "POST",
+			url:        "/subscribe",
+			body:       `{"label":"hot wallet"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"request body does not match the schema","data":["body.address: is required"]}`,
+		},
+		{
+			name:       "malformed JSON",
+			method:     "POST",
+			url:        "/v1/groups",
+			body:       `{"name":`,
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"type":"about:blank","title":"Bad Request","status":400,"detail":"request body does not match the schema","code":"invalid_request",` +
+				`"data":["body is not valid JSON"]}`,
+		},
+		{
+			name:        "CSV import is not validated",
+			method:      "POST",
+			url:         "/v1/subscriptions/import",
+			contentType: "text/csv",
+			body:        "address\n0x0000000000000000000000000000000000000001\n",
+			setup: func(m *MockService) {
+				m.On("ImportSubscriptions", mock.Anything).Return([]*domain.ImportResult{}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "empty optional body",
+			method:     "PUT",
+			url:        "/v1/subscriptions/address-1",
+			wantStatus: http.StatusOK,
+			setup: func(m *MockService) {
+				m.On("Subscribe").Return(true, nil)
+				m.On("GetSubscription", "address-1").Return(&domain.Subscription{Address: "address-1"}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			if tt.setup != nil {
+				tt.setup(mockService)
+			}
+			router := NewRouter(slog.Default(), mockService)
+			router.EnableRequestValidation()
+
+			req, _ := http.NewRequestWithContext(context.TODO(), tt.method, tt.url, bytes.NewBufferString(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody+"\n", rr.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	apiVersionHeader   = "API-Version"
+	apiVersion         = "v1"
+	apiVersionPrefix   = "/" + apiVersion + "/"
+	problemContentType = "application/problem+json"
+)
+
+// Problem is an RFC 7807 error body returned by the /v1 routes. Code is a
+// stable machine-readable error code, and Data has error specific details
+// like the position of an expression syntax error.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+	Data   any    `json:"data,omitempty"`
+}
+
+// error codes for failures without a more specific one
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:            "invalid_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusConflict:              "conflict",
+	http.StatusRequestEntityTooLarge: "request_too_large",
+	http.StatusTooManyRequests:       "rate_limited",
+}
+
+func isVersioned(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, apiVersionPrefix)
+}
+
+func (r *Router) writeProblem(resp Response, w http.ResponseWriter) {
+	code := resp.ErrorCode
+	if code == "" {
+		code = statusErrorCodes[resp.Code]
+	}
+	if code == "" {
+		code = "internal"
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(resp.Code)
+
+	err := json.NewEncoder(w).Encode(Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(resp.Code),
+		Status: resp.Code,
+		Detail: resp.Message,
+		Code:   code,
+		Data:   resp.Data,
+	})
+	if err != nil {
+		r.log.Error("failed to write problem response", "error", err)
+	}
+}
+
+// muxErrorWriter replaces the plain text "not found" and "method not
+// allowed" replies of the mux with problem details.
+type muxErrorWriter struct {
+	http.ResponseWriter
+	router *Router
+	wrote  bool
+}
+
+func (w *muxErrorWriter) WriteHeader(status int) {
+	w.wrote = true
+	w.router.writeProblem(Response{Message: http.StatusText(status), Code: status}, w.ResponseWriter)
+}
+
+func (w *muxErrorWriter) Write(b []byte) (int, error) {
+	if w.wrote {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// deprecated marks a legacy route and points clients at its /v1 successor.
+func deprecated(successor string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		h(w, req)
+	}
+}
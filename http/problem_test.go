@@ -0,0 +1,173 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"deshev.com/eth-address-watch/domain"
+	"deshev.com/eth-address-watch/expr"
+)
+
+func Test_V1Routes(t *testing.T) {
+	sub := &domain.Subscription{
+		Address:              "address-1",
+		SubscriptionMetadata: domain.SubscriptionMetadata{Label: "hot wallet"},
+		CreatedAt:            time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	ruleErr := fmt.Errorf("%w: expression: %w", domain.ErrInvalidRule, &expr.Error{Pos: 7, Msg: "unexpected end of expression"})
+
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		body        string
+		setup       func(m *MockService)
+		wantStatus  int
+		wantType    string
+		wantBody    string
+		wantHeaders map[string]string
+	}{
+		{
+			name:   "address transactions",
+			method: "GET",
+			url:    "/v1/addresses/address-1/transactions",
+			setup: func(m *MockService) {
+				m.On("GetTransactions").Return([]*domain.Transaction{{From: "address-1", To: "address-2"}})
+			},
+			wantStatus:  http.StatusOK,
+			wantType:    "application/json",
+			wantBody:    `{"data":[{"blockNumber":"","from":"address-1","to":"address-2"}]}`,
+			wantHeaders: map[string]string{apiVersionHeader: "v1"},
+		},
+		{
+			name:   "put subscription",
+			method: "PUT",
+			url:    "/v1/subscriptions/address-1",
+			body:   `{"label":"hot wallet"}`,
+			setup: func(m *MockService) {
+				m.On("Subscribe").Return(true, nil)
+				m.On("SetMetadata", "address-1", domain.SubscriptionMetadata{Label: "hot wallet"}).Return(nil)
+				m.On("GetSubscription", "address-1").Return(sub, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			wantBody:   `{"data":{"address":"address-1","label":"hot wallet","createdAt":"2024-01-02T03:04:05Z"}}`,
+		},
+		{
+			name:   "put subscription without body keeps metadata",
+			method: "PUT",
+			url:    "/v1/subscriptions/address-1",
+			setup: func(m *MockService) {
+				m.On("Subscribe").Return(true, nil)
+				m.On("GetSubscription", "address-1").Return(sub, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			wantBody:   `{"data":{"address":"address-1","label":"hot wallet","createdAt":"2024-01-02T03:04:05Z"}}`,
+		},
+		{
+			name:   "unknown subscription",
+			method: "GET",
+			url:    "/v1/subscriptions/address-2",
+			setup: func(m *MockService) {
+				m.On("GetSubscription", "address-2").Return(nil, domain.ErrNotSubscribed)
+			},
+			wantStatus: http.StatusNotFound,
+			wantType:   problemContentType,
+			wantBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"address not subscribed","code":"not_subscribed"}`,
+		},
+		{
+			name:   "delete subscription",
+			method: "DELETE",
+			url:    "/v1/subscriptions/address-1",
+			setup: func(m *MockService) {
+				m.On("Unsubscribe", "address-1").Return(nil)
+			},
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			wantBody:   `{"data":true}`,
+		},
+		{
+			name:   "invalid rule",
+			method: "PUT",
+			url:    "/v1/subscriptions/address-1/rules",
+			body:   `{"rules":[{"expression":"value >"}]}`,
+			setup: func(m *MockService) {
+				m.On("SetRules", "address-1", mock.Anything).Return(ruleErr)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantType:   problemContentType,
+			wantBody:   `{"type":"about:blank","title":"Bad Request","status":400,"detail":"` + ruleErr.Error() + `","code":"invalid_rule","data":{"position":7}}`,
+		},
+		{
+			name:       "method not allowed",
+			method:     "POST",
+			url:        "/v1/block",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantType:   problemContentType,
+			wantBody:   `{"type":"about:blank","title":"Method Not Allowed","status":405,"detail":"Method Not Allowed","code":"method_not_allowed"}`,
+		},
+		{
+			name:       "unknown route",
+			method:     "GET",
+			url:        "/v1/missing",
+			wantStatus: http.StatusNotFound,
+			wantType:   problemContentType,
+			wantBody:   `{"type":"about:blank","title":"Not Found","status":404,"detail":"Not Found","code":"not_found"}`,
+		},
+		{
+			name:   "deprecated route",
+			method: "GET",
+			url:    "/block",
+			setup: func(m *MockService) {
+				m.On("GetCurrentBlock").Return(1)
+			},
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			wantBody:   `{"data":1}`,
+			wantHeaders: map[string]string{
+				"Deprecation": "true",
+				"Link":        `</v1/block>; rel="successor-version"`,
+			},
+		},
+		{
+			name:       "deprecated route errors",
+			method:     "GET",
+			url:        "/transactions",
+			wantStatus: http.StatusBadRequest,
+			wantType:   "application/json",
+			wantBody:   `{"message":"required address field missing"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			if tt.setup != nil {
+				tt.setup(mockService)
+			}
+			router := NewRouter(slog.Default(), mockService)
+
+			req, _ := http.NewRequestWithContext(context.TODO(), tt.method, tt.url, bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantBody+"\n", rr.Body.String())
+			for name, value := range tt.wantHeaders {
+				assert.Equal(t, value, rr.Header().Get(name))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
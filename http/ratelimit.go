@@ -0,0 +1,45 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"deshev.com/eth-address-watch/ratelimit"
+)
+
+// quotaRetryAfter is the Retry-After of quota rejections in seconds. A quota
+// frees up when the tenant unsubscribes, so it is only a hint.
+const quotaRetryAfter = 60
+
+// SetRateLimiter limits every API key, or client IP for requests without a
+// valid key, with limiter, which the gRPC API shares.
+func (r *Router) SetRateLimiter(limiter *ratelimit.Limiter) {
+	r.limiter = limiter
+}
+
+// rateLimit replies with 429 and returns false when the client is over its limit.
+func (r *Router) rateLimit(w http.ResponseWriter, req *http.Request, authenticated bool) bool {
+	allowed, wait := r.limiter.Allow(rateLimitClient(req, authenticated))
+	if allowed {
+		return true
+	}
+
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	r.writeJSON(Response{
+		Message: "rate limit exceeded",
+		Code:    http.StatusTooManyRequests,
+		Data:    map[string]int{"retryAfter": retryAfter},
+	}, w)
+	return false
+}
+
+// rateLimitClient is the API key of authenticated requests and the client IP
+// of the others, as every made up key would otherwise get a bucket of its own.
+func rateLimitClient(req *http.Request, authenticated bool) string {
+	if key := apiKeyFromRequest(req); key != "" && authenticated {
+		return ratelimit.Key(key)
+	}
+	return ratelimit.IP(req.RemoteAddr)
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"deshev.com/eth-address-watch/domain"
+	"deshev.com/eth-address-watch/ratelimit"
+)
+
+func Test_RateLimit(t *testing.T) {
+	log := slog.Default()
+	mockService := new(MockService)
+	mockService.On("GetCurrentBlock").Return(1)
+	mockService.On("Authenticate", "key-1").Return("tenant-1", nil)
+	mockService.On("Authenticate", "key-2").Return("tenant-2", nil)
+	mockService.On("Authenticate", mock.Anything).Return("", domain.ErrUnauthorized)
+
+	router := NewRouter(log, mockService)
+	router.EnableAuth("admin-key")
+	router.SetRateLimiter(ratelimit.New(0.5, 1))
+
+	get := func(key string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/block", http.NoBody)
+		req.Header.Set(apiKeyHeader, key)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, get("key-1").Code)
+	assert.Equal(t, http.StatusOK, get("key-2").Code)
+
+	rr := get("key-1")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+	assert.Equal(t, `{"message":"rate limit exceeded","data":{"retryAfter":2}}`+"\n", rr.Body.String())
+
+	// keys that do not authenticate share the bucket of the client IP
+	assert.Equal(t, http.StatusUnauthorized, get("bogus-1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get("bogus-2").Code)
+}
+
+func Test_QuotaExceeded(t *testing.T) {
+	log := slog.Default()
+	mockService := new(MockService)
+	mockService.On("Subscribe").Return(false, domain.ErrQuotaExceeded)
+
+	router := NewRouter(log, mockService)
+	req, _ := http.NewRequestWithContext(context.TODO(), "POST", "/subscribe", bytes.NewBufferString(`{"address":"address-1"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	assert.Equal(t, `{"message":"quota exceeded"}`+"\n", rr.Body.String())
+}
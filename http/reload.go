@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/http"
+)
+
+const reloadPath = "/v1/admin/reload"
+
+// Reloader reloads the configuration and returns the keys that changed.
+type Reloader func() ([]string, error)
+
+// SetReloader enables reloading the configuration with POST
+// /v1/admin/reload.
+func (r *Router) SetReloader(reload Reloader) {
+	r.reload = reload
+}
+
+// Reload applies the current config file, environment and flags. It replies
+// with 409 and changes nothing when a value that needs a restart changed.
+func (r *Router) Reload(w http.ResponseWriter, _ *http.Request) {
+	if r.reload == nil {
+		r.writeJSON(Response{Message: "reload is not available", Code: http.StatusNotFound}, w)
+		return
+	}
+
+	changed, err := r.reload()
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: map[string][]string{"changed": changed}}, w)
+}
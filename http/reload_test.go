@@ -0,0 +1,54 @@
+package http
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"deshev.com/eth-address-watch/config"
+)
+
+func Test_Reload(t *testing.T) {
+	router := NewRouter(slog.Default(), &MockService{})
+	router.EnableAuth(testAdminKey)
+
+	post := func(key string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, reloadPath, nil)
+		req.Header.Set(apiKeyHeader, key)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := post(testAdminKey)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	var reloadErr error
+	router.SetReloader(func() ([]string, error) {
+		if reloadErr != nil {
+			return nil, reloadErr
+		}
+		return []string{"eth_node_url"}, nil
+	})
+
+	rr = post("tenant-key")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = post(testAdminKey)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":{"changed":["eth_node_url"]}}`, rr.Body.String())
+
+	reloadErr = fmt.Errorf("%w: http_port cannot change while running", config.ErrRestartRequired)
+	rr = post(testAdminKey)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Conflict","status":409,`+
+		`"detail":"restart required: http_port cannot change while running","code":"restart_required"}`, rr.Body.String())
+
+	reloadErr = fmt.Errorf("%w: config file read error", config.ErrInvalid)
+	rr = post(testAdminKey)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+}
@@ -10,11 +10,13 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	"deshev.com/eth-address-watch/domain"
+	"deshev.com/eth-address-watch/expr"
 )
 
 type MockService struct {
@@ -26,14 +28,188 @@ func (m *MockService) GetCurrentBlock() int {
 	return args.Int(0)
 }
 
-func (m *MockService) GetTransactions(address string) []*domain.Transaction {
+func (m *MockService) GetTransactions(_ context.Context, address string) []*domain.Transaction {
 	args := m.Called()
 	return args.Get(0).([]*domain.Transaction)
 }
 
-func (m *MockService) Subscribe(address string) bool {
+func (m *MockService) Subscribe(_ context.Context, address string) (bool, error) {
 	args := m.Called()
-	return args.Bool(0)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockService) PageTransactions(_ context.Context, address string, before, limit int) ([]*domain.IndexedTransaction, int, error) {
+	args := m.Called(address, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*domain.IndexedTransaction), args.Int(1), args.Error(2)
+}
+
+func (m *MockService) ScanTransactions(_ context.Context, address string, afterID, limit int) ([]*domain.IndexedTransaction, error) {
+	args := m.Called(address, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.IndexedTransaction), args.Error(1)
+}
+
+func (m *MockService) Unsubscribe(_ context.Context, address string) error {
+	args := m.Called(address)
+	return args.Error(0)
+}
+
+func (m *MockService) Listen(_ context.Context, address string, lastID int) ([]*domain.TransactionMatched, *domain.Subscriber, error) {
+	args := m.Called(address, lastID)
+	if args.Get(1) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).([]*domain.TransactionMatched), args.Get(1).(*domain.Subscriber), args.Error(2)
+}
+
+func (m *MockService) ListenBlocks() *domain.Subscriber {
+	args := m.Called()
+	return args.Get(0).(*domain.Subscriber)
+}
+
+func (m *MockService) GetSubscription(_ context.Context, address string) (*domain.Subscription, error) {
+	args := m.Called(address)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Subscription), args.Error(1)
+}
+
+func (m *MockService) GetRules(_ context.Context, address string) ([]*domain.Rule, error) {
+	args := m.Called(address)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Rule), args.Error(1)
+}
+
+func (m *MockService) SetRules(_ context.Context, address string, rules []*domain.Rule) error {
+	args := m.Called(address, rules)
+	return args.Error(0)
+}
+
+func (m *MockService) SetMetadata(_ context.Context, address string, meta domain.SubscriptionMetadata) error {
+	args := m.Called(address, meta)
+	return args.Error(0)
+}
+
+func (m *MockService) ListSubscriptions(_ context.Context, filter domain.SubscriptionFilter) []*domain.Subscription {
+	args := m.Called(filter)
+	return args.Get(0).([]*domain.Subscription)
+}
+
+func (m *MockService) ImportSubscriptions(_ context.Context, rows []*domain.SubscriptionImport) ([]*domain.ImportResult, error) {
+	args := m.Called(rows)
+	return args.Get(0).([]*domain.ImportResult), args.Error(1)
+}
+
+func (m *MockService) CreateGroup(_ context.Context, name string, members []string) (*domain.Group, error) {
+	args := m.Called(name, members)
+	return args.Get(0).(*domain.Group), args.Error(1)
+}
+
+func (m *MockService) DeleteGroup(_ context.Context, name string) error {
+	args := m.Called(name)
+	return args.Error(0)
+}
+
+func (m *MockService) GetGroup(_ context.Context, name string) (*domain.Group, error) {
+	args := m.Called(name)
+	return args.Get(0).(*domain.Group), args.Error(1)
+}
+
+func (m *MockService) ListGroups(_ context.Context) []*domain.Group {
+	args := m.Called()
+	return args.Get(0).([]*domain.Group)
+}
+
+func (m *MockService) UpdateGroupMembers(_ context.Context, name string, add, remove []string) (*domain.Group, error) {
+	args := m.Called(name, add, remove)
+	return args.Get(0).(*domain.Group), args.Error(1)
+}
+
+func (m *MockService) GetGroupTransactions(_ context.Context, name string) ([]*domain.GroupTransaction, error) {
+	args := m.Called(name)
+	return args.Get(0).([]*domain.GroupTransaction), args.Error(1)
+}
+
+func (m *MockService) CreateAPIKey(tenant, name string) (*domain.APIKey, string, error) {
+	args := m.Called(tenant, name)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).(*domain.APIKey), args.String(1), args.Error(2)
+}
+
+func (m *MockService) Authenticate(key string) (string, error) {
+	args := m.Called(key)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockService) ListAPIKeys() []*domain.APIKey {
+	args := m.Called()
+	return args.Get(0).([]*domain.APIKey)
+}
+
+func (m *MockService) RevokeAPIKey(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockService) Backfill(_ context.Context, address string, from, to int) (*domain.BackfillResult, error) {
+	args := m.Called(address, from, to)
+	result, _ := args.Get(0).(*domain.BackfillResult)
+	return result, args.Error(1)
+}
+
+func (m *MockService) Replay(_ context.Context, address string) (int, error) {
+	args := m.Called(address)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockService) GetBalance(_ context.Context, address string, block int) (*domain.Balance, error) {
+	args := m.Called(address, block)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Balance), args.Error(1)
+}
+
+func (m *MockService) GetBalanceHistory(_ context.Context, address string, from, to int) ([]*domain.BalancePoint, error) {
+	args := m.Called(address, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.BalancePoint), args.Error(1)
+}
+
+func (m *MockService) SubscribeName(_ context.Context, name string) (*domain.Subscription, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Subscription), args.Error(1)
+}
+
+func (m *MockService) GetTokenBalances(_ context.Context, address string) ([]*domain.TokenBalance, error) {
+	args := m.Called(address)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.TokenBalance), args.Error(1)
+}
+
+func (m *MockService) GetTokenBalanceHistory(_ context.Context, address, token string, from, to int) ([]*domain.TokenBalancePoint, error) {
+	args := m.Called(address, token, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.TokenBalancePoint), args.Error(1)
 }
 
 func Test_GetBlock(t *testing.T) {
@@ -55,6 +231,26 @@ func Test_GetBlock(t *testing.T) {
 	assert.Equal(t, 1.0, parsedBody.Data)
 }
 
+func Test_GraphQL(t *testing.T) {
+	log := slog.Default()
+
+	mockService := new(MockService)
+	mockService.On("GetCurrentBlock").Return(1)
+	mockService.On("PageTransactions", "address-1", 0, 3).Return([]*domain.IndexedTransaction{
+		{ID: 1, Transaction: &domain.Transaction{Hash: "0xa"}},
+	}, 1, nil)
+
+	router := NewRouter(log, mockService)
+
+	body := `{"query":"{ block { number } transactions(address: \"address-1\", first: 2) { nodes { id hash } } }"}`
+	req, _ := http.NewRequestWithContext(context.TODO(), "POST", "/graphql", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":{"block":{"number":1},"transactions":{"nodes":[{"id":1,"hash":"0xa"}]}}}`, rr.Body.String())
+}
+
 func Test_GeTransactions(t *testing.T) {
 	log := slog.Default()
 
@@ -149,6 +345,12 @@ func Test_Subscribe(t *testing.T) {
 			wantStatus:  http.StatusOK,
 			wantResult:  true,
 		},
+		{
+			name:        "subscription with metadata",
+			requestBody: `{"address":"address-1","label":"hot wallet","tags":["exchange"],"metadata":{"id":7}}`,
+			wantStatus:  http.StatusOK,
+			wantResult:  true,
+		},
 		{
 			name:        "invalid json",
 			requestBody: "",
@@ -168,7 +370,12 @@ func Test_Subscribe(t *testing.T) {
 			log := slog.Default()
 
 			mockService := &MockService{}
-			mockService.On("Subscribe").Return(tt.wantResult)
+			mockService.On("Subscribe").Return(tt.wantResult, nil)
+			mockService.On("SetMetadata", "address-1", domain.SubscriptionMetadata{
+				Label:    "hot wallet",
+				Tags:     []string{"exchange"},
+				Metadata: json.RawMessage(`{"id":7}`),
+			}).Return(nil)
 
 			router := NewRouter(log, mockService)
 
@@ -191,3 +398,227 @@ func Test_Subscribe(t *testing.T) {
 		})
 	}
 }
+
+func Test_SubscribeName(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockService := &MockService{}
+	mockService.On("SubscribeName", "vitalik.eth").Return(&domain.Subscription{Address: "0xd8da", Name: "vitalik.eth", CreatedAt: created}, nil)
+	mockService.On("SetMetadata", "0xd8da", domain.SubscriptionMetadata{Label: "vitalik"}).Return(nil)
+	mockService.On("GetSubscription", "0xd8da").Return(&domain.Subscription{
+		Address: "0xd8da", Name: "vitalik.eth", CreatedAt: created, SubscriptionMetadata: domain.SubscriptionMetadata{Label: "vitalik"},
+	}, nil)
+	mockService.On("SubscribeName", "nobody.eth").Return(nil, domain.ErrNameNotFound)
+	router := NewRouter(slog.Default(), mockService)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/subscribe", strings.NewReader(`{"address":"vitalik.eth"}`)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":{"address":"0xd8da","name":"vitalik.eth","createdAt":"2024-01-02T03:04:05Z"}}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/subscribe", strings.NewReader(`{"address":"vitalik.eth","label":"vitalik"}`)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":{"address":"0xd8da","name":"vitalik.eth","label":"vitalik","createdAt":"2024-01-02T03:04:05Z"}}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/subscribe", strings.NewReader(`{"address":"nobody.eth"}`)))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func Test_GetRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		address    string
+		rules      []*domain.Rule
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "subscribed address",
+			address:    "address-1",
+			rules:      []*domain.Rule{{MinValue: "1 ether", Direction: domain.DirectionIn}},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":[{"minValue":"1 ether","direction":"in"}]}`,
+		},
+		{
+			name:       "not subscribed",
+			address:    "address-2",
+			err:        domain.ErrNotSubscribed,
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"message":"address not subscribed"}`,
+		},
+		{
+			name:       "missing address",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"required address field missing"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockService.On("GetRules", tt.address).Return(tt.rules, tt.err)
+
+			router := NewRouter(slog.Default(), mockService)
+
+			req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/rules?address="+tt.address, http.NoBody)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.JSONEq(t, tt.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func Test_SetRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		requestBody string
+		err         error
+		wantStatus  int
+		wantError   string
+		wantData    any
+	}{
+		{
+			name:        "valid rules",
+			method:      "PUT",
+			requestBody: `{"address":"address-1","rules":[{"maxValue":"2 ether","method":"0xa9059cbb"}]}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "rejected by validation",
+			method:      "PUT",
+			requestBody: `{"address":"address-1","rules":[{"maxValue":"2 apples"}]}`,
+			err:         fmt.Errorf("rule 0: %w: maxValue: unknown unit", domain.ErrInvalidRule),
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "rule 0: invalid rule: maxValue: unknown unit",
+		},
+		{
+			name:        "expression syntax error",
+			method:      "PUT",
+			requestBody: `{"address":"address-1","rules":[{"expression":"value >"}]}`,
+			err: fmt.Errorf("rule 0: %w: expression: %w", domain.ErrInvalidRule,
+				&expr.Error{Pos: 8, Msg: "unexpected end of expression"}),
+			wantStatus: http.StatusBadRequest,
+			wantError:  "rule 0: invalid rule: expression: position 8: unexpected end of expression",
+			wantData:   map[string]any{"position": 8.0},
+		},
+		{
+			name:        "unknown rule field",
+			method:      "POST",
+			requestBody: `{"address":"address-1","rules":[{"minimum":"2 ether"}]}`,
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "invalid rules request",
+		},
+		{
+			name:        "missing rules",
+			method:      "PUT",
+			requestBody: `{"address":"address-1"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "invalid rules request",
+		},
+		{
+			name:       "unsupported method",
+			method:     "DELETE",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantError:  "method not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockService.On("SetRules", "address-1", mock.Anything).Return(tt.err)
+
+			router := NewRouter(slog.Default(), mockService)
+
+			body := bytes.NewBufferString(tt.requestBody)
+			req, _ := http.NewRequestWithContext(context.TODO(), tt.method, "/rules", body)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			var parsedBody Response
+			err := json.NewDecoder(rr.Body).Decode(&parsedBody)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantError, parsedBody.Message)
+			if tt.wantError != "" {
+				assert.Equal(t, tt.wantData, parsedBody.Data)
+			}
+		})
+	}
+}
+
+func Test_ListSubscriptions(t *testing.T) {
+	mockService := &MockService{}
+	mockService.On("ListSubscriptions", domain.SubscriptionFilter{Tag: "exchange", Owner: "team-a"}).
+		Return([]*domain.Subscription{
+			{
+				Address:              "address-1",
+				SubscriptionMetadata: domain.SubscriptionMetadata{Label: "hot wallet", Tags: []string{"exchange"}},
+				CreatedAt:            time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		})
+
+	router := NewRouter(slog.Default(), mockService)
+
+	req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/subscriptions?tag=exchange&owner=team-a", http.NoBody)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":[{"address":"address-1","label":"hot wallet","tags":["exchange"],`+
+		`"createdAt":"2024-01-02T03:04:05Z"}]}`, rr.Body.String())
+}
+
+func Test_SetMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		requestBody string
+		err         error
+		wantStatus  int
+		wantError   string
+	}{
+		{
+			name:        "update metadata",
+			requestBody: `{"address":"address-1","owner":"team-a"}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "not subscribed",
+			requestBody: `{"address":"address-1","owner":"team-a"}`,
+			err:         domain.ErrNotSubscribed,
+			wantStatus:  http.StatusNotFound,
+			wantError:   "address not subscribed",
+		},
+		{
+			name:        "unknown field",
+			requestBody: `{"address":"address-1","colour":"red"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "invalid subscription request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockService.On("SetMetadata", "address-1", domain.SubscriptionMetadata{Owner: "team-a"}).Return(tt.err)
+
+			router := NewRouter(slog.Default(), mockService)
+
+			body := bytes.NewBufferString(tt.requestBody)
+			req, _ := http.NewRequestWithContext(context.TODO(), "PUT", "/subscriptions", body)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			var parsedBody Response
+			err := json.NewDecoder(rr.Body).Decode(&parsedBody)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantError, parsedBody.Message)
+		})
+	}
+}
@@ -1,17 +1,58 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/gorilla/websocket"
+
+	"deshev.com/eth-address-watch/config"
 	"deshev.com/eth-address-watch/domain"
+	"deshev.com/eth-address-watch/expr"
+	"deshev.com/eth-address-watch/graphql"
+	"deshev.com/eth-address-watch/health"
+	"deshev.com/eth-address-watch/metrics"
+	"deshev.com/eth-address-watch/ratelimit"
 )
 
 type Service interface {
 	GetCurrentBlock() int
-	GetTransactions(address string) []*domain.Transaction
-	Subscribe(address string) bool
+	GetTransactions(ctx context.Context, address string) []*domain.Transaction
+	PageTransactions(ctx context.Context, address string, before, limit int) ([]*domain.IndexedTransaction, int, error)
+	ScanTransactions(ctx context.Context, address string, afterID, limit int) ([]*domain.IndexedTransaction, error)
+	Subscribe(ctx context.Context, address string) (bool, error)
+	SubscribeName(ctx context.Context, name string) (*domain.Subscription, error)
+	Unsubscribe(ctx context.Context, address string) error
+	Listen(ctx context.Context, address string, lastID int) ([]*domain.TransactionMatched, *domain.Subscriber, error)
+	ListenBlocks() *domain.Subscriber
+	GetSubscription(ctx context.Context, address string) (*domain.Subscription, error)
+	GetRules(ctx context.Context, address string) ([]*domain.Rule, error)
+	SetRules(ctx context.Context, address string, rules []*domain.Rule) error
+	SetMetadata(ctx context.Context, address string, meta domain.SubscriptionMetadata) error
+	ListSubscriptions(ctx context.Context, filter domain.SubscriptionFilter) []*domain.Subscription
+	ImportSubscriptions(ctx context.Context, rows []*domain.SubscriptionImport) ([]*domain.ImportResult, error)
+	CreateGroup(ctx context.Context, name string, members []string) (*domain.Group, error)
+	DeleteGroup(ctx context.Context, name string) error
+	GetGroup(ctx context.Context, name string) (*domain.Group, error)
+	ListGroups(ctx context.Context) []*domain.Group
+	UpdateGroupMembers(ctx context.Context, name string, add, remove []string) (*domain.Group, error)
+	GetGroupTransactions(ctx context.Context, name string) ([]*domain.GroupTransaction, error)
+	CreateAPIKey(tenant, name string) (*domain.APIKey, string, error)
+	Authenticate(key string) (string, error)
+	ListAPIKeys() []*domain.APIKey
+	RevokeAPIKey(id string) error
+	Backfill(ctx context.Context, address string, from, to int) (*domain.BackfillResult, error)
+	Replay(ctx context.Context, address string) (int, error)
+	GetBalance(ctx context.Context, address string, block int) (*domain.Balance, error)
+	GetBalanceHistory(ctx context.Context, address string, from, to int) ([]*domain.BalancePoint, error)
+	GetTokenBalances(ctx context.Context, address string) ([]*domain.TokenBalance, error)
+	GetTokenBalanceHistory(ctx context.Context, address, token string, from, to int) ([]*domain.TokenBalancePoint, error)
 }
 
 type Router struct {
@@ -19,12 +60,27 @@ type Router struct {
 
 	log     *slog.Logger
 	service Service
+
+	heartbeatInterval time.Duration
+	upgrader          websocket.Upgrader
+
+	authEnabled      bool
+	adminKey         string
+	limiter          *ratelimit.Limiter
+	validateRequests bool
+	graphql          *graphql.Handler
+	readiness        *health.Checker
+	reload           Reloader
+	// patterns are the registered routes, see OpenAPI
+	patterns []string
 }
 
 type Response struct {
 	Message string `json:"message,omitempty"`
 	Code    int    `json:"-"`
 	Data    any    `json:"data,omitempty"`
+	// ErrorCode is the machine-readable code of /v1 error responses
+	ErrorCode string `json:"-"`
 }
 
 func NewRouter(log *slog.Logger, s Service) *Router {
@@ -34,15 +90,127 @@ func NewRouter(log *slog.Logger, s Service) *Router {
 		ServeMux: mux,
 		log:      log,
 		service:  s,
+
+		heartbeatInterval: streamHeartbeatInterval,
+		graphql:           graphql.NewHandler(log, s),
 	}
 
-	mux.HandleFunc("/block", r.GetBlock)
-	mux.HandleFunc("/transactions", r.GetTransactions)
-	mux.HandleFunc("/subscribe", r.Subscribe)
+	r.handle("GET /v1/block", r.GetBlock)
+	r.handle("GET /v1/addresses/{address}/transactions", r.GetTransactions)
+	r.handle("GET /v1/addresses/{address}/transactions/export", r.ExportTransactions)
+	r.handle("GET /v1/addresses/{address}/balance", r.GetBalance)
+	r.handle("GET /v1/addresses/{address}/balance/history", r.GetBalanceHistory)
+	r.handle("GET /v1/addresses/{address}/tokens", r.GetTokenBalances)
+	r.handle("GET /v1/addresses/{address}/tokens/{token}/history", r.GetTokenBalanceHistory)
+	r.handle("GET /v1/addresses/{address}/stream", r.Stream)
+	r.handle("GET /v1/ws", r.WebSocket)
+	r.handle("GET /v1/subscriptions", r.listSubscriptions)
+	r.handle("GET /v1/subscriptions/{address}", r.getSubscription)
+	r.handle("PUT /v1/subscriptions/{address}", r.putSubscription)
+	r.handle("DELETE /v1/subscriptions/{address}", r.deleteSubscription)
+	r.handle("GET /v1/subscriptions/{address}/rules", r.getRules)
+	r.handle("PUT /v1/subscriptions/{address}/rules", r.setRules)
+	r.handle("POST /v1/subscriptions/{address}/backfill", r.Backfill)
+	r.handle("POST /v1/subscriptions/{address}/replay", r.Replay)
+	r.handle("POST /v1/subscriptions/import", r.ImportSubscriptions)
+	r.handle("GET /v1/subscriptions/export", r.ExportSubscriptions)
+	r.handle("GET /v1/groups", r.listGroups)
+	r.handle("POST /v1/groups", r.createGroup)
+	r.handle("GET /v1/groups/{name}", r.getGroup)
+	r.handle("DELETE /v1/groups/{name}", r.deleteGroup)
+	r.handle("POST /v1/groups/{name}/members", r.updateGroupMembers)
+	r.handle("GET /v1/groups/{name}/transactions", r.GroupTransactions)
+	r.handle("GET /v1/groups/{name}/transactions/export", r.ExportGroupTransactions)
+	r.handle("GET /v1/admin/keys", r.listAPIKeys)
+	r.handle("POST /v1/admin/keys", r.createAPIKey)
+	r.handle("DELETE /v1/admin/keys/{id}", r.revokeAPIKey)
+	r.handle("POST "+reloadPath, r.Reload)
+	r.handle("GET "+openAPIPath, r.GetOpenAPI)
+	r.handle("GET /graphql", r.graphql.ServeHTTP)
+	r.handle("POST /graphql", r.graphql.ServeHTTP)
+	r.handle("GET "+metricsPath, metrics.Default.Handler())
+	r.handle("GET "+healthzPath, r.GetHealth)
+	r.handle("GET "+readyzPath, r.GetReadiness)
+
+	// deprecated unversioned routes
+	r.handle("/block", deprecated("/v1/block", r.GetBlock))
+	r.handle("/transactions", deprecated("/v1/addresses/{address}/transactions", r.GetTransactions))
+	r.handle("/subscribe", deprecated("/v1/subscriptions/{address}", r.Subscribe))
+	r.handle("/stream", deprecated("/v1/addresses/{address}/stream", r.Stream))
+	r.handle("/ws", deprecated("/v1/ws", r.WebSocket))
+	r.handle("/rules", deprecated("/v1/subscriptions/{address}/rules", r.Rules))
+	r.handle("/subscriptions", deprecated("/v1/subscriptions", r.Subscriptions))
+	r.handle("/subscriptions/import", deprecated("/v1/subscriptions/import", r.ImportSubscriptions))
+	r.handle("/subscriptions/export", deprecated("/v1/subscriptions/export", r.ExportSubscriptions))
+	r.handle("/groups", deprecated("/v1/groups", r.Groups))
+	r.handle("/groups/members", deprecated("/v1/groups/{name}/members", r.GroupMembers))
+	r.handle("/groups/transactions", deprecated("/v1/groups/{name}/transactions", r.GroupTransactions))
+	r.handle("/admin/keys", deprecated("/v1/admin/keys", r.APIKeys))
 
 	return r
 }
 
+// SetGraphQLLimits replaces the complexity and depth limits of /graphql.
+func (r *Router) SetGraphQLLimits(limits graphql.Limits) {
+	r.graphql.SetLimits(limits)
+}
+
+// ServeHTTP applies rate limiting, authentication and request validation
+// before routing, and records request metrics and a span.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	route := r.route(req)
+	req, span := startSpan(req, route)
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		endSpan(span, rec.status)
+		observeRequest(route, rec.status, time.Since(start))
+	}()
+	w = rec
+
+	if isVersioned(req) {
+		w.Header().Set(apiVersionHeader, apiVersion)
+	}
+	probe := isProbePath(req.URL.Path)
+	// the rate limit applies to failed authentication as well, so the key is
+	// checked first and the error written after
+	authenticated := false
+	var authErr error
+	if r.authEnabled && !probe && req.URL.Path != openAPIPath {
+		var authReq *http.Request
+		if authReq, authErr = r.authenticate(req); authErr == nil {
+			req = authReq
+			authenticated = true
+		}
+	}
+	if r.limiter != nil && !probe && !r.rateLimit(w, req, authenticated) {
+		return
+	}
+	if authErr != nil {
+		r.writeError(authErr, w)
+		return
+	}
+	if r.validateRequests && !r.validateRequest(w, req) {
+		return
+	}
+
+	if isVersioned(req) {
+		if _, pattern := r.ServeMux.Handler(req); pattern == "" {
+			w = &muxErrorWriter{ResponseWriter: w, router: r}
+		}
+	}
+	r.ServeMux.ServeHTTP(w, req)
+}
+
+// param reads a path parameter of the /v1 routes or, for the deprecated
+// routes, the query parameter with the same name.
+func param(req *http.Request, name string) string {
+	if value := req.PathValue(name); value != "" {
+		return value
+	}
+	return req.URL.Query().Get(name)
+}
+
 func (r *Router) GetBlock(w http.ResponseWriter, req *http.Request) {
 	resp := Response{
 		Data: r.service.GetCurrentBlock(),
@@ -51,7 +219,7 @@ func (r *Router) GetBlock(w http.ResponseWriter, req *http.Request) {
 }
 
 func (r *Router) GetTransactions(w http.ResponseWriter, req *http.Request) {
-	address := req.URL.Query().Get("address")
+	address := param(req, "address")
 	if address == "" {
 		resp := Response{
 			Message: "required address field missing",
@@ -62,7 +230,7 @@ func (r *Router) GetTransactions(w http.ResponseWriter, req *http.Request) {
 	}
 
 	resp := Response{
-		Data: r.service.GetTransactions(address),
+		Data: r.service.GetTransactions(req.Context(), address),
 	}
 	r.writeJSON(resp, w)
 }
@@ -70,6 +238,7 @@ func (r *Router) GetTransactions(w http.ResponseWriter, req *http.Request) {
 func (r *Router) Subscribe(w http.ResponseWriter, req *http.Request) {
 	var body struct {
 		Address string `json:"address"`
+		domain.SubscriptionMetadata
 	}
 	err := json.NewDecoder(req.Body).Decode(&body)
 	if err != nil || body.Address == "" {
@@ -81,15 +250,294 @@ func (r *Router) Subscribe(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// ENS names answer with the subscription, which has the address
+	var subscribed any
+	address := body.Address
+	if domain.IsName(body.Address) {
+		sub, err := r.service.SubscribeName(req.Context(), body.Address)
+		if err != nil {
+			r.writeError(err, w)
+			return
+		}
+		subscribed, address = sub, sub.Address
+	} else {
+		ok, err := r.service.Subscribe(req.Context(), body.Address)
+		if err != nil {
+			r.writeError(err, w)
+			return
+		}
+		subscribed = ok
+	}
+	if hasMetadata(body.SubscriptionMetadata) {
+		if err := r.service.SetMetadata(req.Context(), address, body.SubscriptionMetadata); err != nil {
+			r.writeError(err, w)
+			return
+		}
+		if _, isName := subscribed.(*domain.Subscription); isName {
+			if subscribed, err = r.service.GetSubscription(req.Context(), address); err != nil {
+				r.writeError(err, w)
+				return
+			}
+		}
+	}
+
 	resp := Response{
-		Data: r.service.Subscribe(body.Address),
+		Data: subscribed,
 	}
 	r.writeJSON(resp, w)
 }
 
-func (r *Router) writeJSON(resp Response, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json")
+// Subscriptions lists subscriptions filtered by tag and owner (GET) or
+// replaces the metadata of one (PUT).
+func (r *Router) Subscriptions(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		r.listSubscriptions(w, req)
+	case http.MethodPut:
+		r.setMetadata(w, req)
+	default:
+		resp := Response{
+			Message: "method not allowed",
+			Code:    http.StatusMethodNotAllowed,
+		}
+		r.writeJSON(resp, w)
+	}
+}
+
+func (r *Router) listSubscriptions(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	resp := Response{
+		Data: r.service.ListSubscriptions(req.Context(), domain.SubscriptionFilter{
+			Tag:   query.Get("tag"),
+			Owner: query.Get("owner"),
+		}),
+	}
+	r.writeJSON(resp, w)
+}
+
+func (r *Router) getSubscription(w http.ResponseWriter, req *http.Request) {
+	sub, err := r.service.GetSubscription(req.Context(), req.PathValue("address"))
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: sub}, w)
+}
+
+// putSubscription subscribes the address if needed and replaces its
+// metadata. Without a body the metadata of existing subscriptions is kept.
+func (r *Router) putSubscription(w http.ResponseWriter, req *http.Request) {
+	// meta stays nil without a body
+	var meta *domain.SubscriptionMetadata
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&meta); err != nil && !errors.Is(err, io.EOF) {
+		r.writeJSON(Response{Message: "invalid subscription request", Code: http.StatusBadRequest}, w)
+		return
+	}
 
+	address := req.PathValue("address")
+	if _, err := r.service.Subscribe(req.Context(), address); err != nil {
+		r.writeError(err, w)
+		return
+	}
+	if meta != nil {
+		if err := r.service.SetMetadata(req.Context(), address, *meta); err != nil {
+			r.writeError(err, w)
+			return
+		}
+	}
+	r.getSubscription(w, req)
+}
+
+func (r *Router) deleteSubscription(w http.ResponseWriter, req *http.Request) {
+	if err := r.service.Unsubscribe(req.Context(), req.PathValue("address")); err != nil {
+		r.writeError(err, w)
+		return
+	}
+	r.writeJSON(Response{Data: true}, w)
+}
+
+func (r *Router) setMetadata(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Address string `json:"address"`
+		domain.SubscriptionMetadata
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&body)
+	if err != nil || body.Address == "" {
+		resp := Response{
+			Message: "invalid subscription request",
+			Code:    http.StatusBadRequest,
+		}
+		r.writeJSON(resp, w)
+		return
+	}
+
+	if err := r.service.SetMetadata(req.Context(), body.Address, body.SubscriptionMetadata); err != nil {
+		r.writeError(err, w)
+		return
+	}
+
+	resp := Response{
+		Data: body.SubscriptionMetadata,
+	}
+	r.writeJSON(resp, w)
+}
+
+func hasMetadata(meta domain.SubscriptionMetadata) bool {
+	return meta.Label != "" || len(meta.Tags) > 0 || meta.Owner != "" || len(meta.Metadata) > 0
+}
+
+// Rules lists (GET) or replaces (PUT/POST) the alert rules of a subscription.
+func (r *Router) Rules(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		r.getRules(w, req)
+	case http.MethodPut, http.MethodPost:
+		r.setRules(w, req)
+	default:
+		resp := Response{
+			Message: "method not allowed",
+			Code:    http.StatusMethodNotAllowed,
+		}
+		r.writeJSON(resp, w)
+	}
+}
+
+func (r *Router) getRules(w http.ResponseWriter, req *http.Request) {
+	address := param(req, "address")
+	if address == "" {
+		resp := Response{
+			Message: "required address field missing",
+			Code:    http.StatusBadRequest,
+		}
+		r.writeJSON(resp, w)
+		return
+	}
+
+	rules, err := r.service.GetRules(req.Context(), address)
+	if err != nil {
+		r.writeError(err, w)
+		return
+	}
+
+	resp := Response{
+		Data: rules,
+	}
+	r.writeJSON(resp, w)
+}
+
+func (r *Router) setRules(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Address string         `json:"address"`
+		Rules   []*domain.Rule `json:"rules"`
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&body)
+	if address := req.PathValue("address"); address != "" {
+		body.Address = address
+	}
+	if err != nil || body.Address == "" || body.Rules == nil {
+		resp := Response{
+			Message: "invalid rules request",
+			Code:    http.StatusBadRequest,
+		}
+		r.writeJSON(resp, w)
+		return
+	}
+
+	if err := r.service.SetRules(req.Context(), body.Address, body.Rules); err != nil {
+		r.writeError(err, w)
+		return
+	}
+
+	resp := Response{
+		Data: body.Rules,
+	}
+	r.writeJSON(resp, w)
+}
+
+// writeError maps domain errors to HTTP status codes.
+func (r *Router) writeError(err error, w http.ResponseWriter) {
+	resp := Response{
+		Message: err.Error(),
+	}
+	switch {
+	case errors.Is(err, domain.ErrUnauthorized):
+		resp.Code = http.StatusUnauthorized
+		resp.ErrorCode = "unauthorized"
+	case errors.Is(err, domain.ErrQuotaExceeded):
+		resp.Code = http.StatusTooManyRequests
+		resp.ErrorCode = "quota_exceeded"
+		w.Header().Set("Retry-After", strconv.Itoa(quotaRetryAfter))
+	case errors.Is(err, domain.ErrNotSubscribed):
+		resp.Code = http.StatusNotFound
+		resp.ErrorCode = "not_subscribed"
+	case errors.Is(err, domain.ErrGroupNotFound):
+		resp.Code = http.StatusNotFound
+		resp.ErrorCode = "group_not_found"
+	case errors.Is(err, domain.ErrAPIKeyNotFound):
+		resp.Code = http.StatusNotFound
+		resp.ErrorCode = "api_key_not_found"
+	case errors.Is(err, domain.ErrGroupExists):
+		resp.Code = http.StatusConflict
+		resp.ErrorCode = "group_exists"
+	case errors.Is(err, domain.ErrInvalidGroup):
+		resp.Code = http.StatusBadRequest
+		resp.ErrorCode = "invalid_group"
+	case errors.Is(err, domain.ErrInvalidTenant):
+		resp.Code = http.StatusBadRequest
+		resp.ErrorCode = "invalid_tenant"
+	case errors.Is(err, domain.ErrInvalidRange):
+		resp.Code = http.StatusBadRequest
+		resp.ErrorCode = "invalid_range"
+	case errors.Is(err, domain.ErrBackfillUnavailable):
+		resp.Code = http.StatusNotImplemented
+		resp.ErrorCode = "backfill_unavailable"
+	case errors.Is(err, domain.ErrNodeUnavailable):
+		resp.Code = http.StatusBadGateway
+		resp.ErrorCode = "node_unavailable"
+	case errors.Is(err, domain.ErrBalanceUnavailable):
+		resp.Code = http.StatusNotImplemented
+		resp.ErrorCode = "balance_unavailable"
+	case errors.Is(err, domain.ErrBalancePending):
+		resp.Code = http.StatusServiceUnavailable
+		resp.ErrorCode = "balance_pending"
+	case errors.Is(err, domain.ErrUnknownToken):
+		resp.Code = http.StatusNotFound
+		resp.ErrorCode = "unknown_token"
+	case errors.Is(err, domain.ErrNameNotFound):
+		resp.Code = http.StatusNotFound
+		resp.ErrorCode = "name_not_found"
+	case errors.Is(err, domain.ErrNamesUnavailable):
+		resp.Code = http.StatusNotImplemented
+		resp.ErrorCode = "names_unavailable"
+	case errors.Is(err, config.ErrRestartRequired):
+		resp.Code = http.StatusConflict
+		resp.ErrorCode = "restart_required"
+	case errors.Is(err, config.ErrInvalid):
+		resp.Code = http.StatusUnprocessableEntity
+		resp.ErrorCode = "invalid_config"
+	case errors.Is(err, domain.ErrInvalidRule):
+		resp.Code = http.StatusBadRequest
+		resp.ErrorCode = "invalid_rule"
+		var exprErr *expr.Error
+		if errors.As(err, &exprErr) {
+			resp.Data = map[string]int{"position": exprErr.Pos}
+		}
+	default:
+		r.log.Error("request failed", "error", err)
+		resp.Message = "internal error"
+	}
+	r.writeJSON(resp, w)
+}
+
+// writeJSON writes the response, or problem details for errors on the /v1
+// routes.
+func (r *Router) writeJSON(resp Response, w http.ResponseWriter) {
 	if resp.Code == 0 {
 		if resp.Message != "" {
 			resp.Code = http.StatusInternalServerError
@@ -97,6 +545,12 @@ func (r *Router) writeJSON(resp Response, w http.ResponseWriter) {
 			resp.Code = http.StatusOK
 		}
 	}
+	if resp.Code >= http.StatusBadRequest && w.Header().Get(apiVersionHeader) == apiVersion {
+		r.writeProblem(resp, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(resp.Code)
 
 	err := json.NewEncoder(w).Encode(resp)
@@ -5,36 +5,56 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"time"
 
+	"deshev.com/eth-address-watch/config"
 	"deshev.com/eth-address-watch/domain"
-)
-
-const (
-	port        = 9000
-	httpTimeout = 10 * time.Second
+	"deshev.com/eth-address-watch/graphql"
+	"deshev.com/eth-address-watch/health"
+	"deshev.com/eth-address-watch/ratelimit"
 )
 
 type Server struct {
-	log  *slog.Logger
-	http *http.Server
+	log    *slog.Logger
+	http   *http.Server
+	router *Router
 }
 
-func NewServer(log *slog.Logger, s *domain.Service) *Server {
+// NewServer serves the API of s. limiter is nil without rate limiting.
+func NewServer(log *slog.Logger, cfg *config.Config, s *domain.Service, readiness *health.Checker, limiter *ratelimit.Limiter) *Server {
 	r := NewRouter(log, s)
+	r.SetReadiness(readiness)
+	r.heartbeatInterval = cfg.StreamHeartbeatInterval
+	if cfg.AdminAPIKey != "" {
+		r.EnableAuth(cfg.AdminAPIKey)
+	} else {
+		log.Warn("ADMIN_API_KEY is not set, the API is not authenticated")
+	}
+	if cfg.ValidateRequests {
+		r.EnableRequestValidation()
+	}
+	r.SetGraphQLLimits(graphql.Limits{MaxComplexity: cfg.GraphQLMaxComplexity, MaxDepth: cfg.GraphQLMaxDepth})
+	if limiter != nil {
+		r.SetRateLimiter(limiter)
+	}
 	return &Server{
-		log: log,
+		log:    log,
+		router: r,
 		http: &http.Server{
-			Addr:         fmt.Sprintf(":%d", port),
+			Addr:         fmt.Sprintf(":%d", cfg.HTTPPort),
 			Handler:      r,
-			ReadTimeout:  httpTimeout,
-			WriteTimeout: httpTimeout,
+			ReadTimeout:  cfg.HTTPTimeout,
+			WriteTimeout: cfg.HTTPTimeout,
 		},
 	}
 }
 
+// SetReloader enables the config reload endpoint.
+func (s *Server) SetReloader(reload Reloader) {
+	s.router.SetReloader(reload)
+}
+
 func (s *Server) Start(ctx context.Context) error {
-	s.log.Info("starting http server", "port", port)
+	s.log.Info("starting http server", "addr", s.http.Addr)
 	errCh := make(chan error, 1)
 	go func() {
 		err := s.http.ListenAndServe()
@@ -56,6 +76,6 @@ func (s *Server) Stop() {
 	s.log.Info("stopping http server")
 	err := s.http.Shutdown(context.Background())
 	if err != nil {
-		s.log.Error("http: server: shutdown", err)
+		s.log.Error("http: server: shutdown", "error", err)
 	}
 }
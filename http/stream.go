@@ -0,0 +1,137 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const (
+	streamHeartbeatInterval = 15 * time.Second
+)
+
+// Stream pushes matched transactions for an address as Server-Sent Events.
+// Every event carries the transaction along with the subscription metadata.
+// A name event tells that the ENS name of the subscription moved to another
+// address, which has a stream of its own.
+// Clients resume with the standard Last-Event-ID header, and the stream ends
+// when the client falls behind so it can reconnect and catch up from the store.
+func (r *Router) Stream(w http.ResponseWriter, req *http.Request) {
+	address := param(req, "address")
+	if address == "" {
+		r.writeJSON(Response{Message: "required address field missing", Code: http.StatusBadRequest}, w)
+		return
+	}
+
+	lastID := 0
+	if header := req.Header.Get("Last-Event-ID"); header != "" {
+		id, err := strconv.Atoi(header)
+		if err != nil || id < 0 {
+			r.writeJSON(Response{Message: "invalid Last-Event-ID header", Code: http.StatusBadRequest}, w)
+			return
+		}
+		lastID = id
+	}
+
+	backlog, listener, err := r.service.Listen(req.Context(), address, lastID)
+	if errors.Is(err, domain.ErrNotSubscribed) {
+		r.writeError(err, w)
+		return
+	}
+	if err != nil {
+		r.writeJSON(Response{Message: "stream unavailable"}, w)
+		return
+	}
+	defer listener.Close()
+
+	rc := http.NewResponseController(w)
+	// streams outlive the server write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		r.log.Error("failed to clear stream write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, n := range backlog {
+		if err := writeEvent(w, n); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	r.streamEvents(w, rc, req, listener)
+}
+
+func (r *Router) streamEvents(w io.Writer, rc *http.ResponseController, req *http.Request, sub *domain.Subscriber) {
+	heartbeat := time.NewTicker(r.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				r.log.Info("stream listener dropped", "address", param(req, "address"))
+				return
+			}
+			switch e := e.(type) {
+			case *domain.TransactionMatched:
+				if err := writeEvent(w, e); err != nil {
+					return
+				}
+			case *domain.NameChanged:
+				if err := writeNameEvent(w, e); err != nil {
+					return
+				}
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w io.Writer, n *domain.TransactionMatched) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("event encode error: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: transaction\ndata: %s\n\n", n.ID, data)
+	if err != nil {
+		return fmt.Errorf("event write error: %w", err)
+	}
+	return nil
+}
+
+// writeNameEvent has no ID, so it does not move the Last-Event-ID clients
+// resume from.
+func writeNameEvent(w io.Writer, n *domain.NameChanged) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("event encode error: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "event: name\ndata: %s\n\n", data)
+	if err != nil {
+		return fmt.Errorf("event write error: %w", err)
+	}
+	return nil
+}
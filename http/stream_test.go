@@ -0,0 +1,128 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+func Test_Stream(t *testing.T) {
+	tests := []struct {
+		name        string
+		address     string
+		lastEventID string
+		wantLastID  int
+		listenErr   error
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:       "backlog and live events",
+			address:    "address-1",
+			wantStatus: http.StatusOK,
+			wantBody: "id: 1\nevent: transaction\ndata: " +
+				`{"id":1,"address":"a","transaction":{"blockNumber":"0x1","from":"a","to":"b"},` +
+				`"subscription":{"address":"a","label":"hot wallet","createdAt":"0001-01-01T00:00:00Z"}}` + "\n\n" +
+				"id: 2\nevent: transaction\ndata: " +
+				`{"id":2,"address":"a","transaction":{"blockNumber":"0x2","from":"b","to":"a"}}` + "\n\n" +
+				"event: name\ndata: " + `{"name":"a.eth","address":"c","previous":"a"}` + "\n\n",
+		},
+		{
+			name:        "resume from last event id",
+			address:     "address-1",
+			lastEventID: "7",
+			wantLastID:  7,
+			wantStatus:  http.StatusOK,
+			wantBody: "id: 1\nevent: transaction\ndata: " +
+				`{"id":1,"address":"a","transaction":{"blockNumber":"0x1","from":"a","to":"b"},` +
+				`"subscription":{"address":"a","label":"hot wallet","createdAt":"0001-01-01T00:00:00Z"}}` + "\n\n" +
+				"id: 2\nevent: transaction\ndata: " +
+				`{"id":2,"address":"a","transaction":{"blockNumber":"0x2","from":"b","to":"a"}}` + "\n\n" +
+				"event: name\ndata: " + `{"name":"a.eth","address":"c","previous":"a"}` + "\n\n",
+		},
+		{
+			name:        "invalid last event id",
+			address:     "address-1",
+			lastEventID: "abc",
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"message":"invalid Last-Event-ID header"}` + "\n",
+		},
+		{
+			name:       "missing address",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"required address field missing"}` + "\n",
+		},
+		{
+			name:       "not subscribed",
+			address:    "address-2",
+			listenErr:  domain.ErrNotSubscribed,
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"message":"address not subscribed"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := make(chan domain.Event, 2)
+			c <- &domain.TransactionMatched{ID: 2, Address: "a", Transaction: &domain.Transaction{BlockNumber: "0x2", From: "b", To: "a"}}
+			c <- &domain.NameChanged{Name: "a.eth", Address: "c", Previous: "a"}
+			close(c)
+			backlog := []*domain.TransactionMatched{
+				{
+					ID:          1,
+					Address:     "a",
+					Transaction: &domain.Transaction{BlockNumber: "0x1", From: "a", To: "b"},
+					Subscription: &domain.Subscription{
+						Address:              "a",
+						SubscriptionMetadata: domain.SubscriptionMetadata{Label: "hot wallet"},
+					},
+				},
+			}
+
+			mockService := &MockService{}
+			if tt.listenErr != nil {
+				mockService.On("Listen", tt.address, tt.wantLastID).Return(nil, nil, tt.listenErr)
+			} else {
+				mockService.On("Listen", tt.address, tt.wantLastID).Return(backlog, &domain.Subscriber{C: c}, nil)
+			}
+
+			router := NewRouter(slog.Default(), mockService)
+
+			req, _ := http.NewRequestWithContext(context.TODO(), "GET", "/stream?address="+tt.address, http.NoBody)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func Test_Stream_Heartbeat(t *testing.T) {
+	c := make(chan domain.Event)
+	mockService := &MockService{}
+	mockService.On("Listen", "address-1", 0).Return([]*domain.TransactionMatched{}, &domain.Subscriber{C: c}, nil)
+
+	router := NewRouter(slog.Default(), mockService)
+	router.heartbeatInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/stream?address=address-1", http.NoBody)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), ": heartbeat\n\n")
+}
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"deshev.com/eth-address-watch/tracing"
+)
+
+// startSpan continues the trace of the W3C traceparent header of the request,
+// if any, with a server span named after the route.
+func startSpan(req *http.Request, route string) (*http.Request, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+	ctx, span := tracing.Start(ctx, route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.HTTPRoute(route),
+		semconv.URLPath(req.URL.Path),
+	))
+	return req.WithContext(ctx), span
+}
+
+// endSpan marks server errors as failed spans; 4xx responses are the fault of
+// the client and leave the span status unset.
+func endSpan(span trace.Span, status int) {
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	span.End()
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"deshev.com/eth-address-watch/domain"
+	"deshev.com/eth-address-watch/tracing/tracingtest"
+)
+
+func Test_Tracing(t *testing.T) {
+	recorder := tracingtest.Record(t)
+	mockService := &MockService{}
+	mockService.On("GetCurrentBlock").Return(0x11)
+	mockService.On("Unsubscribe", "0x1111").Return(domain.ErrNotSubscribed)
+	router := NewRouter(slog.Default(), mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/block", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/v1/subscriptions/0x1111", nil))
+
+	spans := tracingtest.Named(recorder, "GET /v1/block")
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+
+	spans = tracingtest.Named(recorder, "DELETE /v1/subscriptions/{address}")
+	require.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("http.response.status_code", http.StatusNotFound))
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func Test_EndSpan_ServerError(t *testing.T) {
+	recorder := tracingtest.Record(t)
+
+	_, span := startSpan(httptest.NewRequest(http.MethodGet, "/v1/block", nil), "GET /v1/block")
+	endSpan(span, http.StatusInternalServerError)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
@@ -0,0 +1,300 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+const (
+	wsSendBufferSize = 256
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingInterval   = wsPongWait * 9 / 10
+	wsMaxMessageSize = 4096
+
+	wsReasonFellBehind = "client fell behind"
+)
+
+// WSRequest is a message sent by the client over the WebSocket connection.
+// LastID is optional and replays stored transactions after that position.
+type WSRequest struct {
+	Action  string `json:"action"`
+	Address string `json:"address"`
+	LastID  *int   `json:"lastId,omitempty"`
+}
+
+// WSMessage is a frame pushed to the client. Type is one of "block",
+// "transaction", "name", "subscribed", "unsubscribed" or "error".
+type WSMessage struct {
+	Type         string               `json:"type"`
+	Address      string               `json:"address,omitempty"`
+	ID           int                  `json:"id,omitempty"`
+	Message      string               `json:"message,omitempty"`
+	Data         any                  `json:"data,omitempty"`
+	Subscription *domain.Subscription `json:"subscription,omitempty"`
+}
+
+type wsBlock struct {
+	Number       int    `json:"number"`
+	Hash         string `json:"hash"`
+	Transactions int    `json:"transactions"`
+}
+
+// WebSocket serves live blocks and matched transactions to clients that
+// subscribe and unsubscribe addresses dynamically over a single connection.
+// Only addresses subscribed through the API can be listened to, so sockets
+// never leave subscriptions behind.
+func (r *Router) WebSocket(w http.ResponseWriter, req *http.Request) {
+	conn, err := r.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		// the upgrader has already replied with an HTTP error
+		r.log.Info("websocket upgrade failed", "error", err)
+		return
+	}
+
+	c := &wsConn{
+		ctx:       req.Context(),
+		router:    r,
+		conn:      conn,
+		send:      make(chan *WSMessage, wsSendBufferSize),
+		done:      make(chan struct{}),
+		listeners: map[string]*domain.Subscriber{},
+	}
+	c.run()
+}
+
+type wsConn struct {
+	// ctx carries the tenant of the request that opened the connection
+	ctx    context.Context
+	router *Router
+	conn   *websocket.Conn
+	send   chan *WSMessage
+
+	mtx       sync.Mutex
+	listeners map[string]*domain.Subscriber
+	closeOnce sync.Once
+	reason    string
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+func (c *wsConn) run() {
+	blocks := c.router.service.ListenBlocks()
+	c.wg.Add(2)
+	go c.writeLoop()
+	go c.forwardBlocks(blocks)
+
+	c.readLoop()
+	c.fail("")
+	blocks.Close()
+
+	c.mtx.Lock()
+	for address, l := range c.listeners {
+		delete(c.listeners, address)
+		l.Close()
+	}
+	c.mtx.Unlock()
+
+	c.wg.Wait()
+}
+
+func (c *wsConn) readLoop() {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var msg WSRequest
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				c.router.log.Info("websocket read failed", "error", err)
+			}
+			return
+		}
+
+		switch msg.Action {
+		case "subscribe":
+			c.subscribe(msg)
+		case "unsubscribe":
+			c.unsubscribe(msg.Address)
+		default:
+			c.enqueue(&WSMessage{Type: "error", Message: "unknown action"})
+		}
+	}
+}
+
+func (c *wsConn) subscribe(msg WSRequest) {
+	if msg.Address == "" {
+		c.enqueue(&WSMessage{Type: "error", Message: "required address field missing"})
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if _, exists := c.listeners[msg.Address]; exists {
+		c.enqueue(&WSMessage{Type: "subscribed", Address: msg.Address})
+		return
+	}
+
+	lastID := math.MaxInt
+	if msg.LastID != nil {
+		lastID = *msg.LastID
+	}
+	backlog, l, err := c.router.service.Listen(c.ctx, msg.Address, lastID)
+	if err != nil {
+		c.enqueue(&WSMessage{Type: "error", Address: msg.Address, Message: err.Error()})
+		return
+	}
+	c.listeners[msg.Address] = l
+
+	c.enqueue(&WSMessage{Type: "subscribed", Address: msg.Address})
+	for _, n := range backlog {
+		c.enqueue(transactionMessage(n))
+	}
+
+	c.wg.Add(1)
+	go c.forwardTransactions(msg.Address, l)
+}
+
+func (c *wsConn) unsubscribe(address string) {
+	c.mtx.Lock()
+	l, exists := c.listeners[address]
+	delete(c.listeners, address)
+	c.mtx.Unlock()
+
+	if exists {
+		l.Close()
+	}
+	c.enqueue(&WSMessage{Type: "unsubscribed", Address: address})
+}
+
+func (c *wsConn) forwardTransactions(address string, l *domain.Subscriber) {
+	defer c.wg.Done()
+
+	for e := range l.C {
+		switch e := e.(type) {
+		case *domain.TransactionMatched:
+			c.enqueue(transactionMessage(e))
+		case *domain.NameChanged:
+			c.enqueue(&WSMessage{Type: "name", Address: address, Data: e})
+		}
+	}
+
+	// the listener is closed either by unsubscribe or because it was dropped
+	c.mtx.Lock()
+	current, active := c.listeners[address]
+	c.mtx.Unlock()
+	if active && current == l {
+		c.fail(wsReasonFellBehind)
+	}
+}
+
+func (c *wsConn) forwardBlocks(l *domain.Subscriber) {
+	defer c.wg.Done()
+
+	for e := range l.C {
+		ingested, ok := e.(*domain.BlockIngested)
+		if !ok {
+			continue
+		}
+		block := ingested.Block
+		c.enqueue(&WSMessage{
+			Type: "block",
+			Data: wsBlock{
+				Number:       block.NumberParsed,
+				Hash:         block.Hash,
+				Transactions: len(block.Transactions),
+			},
+		})
+	}
+
+	select {
+	case <-c.done:
+	default:
+		c.fail(wsReasonFellBehind)
+	}
+}
+
+// enqueue never blocks: a client that does not drain its bounded buffer is
+// disconnected instead of holding up the notification path.
+func (c *wsConn) enqueue(msg *WSMessage) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	select {
+	case c.send <- msg:
+	default:
+		c.fail(wsReasonFellBehind)
+	}
+}
+
+func (c *wsConn) fail(reason string) {
+	c.closeOnce.Do(func() {
+		c.reason = reason
+		close(c.done)
+	})
+}
+
+func (c *wsConn) writeLoop() {
+	defer c.wg.Done()
+	defer c.conn.Close()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			c.writeClose()
+			return
+		case msg := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				c.fail("")
+				return
+			}
+		case <-ping.C:
+			deadline := time.Now().Add(wsWriteWait)
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				c.fail("")
+				return
+			}
+		}
+	}
+}
+
+func (c *wsConn) writeClose() {
+	code := websocket.CloseNormalClosure
+	if c.reason != "" {
+		code = websocket.ClosePolicyViolation
+		c.router.log.Info("closing websocket", "reason", c.reason)
+	}
+
+	msg := websocket.FormatCloseMessage(code, c.reason)
+	_ = c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+}
+
+func transactionMessage(n *domain.TransactionMatched) *WSMessage {
+	return &WSMessage{
+		Type:         "transaction",
+		Address:      n.Address,
+		ID:           n.ID,
+		Data:         n.Transaction,
+		Subscription: n.Subscription,
+	}
+}
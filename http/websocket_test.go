@@ -0,0 +1,109 @@
+package http
+
+import (
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"deshev.com/eth-address-watch/domain"
+)
+
+func dialWebSocket(t *testing.T, s Service) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(NewRouter(slog.Default(), s))
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	return conn
+}
+
+func Test_WebSocket_SubscribeAndUnsubscribe(t *testing.T) {
+	blockC := make(chan domain.Event, 1)
+	txC := make(chan domain.Event, 1)
+	backlog := []*domain.TransactionMatched{
+		{ID: 1, Address: "address-1", Transaction: &domain.Transaction{From: "a", To: "address-1"}},
+	}
+
+	mockService := &MockService{}
+	mockService.On("ListenBlocks").Return(&domain.Subscriber{C: blockC})
+	mockService.On("Listen", "address-1", 0).Return(backlog, &domain.Subscriber{C: txC}, nil)
+	mockService.On("Listen", "address-2", mock.Anything).Return(nil, nil, domain.ErrNotSubscribed)
+
+	conn := dialWebSocket(t, mockService)
+
+	require.NoError(t, conn.WriteJSON(WSRequest{Action: "subscribe", Address: "address-1", LastID: new(int)}))
+
+	var msg WSMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "subscribed", msg.Type)
+	assert.Equal(t, "address-1", msg.Address)
+
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "transaction", msg.Type)
+	assert.Equal(t, 1, msg.ID)
+
+	txC <- &domain.TransactionMatched{ID: 2, Address: "address-1", Transaction: &domain.Transaction{From: "address-1"}}
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "transaction", msg.Type)
+	assert.Equal(t, 2, msg.ID)
+
+	blockC <- &domain.BlockIngested{Block: &domain.Block{NumberParsed: 0x11, Hash: "0xabc"}}
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "block", msg.Type)
+	assert.Equal(t, map[string]any{"number": 17.0, "hash": "0xabc", "transactions": 0.0}, msg.Data)
+
+	require.NoError(t, conn.WriteJSON(WSRequest{Action: "unsubscribe", Address: "address-1"}))
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "unsubscribed", msg.Type)
+
+	require.NoError(t, conn.WriteJSON(WSRequest{Action: "subscribe", Address: "address-2"}))
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "error", msg.Type)
+	assert.Equal(t, "address-2", msg.Address)
+	assert.Equal(t, domain.ErrNotSubscribed.Error(), msg.Message)
+
+	require.NoError(t, conn.WriteJSON(WSRequest{Action: "dance"}))
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "error", msg.Type)
+	assert.Equal(t, "unknown action", msg.Message)
+	mockService.AssertNotCalled(t, "Subscribe")
+}
+
+func Test_WebSocket_DisconnectsWhenFallingBehind(t *testing.T) {
+	blockC := make(chan domain.Event)
+	txC := make(chan domain.Event)
+	close(txC)
+
+	mockService := &MockService{}
+	mockService.On("ListenBlocks").Return(&domain.Subscriber{C: blockC})
+	mockService.On("Listen", "address-1", mock.Anything).Return([]*domain.TransactionMatched{}, &domain.Subscriber{C: txC}, nil)
+
+	conn := dialWebSocket(t, mockService)
+
+	require.NoError(t, conn.WriteJSON(WSRequest{Action: "subscribe", Address: "address-1"}))
+
+	var err error
+	for err == nil {
+		var msg WSMessage
+		err = conn.ReadJSON(&msg)
+	}
+
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, websocket.ClosePolicyViolation, closeErr.Code)
+	assert.Equal(t, wsReasonFellBehind, closeErr.Text)
+}
@@ -2,49 +2,156 @@ package internal
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"deshev.com/eth-address-watch/client/eth"
 	"deshev.com/eth-address-watch/config"
 	"deshev.com/eth-address-watch/domain"
+	"deshev.com/eth-address-watch/grpc"
+	"deshev.com/eth-address-watch/health"
 	"deshev.com/eth-address-watch/http"
+	"deshev.com/eth-address-watch/metrics"
+	"deshev.com/eth-address-watch/ratelimit"
+	"deshev.com/eth-address-watch/tracing"
 )
 
-const (
-	blockBufferSize = 10
-)
+const traceFlushTimeout = 5 * time.Second
 
 type Application struct {
-	log    *slog.Logger
-	config *config.Config
-	ctx    context.Context
-
-	service *domain.Service
-	watcher *domain.Watcher
-	server  *http.Server
-	blockC  chan *domain.Block
+	log *slog.Logger
+	ctx context.Context
+
+	// config is replaced by Reload
+	mtx        sync.Mutex
+	config     *config.Config
+	loadConfig func() (*config.Config, error)
+
+	service    *domain.Service
+	client     *eth.Client
+	watcher    *domain.Watcher
+	balances   *domain.Balances
+	names      *domain.Names
+	server     *http.Server
+	grpcServer *grpc.Server
+	bus        *domain.Bus
+
+	shutdownTracing func(context.Context) error
 }
 
-func NewApplication(ctx context.Context, log *slog.Logger) *Application {
-	cfg := config.New()
-	blockC := make(chan *domain.Block, blockBufferSize)
+type Option func(*Application)
+
+// WithConfigLoader sets how Reload reads the configuration again. It should
+// load from the same arguments as at startup; the default is config.Load
+// without flags.
+func WithConfigLoader(load func() (*config.Config, error)) Option {
+	return func(a *Application) {
+		a.loadConfig = load
+	}
+}
+
+func NewApplication(ctx context.Context, log *slog.Logger, cfg *config.Config, opts ...Option) *Application {
+	shutdownTracing, err := tracing.Setup(log, cfg)
+	if err != nil {
+		log.Error("tracing disabled", "error", err)
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+	bus := domain.NewBus(log)
 
-	service := domain.NewService(log, blockC)
-	server := http.NewServer(log, service)
 	client := eth.NewClient(cfg)
-	watcher := domain.NewWatcher(log, cfg, client, blockC)
+	balances := domain.NewBalances(log, cfg, client, bus)
+	names := domain.NewNames(log, cfg, client)
+	service := domain.NewService(log, bus, domain.WithBlockBufferSize(cfg.BlockBufferSize), domain.WithBlockSource(client),
+		domain.WithReceiptSource(client), domain.WithBalances(balances), domain.WithNames(names))
+	service.SetQuota(quota(cfg))
+	watcher := domain.NewWatcher(log, cfg, client, bus)
+	readiness := health.NewChecker(
+		health.Check{Name: "ingestion", Run: watcher.CheckIngestion},
+		health.Check{Name: "lag", Run: domain.CheckLag(watcher, service)},
+		health.Check{Name: "store", Run: service.CheckStore},
+	)
+	// both APIs take from the same rate limit buckets
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimitRPS > 0 {
+		limiter = ratelimit.New(cfg.RateLimitRPS, cfg.RateLimitBurst)
+	}
+	server := http.NewServer(log, cfg, service, readiness, limiter)
+	grpcServer := grpc.NewServer(log, cfg, service, limiter)
+	// the service queue took over from the blockC channel of the watcher
+	metrics.Default.NewGaugeFunc("eaw_block_queue_depth", "Ingested blocks waiting to be processed by the service.",
+		func() float64 { return float64(service.BlockQueueDepth()) })
+
+	a := &Application{
+		ctx:        ctx,
+		log:        log,
+		config:     cfg,
+		loadConfig: func() (*config.Config, error) { return config.Load(nil) },
+
+		service:    service,
+		client:     client,
+		server:     server,
+		grpcServer: grpcServer,
+		watcher:    watcher,
+		balances:   balances,
+		names:      names,
+		bus:        bus,
+
+		shutdownTracing: shutdownTracing,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	server.SetReloader(a.Reload)
+	return a
+}
+
+func quota(cfg *config.Config) domain.Quota {
+	return domain.Quota{
+		MaxSubscriptions: cfg.MaxSubscriptions,
+		MaxTransactions:  cfg.MaxTransactions,
+	}
+}
 
-	return &Application{
-		ctx:    ctx,
-		log:    log,
-		config: cfg,
+// Reload loads the configuration again and applies it without losing the
+// subscriptions a restart would: the node endpoint, watcher timing, balance
+// reconciliation, ENS resolution, tenant quotas and readiness limits change
+// live. Nothing is applied when the new configuration is invalid or changes a
+// value that is only read at startup. It returns the keys that changed.
+func (a *Application) Reload() ([]string, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
 
-		service: service,
-		server:  server,
-		watcher: watcher,
-		blockC:  blockC,
+	next, err := a.loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", config.ErrInvalid, err)
+	}
+	changed, err := a.config.CheckReload(next)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the error names the keys
+	}
+
+	a.client.Reconfigure(next)
+	a.watcher.Reconfigure(next)
+	a.balances.Reconfigure(next)
+	a.names.Reconfigure(next)
+	a.service.SetQuota(quota(next))
+	a.config = next
+	a.log.Info("configuration reloaded", "changed", changed)
+	return changed, nil
+}
+
+// FlushTraces exports the spans still buffered before the process exits.
+func (a *Application) FlushTraces() {
+	ctx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+	defer cancel()
+
+	if err := a.shutdownTracing(ctx); err != nil {
+		a.log.Error("error flushing traces", "error", err)
 	}
 }
 
@@ -53,23 +160,49 @@ func (a *Application) StartAPIServer() error {
 	return a.server.Start(a.ctx)
 }
 
+func (a *Application) StartGRPCServer() error {
+	//nolint:wrapcheck // boot errors are logged in main
+	return a.grpcServer.Start(a.ctx)
+}
+
 func (a *Application) StartBlockWatcher() error {
 	//nolint:wrapcheck // boot errors are logged in main
 	return a.watcher.Start(a.ctx)
 }
 
+func (a *Application) StartBalanceTracker() error {
+	//nolint:wrapcheck // boot errors are logged in main
+	return a.balances.Start(a.ctx)
+}
+
+func (a *Application) StartNameWatcher() error {
+	//nolint:wrapcheck // boot errors are logged in main
+	return a.service.WatchNames(a.ctx)
+}
+
 func (a *Application) StartNotificationService() error {
 	//nolint:wrapcheck // boot errors are logged in main
 	return a.service.Start(a.ctx)
 }
 
+// StartSignalMonitor stops the application on SIGINT and reloads the
+// configuration on SIGHUP.
 func (a *Application) StartSignalMonitor() error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
-	select {
-	case <-a.ctx.Done():
-		return context.Canceled
-	case <-quit:
-		return context.Canceled
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	for {
+		select {
+		case <-a.ctx.Done():
+			return context.Canceled
+		case <-quit:
+			return context.Canceled
+		case <-hangup:
+			if _, err := a.Reload(); err != nil {
+				a.log.Error("configuration reload failed", "error", err)
+			}
+		}
 	}
 }
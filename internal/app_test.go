@@ -2,17 +2,66 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/domain"
+	"deshev.com/eth-address-watch/metrics"
 )
 
 func Test_NewApplication(t *testing.T) {
 	log := slog.Default()
 	ctx := context.TODO()
-	a := NewApplication(ctx, log)
+	a := NewApplication(ctx, log, config.Default())
 
 	assert.NotNil(t, a.service)
 	assert.NotNil(t, a.watcher)
+	assert.NotNil(t, a.grpcServer)
+	assert.NotPanics(t, a.FlushTraces)
+}
+
+func Test_BlockQueueDepthMetric(t *testing.T) {
+	a := NewApplication(context.TODO(), slog.Default(), config.Default())
+	a.bus.Publish(&domain.BlockIngested{Block: &domain.Block{NumberParsed: 0x11}})
+
+	var out strings.Builder
+	require.NoError(t, metrics.Default.WriteText(&out))
+	assert.Contains(t, out.String(), "\neaw_block_queue_depth 1\n")
+}
+
+func Test_Reload(t *testing.T) {
+	next := config.Default()
+	var loadErr error
+	a := NewApplication(context.TODO(), slog.Default(), config.Default(), WithConfigLoader(func() (*config.Config, error) {
+		return next, loadErr
+	}))
+
+	next.EthNodeURL = "http://localhost:8545"
+	next.MaxSubscriptions = 1
+	changed, err := a.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eth_node_url", "max_subscriptions_per_tenant"}, changed)
+	assert.Same(t, next, a.config)
+	_, err = a.service.Subscribe(context.TODO(), "0x1111")
+	require.NoError(t, err)
+	_, err = a.service.Subscribe(context.TODO(), "0x2222")
+	assert.ErrorIs(t, err, domain.ErrQuotaExceeded)
+
+	restart := *next
+	restart.HTTPPort = 8000
+	next = &restart
+	_, err = a.Reload()
+	assert.ErrorIs(t, err, config.ErrRestartRequired)
+	assert.NotSame(t, next, a.config)
+
+	loadErr = errors.New("block_tick_interval must be positive")
+	_, err = a.Reload()
+	assert.ErrorIs(t, err, config.ErrInvalid)
+	assert.EqualError(t, err, "invalid configuration: block_tick_interval must be positive")
 }
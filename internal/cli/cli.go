@@ -0,0 +1,143 @@
+// Package cli is the eth-address-watch command line. Every command loads the
+// configuration the same way, from the config file, environment variables and
+// flags, and commands that talk to a running instance find it with
+// config.InstanceURL.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"deshev.com/eth-address-watch/client/watch"
+	"deshev.com/eth-address-watch/config"
+)
+
+const name = "eth-address-watch"
+
+type command struct {
+	name string
+	// args are the flags and arguments of the command besides the config flags
+	args    string
+	summary string
+	run     func(ctx context.Context, stdout io.Writer, args []string) error
+}
+
+var commands = []*command{
+	{name: "serve", summary: "Run the service, the default without a command", run: serve},
+	{name: "backfill", args: "--address ADDRESS --from BLOCK --to BLOCK", summary: "Store the transactions of a subscribed address in past blocks", run: backfill},
+	{name: "export", args: "--address ADDRESS | --group NAME [--format csv|ndjson|json|parquet] [--from TIME] [--to TIME] [--output FILE]", summary: "Stream the stored transactions of an address or group", run: exportTransactions},
+	{name: "replay", args: "[--address ADDRESS]", summary: "Publish stored transactions matching the current rules again, of every subscription by default", run: replay},
+	{name: "subscribe", args: "--address ADDRESS", summary: "Start watching an address", run: subscribe},
+	{name: "unsubscribe", args: "--address ADDRESS", summary: "Stop watching an address and drop its transactions", run: unsubscribe},
+	{name: "config", args: "print", summary: "Print the effective configuration and where each value came from", run: printConfig},
+}
+
+// usageError is a mistake in the command line or the configuration, reported
+// with exit code 2.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+func usagef(format string, args ...any) error {
+	return &usageError{err: fmt.Errorf(format, args...)}
+}
+
+// Run runs the command named by the first argument and returns the exit code.
+// Without a command, or when the first argument is a flag, it serves.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	cmd := commands[0]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		if args[0] == "help" {
+			printUsage(stdout)
+			return 0
+		}
+		cmd = findCommand(args[0])
+		if cmd == nil {
+			fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+			printUsage(stderr)
+			return 2
+		}
+		args = args[1:]
+	}
+
+	err := cmd.run(ctx, stdout, args)
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		printCommandUsage(stdout, cmd)
+		return 0
+	case errors.As(err, &usageErr):
+		fmt.Fprintln(stderr, err)
+		return 2
+	default:
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [command] [flags]\n\nCommands:\n", name)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun %s <command> --help for the flags of a command.\n", name)
+}
+
+func printCommandUsage(w io.Writer, cmd *command) {
+	fmt.Fprintf(w, "Usage: %s %s %s [flags]\n\n%s.\n\nConfig flags:\n", name, cmd.name, cmd.args, cmd.summary)
+	config.Usage(w)
+}
+
+// parse parses the command line of a command with its own flags on fs and
+// loads the configuration.
+func parse(fs *flag.FlagSet, args []string) (*config.Config, error) {
+	load := config.Bind(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err //nolint:wrapcheck // shows the usage
+		}
+		return nil, &usageError{err: err}
+	}
+	if fs.NArg() > 0 {
+		return nil, usagef("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	cfg, err := load()
+	if err != nil {
+		return nil, usagef("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// newClient connects to the running instance of cfg.
+func newClient(cfg *config.Config) *watch.Client {
+	return watch.NewClient(cfg.InstanceURL(), watch.WithAPIKey(cfg.APIKey))
+}
+
+func addressFlag(fs *flag.FlagSet) *string {
+	return fs.String("address", "", "subscribed address")
+}
+
+func requireAddress(address string) error {
+	if address == "" {
+		return usagef("--address is required")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"deshev.com/eth-address-watch/domain"
+	api "deshev.com/eth-address-watch/http"
+)
+
+const (
+	address1 = "0x0000000000000000000000000000000000000001"
+	address2 = "0x0000000000000000000000000000000000000002"
+)
+
+// fakeNode serves past blocks to backfill, numbered from 1.
+type fakeNode []*domain.Block
+
+func (n fakeNode) GetLatestBlock(context.Context) (int, error) {
+	return len(n), nil
+}
+
+func (n fakeNode) GetBlock(_ context.Context, blockNumber int) (*domain.Block, error) {
+	return n[blockNumber-1], nil
+}
+
+// newInstance runs the API on an in-memory service with the blocks of node
+// and returns a function running commands against it.
+func newInstance(t *testing.T, node fakeNode) (*domain.Service, func(args ...string) (int, string, string)) {
+	t.Helper()
+
+	log := slog.Default()
+	service := domain.NewService(log, domain.NewBus(log), domain.WithBlockSource(node))
+	server := httptest.NewServer(api.NewRouter(log, service))
+	t.Cleanup(server.Close)
+
+	return service, func(args ...string) (int, string, string) {
+		var stdout, stderr strings.Builder
+		code := Run(context.Background(), append(args, "--api-url", server.URL), &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+}
+
+func Test_Subscribe(t *testing.T) {
+	service, run := newInstance(t, nil)
+
+	code, stdout, _ := run("subscribe", "--address", address1)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "subscribed "+address1+"\n", stdout)
+	_, err := service.GetSubscription(context.Background(), address1)
+	assert.NoError(t, err)
+
+	code, _, _ = run("unsubscribe", "--address", address1)
+	assert.Equal(t, 0, code)
+	code, _, stderr := run("unsubscribe", "--address", address1)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "address not subscribed")
+
+	code, _, stderr = run("subscribe")
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "--address is required\n", stderr)
+}
+
+func Test_BackfillAndExport(t *testing.T) {
+	node := make(fakeNode, 12)
+	for i := range node {
+		node[i] = &domain.Block{}
+	}
+	node[2].Timestamp = "0x65920080"
+	node[11].Timestamp = "0x65935200"
+	node[2].Transactions = []*domain.Transaction{{Hash: "0xa", BlockNumber: "0x3", From: address2, To: address1, Value: "0x10"}}
+	node[11].Transactions = []*domain.Transaction{{Hash: "0xb", BlockNumber: "0xc", From: address1, To: address2, Value: "0x20"}}
+	_, run := newInstance(t, node)
+
+	code, _, _ := run("subscribe", "--address", address1)
+	require.Equal(t, 0, code)
+
+	code, stdout, _ := run("backfill", "--address", address1, "--from", "1", "--to", "12")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "blocks 1 to 10: 1 transactions stored\n"+
+		"blocks 11 to 12: 1 transactions stored\n"+
+		"2 transactions stored for "+address1+"\n", stdout)
+
+	code, stdout, _ = run("export", "--address", address1)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "address,id,hash,block_number,block_time,from,to,direction,value_wei,value_eth,gas,gas_price_wei,status\n"+
+		address1+",1,0xa,3,2024-01-01T00:00:00Z,"+address2+","+address1+",in,16,0.000000000000000016,0,0,\n"+
+		address1+",2,0xb,12,2024-01-02T00:00:00Z,"+address1+","+address2+",out,32,0.000000000000000032,0,0,\n", stdout)
+
+	output := filepath.Join(t.TempDir(), "export.ndjson")
+	code, stdout, _ = run("export", "--address", address1, "--format", "ndjson", "--from", "2024-01-02", "--output", output)
+	assert.Equal(t, 0, code)
+	assert.Empty(t, stdout)
+	written, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"address":"`+address1+`","id":2,"hash":"0xb","block_number":12,"block_time":"2024-01-02T00:00:00Z",`+
+		`"from":"`+address1+`","to":"`+address2+`","direction":"out","value_wei":"32","value_eth":"0.000000000000000032",`+
+		`"gas":0,"gas_price_wei":"0","status":""}`, string(written))
+
+	// json is an alias of ndjson
+	code, stdout, _ = run("export", "--address", address1, "--format", "json", "--from", "2024-01-02")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, string(written), stdout)
+
+	code, stdout, _ = run("replay")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, address1+": 2 transactions matched\n", stdout)
+
+	code, _, stderr := run("export", "--address", address1, "--format", "xml")
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "unknown export format \"xml\", use csv, ndjson or parquet\n", stderr)
+	code, _, stderr = run("export", "--address", address1, "--group", "treasury")
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "either --address or --group is required\n", stderr)
+	code, _, stderr = run("export", "--group", "treasury")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "group not found")
+	code, _, _ = run("backfill", "--address", address1, "--from", "5", "--to", "4")
+	assert.Equal(t, 2, code)
+}
+
+func Test_Run_Usage(t *testing.T) {
+	var stdout, stderr strings.Builder
+	assert.Equal(t, 2, Run(context.Background(), []string{"deploy"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "unknown command \"deploy\"")
+
+	stdout.Reset()
+	assert.Equal(t, 0, Run(context.Background(), []string{"backfill", "--help"}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "Usage: eth-address-watch backfill --address ADDRESS --from BLOCK --to BLOCK [flags]")
+	assert.Contains(t, stdout.String(), "--eth-node-url string")
+
+	stderr.Reset()
+	assert.Equal(t, 2, Run(context.Background(), []string{"config", "print", "--http-port", "0"}, &stdout, &stderr))
+	assert.Equal(t, "invalid configuration:\nhttp_port: must be between 1 and 65535, got 0\n", stderr.String())
+
+	stdout.Reset()
+	assert.Equal(t, 0, Run(context.Background(), []string{"config", "print", "--http-port", "8000"}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "http_port: 8000 # flag --http-port\n")
+}
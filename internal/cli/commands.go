@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"deshev.com/eth-address-watch/client/watch"
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/domain"
+	"deshev.com/eth-address-watch/export"
+)
+
+// backfillBatch keeps each backfill request well within the HTTP timeout.
+const backfillBatch = 10
+
+func backfill(ctx context.Context, stdout io.Writer, args []string) error {
+	fs := config.NewFlagSet("backfill")
+	address := addressFlag(fs)
+	from := fs.Int("from", -1, "first block")
+	to := fs.Int("to", -1, "last block")
+	cfg, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireAddress(*address); err != nil {
+		return err
+	}
+	if *from < 0 || *to < *from {
+		return usagef("--from and --to are required, with --from not after --to")
+	}
+
+	client := newClient(cfg)
+	stored := 0
+	for start := *from; start <= *to; start += backfillBatch {
+		end := min(start+backfillBatch-1, *to)
+		result, err := client.Backfill(ctx, *address, start, end)
+		if err != nil {
+			return fmt.Errorf("backfill of blocks %d to %d failed: %w", start, end, err)
+		}
+		stored += result.Stored
+		fmt.Fprintf(stdout, "blocks %d to %d: %d transactions stored\n", start, end, result.Stored)
+	}
+	fmt.Fprintf(stdout, "%d transactions stored for %s\n", stored, *address)
+	return nil
+}
+
+func exportTransactions(ctx context.Context, stdout io.Writer, args []string) error {
+	fs := config.NewFlagSet("export")
+	address := addressFlag(fs)
+	group := fs.String("group", "", "group name, to export the transactions of all members")
+	format := fs.String("format", export.FormatCSV, "csv, ndjson (or json) or parquet")
+	from := fs.String("from", "", "first block time included, RFC 3339 or YYYY-MM-DD")
+	to := fs.String("to", "", "block time to stop at, excluded, RFC 3339 or YYYY-MM-DD")
+	output := fs.String("output", "", "file to write, standard output by default")
+	cfg, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if (*address == "") == (*group == "") {
+		return usagef("either --address or --group is required")
+	}
+	// json was the line per transaction format before Parquet was added
+	if *format == "json" {
+		*format = export.FormatNDJSON
+	}
+	if _, err := export.NewWriter(io.Discard, *format); err != nil {
+		return usagef("%s", err)
+	}
+	query := watch.ExportQuery{Address: *address, Group: *group, Format: *format}
+	if query.From, err = export.ParseTime(*from); err != nil {
+		return usagef("invalid --from: %s", err)
+	}
+	if query.To, err = export.ParseTime(*to); err != nil {
+		return usagef("invalid --to: %s", err)
+	}
+
+	body, err := newClient(cfg).Export(ctx, query)
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	defer body.Close()
+
+	if *output == "" {
+		_, err = io.Copy(stdout, body)
+	} else {
+		err = writeFile(*output, body)
+	}
+	if err != nil {
+		return fmt.Errorf("export interrupted: %w", err)
+	}
+	return nil
+}
+
+func writeFile(name string, r io.Reader) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err //nolint:wrapcheck // errors name the file
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err //nolint:wrapcheck // errors name the file
+	}
+	return file.Close() //nolint:wrapcheck // errors name the file
+}
+
+func replay(ctx context.Context, stdout io.Writer, args []string) error {
+	fs := config.NewFlagSet("replay")
+	address := addressFlag(fs)
+	cfg, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	client := newClient(cfg)
+	addresses := []string{*address}
+	if *address == "" {
+		subs, err := client.ListSubscriptions(ctx, domain.SubscriptionFilter{})
+		if err != nil {
+			return fmt.Errorf("listing subscriptions failed: %w", err)
+		}
+		addresses = addresses[:0]
+		for _, sub := range subs {
+			addresses = append(addresses, sub.Address)
+		}
+	}
+
+	for _, address := range addresses {
+		matched, err := client.Replay(ctx, address)
+		if err != nil {
+			return fmt.Errorf("replay of %s failed: %w", address, err)
+		}
+		fmt.Fprintf(stdout, "%s: %d transactions matched\n", address, matched)
+	}
+	return nil
+}
+
+func subscribe(ctx context.Context, stdout io.Writer, args []string) error {
+	fs := config.NewFlagSet("subscribe")
+	address := addressFlag(fs)
+	cfg, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireAddress(*address); err != nil {
+		return err
+	}
+
+	if _, err := newClient(cfg).Subscribe(ctx, *address); err != nil {
+		return fmt.Errorf("subscribe failed: %w", err)
+	}
+	fmt.Fprintf(stdout, "subscribed %s\n", *address)
+	return nil
+}
+
+func unsubscribe(ctx context.Context, stdout io.Writer, args []string) error {
+	fs := config.NewFlagSet("unsubscribe")
+	address := addressFlag(fs)
+	cfg, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireAddress(*address); err != nil {
+		return err
+	}
+
+	if err := newClient(cfg).Unsubscribe(ctx, *address); err != nil {
+		return fmt.Errorf("unsubscribe failed: %w", err)
+	}
+	fmt.Fprintf(stdout, "unsubscribed %s\n", *address)
+	return nil
+}
+
+func printConfig(_ context.Context, stdout io.Writer, args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return usagef("unknown config command, use config print")
+	}
+	cfg, err := parse(config.NewFlagSet("config print"), args[1:])
+	if err != nil {
+		return err
+	}
+	return cfg.Print(stdout) //nolint:wrapcheck // write errors name the output
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"golang.org/x/sync/errgroup"
+
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/internal"
+)
+
+func serve(ctx context.Context, _ io.Writer, args []string) error {
+	cfg, err := parse(config.NewFlagSet("serve"), args)
+	if err != nil {
+		return err
+	}
+
+	log := slog.Default()
+	ops, ctx := errgroup.WithContext(ctx)
+
+	// serve has no flags of its own, so Load takes the same arguments
+	app := internal.NewApplication(ctx, log, cfg, internal.WithConfigLoader(func() (*config.Config, error) {
+		return config.Load(args)
+	}))
+	log.Info("starting eth-address-watch")
+
+	ops.Go(app.StartBlockWatcher)
+	ops.Go(app.StartAPIServer)
+	ops.Go(app.StartGRPCServer)
+	ops.Go(app.StartNotificationService)
+	ops.Go(app.StartBalanceTracker)
+	ops.Go(app.StartNameWatcher)
+	ops.Go(app.StartSignalMonitor)
+
+	err = ops.Wait()
+	app.FlushTraces()
+	if !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("server terminated abnormally: %w", err)
+	}
+	return nil
+}
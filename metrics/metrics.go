@@ -0,0 +1,395 @@
+// Package metrics keeps counters, gauges and histograms and serves them in
+// the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets suit request latencies in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default is the registry served at /metrics.
+var Default = NewRegistry()
+
+type family interface {
+	desc() *desc
+	write(w *bufio.Writer)
+}
+
+// Registry is a set of metric families. Registering a name twice replaces
+// the earlier family.
+type Registry struct {
+	mtx      sync.Mutex
+	families map[string]family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]family{}}
+}
+
+func (r *Registry) register(f family) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.families[f.desc().name] = f
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{vec: newVec[*Counter](name, help, "counter", labels, func() *Counter { return &Counter{} })}
+	r.register(v)
+	return v
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *GaugeVec {
+	v := &GaugeVec{vec: newVec[*Gauge](name, help, "gauge", labels, func() *Gauge { return &Gauge{} })}
+	r.register(v)
+	return v
+}
+
+// NewGaugeFunc registers a gauge whose value is read on every scrape.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&gaugeFunc{d: desc{name: name, help: help, kind: "gauge"}, fn: fn})
+}
+
+// NewHistogram counts observations in cumulative buckets with the given
+// upper bounds, which have to be sorted.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	v := &HistogramVec{vec: newVec[*Histogram](name, help, "histogram", labels, func() *Histogram {
+		return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+	})}
+	r.register(v)
+	return v
+}
+
+// WriteText writes every family sorted by name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mtx.Lock()
+	families := make([]family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mtx.Unlock()
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].desc().name < families[j].desc().name
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		d := f.desc()
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.kind)
+		f.write(bw)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("metrics write error: %w", err)
+	}
+	return nil
+}
+
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.WriteText(w)
+	}
+}
+
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+// vec holds the children of a family by label values.
+type vec[M any] struct {
+	d        desc
+	newChild func() M
+
+	mtx      sync.Mutex
+	children map[string]M
+	values   map[string][]string
+}
+
+func newVec[M any](name, help, kind string, labels []string, newChild func() M) vec[M] {
+	return vec[M]{
+		d:        desc{name: name, help: help, kind: kind, labels: labels},
+		newChild: newChild,
+		children: map[string]M{},
+		values:   map[string][]string{},
+	}
+}
+
+func (v *vec[M]) desc() *desc {
+	return &v.d
+}
+
+// with returns the child for the label values, creating it on first use.
+func (v *vec[M]) with(values []string) M {
+	if len(values) != len(v.d.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.d.name, len(v.d.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	child, exists := v.children[key]
+	if !exists {
+		child = v.newChild()
+		v.children[key] = child
+		v.values[key] = values
+	}
+	return child
+}
+
+// Delete drops the child with the label values, for series that stopped
+// existing like the matches of a removed subscription.
+func (v *vec[M]) Delete(values ...string) {
+	key := strings.Join(values, "\xff")
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	delete(v.children, key)
+	delete(v.values, key)
+}
+
+// each calls fn for every child, ordered by label values.
+func (v *vec[M]) each(fn func(labels string, child M)) {
+	v.mtx.Lock()
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	children := make([]M, len(keys))
+	labels := make([]string, len(keys))
+	for i, key := range keys {
+		children[i] = v.children[key]
+		labels[i] = formatLabels(v.d.labels, v.values[key])
+	}
+	v.mtx.Unlock()
+
+	for i := range keys {
+		fn(labels[i], children[i])
+	}
+}
+
+type CounterVec struct {
+	vec[*Counter]
+}
+
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.with(values)
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.each(func(labels string, c *Counter) {
+		writeSample(w, v.d.name, labels, c.Value())
+	})
+}
+
+type Counter struct {
+	value atomicFloat
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add panics on negative values since counters only go up.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.value.add(delta)
+}
+
+func (c *Counter) Value() float64 {
+	return c.value.load()
+}
+
+type GaugeVec struct {
+	vec[*Gauge]
+}
+
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return v.with(values)
+}
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	v.each(func(labels string, g *Gauge) {
+		writeSample(w, v.d.name, labels, g.Value())
+	})
+}
+
+type Gauge struct {
+	value atomicFloat
+}
+
+func (g *Gauge) Set(value float64) {
+	g.value.store(value)
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.value.add(delta)
+}
+
+func (g *Gauge) Value() float64 {
+	return g.value.load()
+}
+
+type gaugeFunc struct {
+	d  desc
+	fn func() float64
+}
+
+func (g *gaugeFunc) desc() *desc {
+	return &g.d
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	writeSample(w, g.d.name, "", g.fn())
+}
+
+type HistogramVec struct {
+	vec[*Histogram]
+}
+
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.with(values)
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.each(func(labels string, h *Histogram) {
+		counts, count, sum := h.snapshot()
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += counts[i]
+			writeSample(w, v.d.name+"_bucket", withLabel(labels, "le", formatFloat(bound)), float64(cumulative))
+		}
+		writeSample(w, v.d.name+"_bucket", withLabel(labels, "le", "+Inf"), float64(count))
+		writeSample(w, v.d.name+"_sum", labels, sum)
+		writeSample(w, v.d.name+"_count", labels, float64(count))
+	})
+}
+
+type Histogram struct {
+	buckets []float64
+
+	mtx    sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	// counts are per bucket here and made cumulative when written
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += value
+}
+
+func (h *Histogram) Count() uint64 {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	return h.count
+}
+
+func (h *Histogram) snapshot() ([]uint64, uint64, float64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	counts := make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+	return counts, h.count, h.sum
+}
+
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+func (f *atomicFloat) store(value float64) {
+	f.bits.Store(math.Float64bits(value))
+}
+
+func (f *atomicFloat) add(delta float64) {
+	for {
+		old := f.bits.Load()
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if f.bits.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func formatLabels(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func withLabel(labels, name, value string) string {
+	pair := name + `="` + value + `"`
+	if labels == "" {
+		return pair
+	}
+	return labels + "," + pair
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
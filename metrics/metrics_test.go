@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WriteText(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("requests_total", "Requests by code.", "code", "path")
+	requests.With("200", "/a").Add(2)
+	requests.With("500", `/"b"`).Inc()
+	queue := r.NewGauge("queue_depth", "Items\nwaiting.")
+	queue.With().Set(3)
+	queue.With().Add(-1)
+	r.NewGaugeFunc("lag", "Lag in blocks.", func() float64 { return 7 })
+	latency := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "method")
+	latency.With("get").Observe(0.05)
+	latency.With("get").Observe(0.5)
+	latency.With("get").Observe(2)
+
+	var out strings.Builder
+	require.NoError(t, r.WriteText(&out))
+	assert.Equal(t, `# HELP lag Lag in blocks.
+# TYPE lag gauge
+lag 7
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="get",le="0.1"} 1
+latency_seconds_bucket{method="get",le="1"} 2
+latency_seconds_bucket{method="get",le="+Inf"} 3
+latency_seconds_sum{method="get"} 2.55
+latency_seconds_count{method="get"} 3
+# HELP queue_depth Items\nwaiting.
+# TYPE queue_depth gauge
+queue_depth 2
+# HELP requests_total Requests by code.
+# TYPE requests_total counter
+requests_total{code="200",path="/a"} 2
+requests_total{code="500",path="/\"b\""} 1
+`, out.String())
+
+	assert.Equal(t, float64(2), requests.With("200", "/a").Value())
+	assert.Equal(t, uint64(3), latency.With("get").Count())
+}
+
+func Test_Delete(t *testing.T) {
+	r := NewRegistry()
+	matches := r.NewCounter("matches_total", "Matches.", "address")
+	matches.With("0x1").Inc()
+	matches.With("0x2").Inc()
+	matches.Delete("0x1")
+
+	var out strings.Builder
+	require.NoError(t, r.WriteText(&out))
+	assert.NotContains(t, out.String(), `address="0x1"`)
+	assert.Contains(t, out.String(), `matches_total{address="0x2"} 1`)
+}
+
+func Test_Reregister(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("depth", "First.").With().Set(1)
+	r.NewGauge("depth", "Second.")
+
+	var out strings.Builder
+	require.NoError(t, r.WriteText(&out))
+	assert.Equal(t, "# HELP depth Second.\n# TYPE depth gauge\n", out.String())
+}
+
+func Test_LabelCount(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("requests_total", "Requests.", "code")
+	assert.Panics(t, func() { requests.With() })
+	assert.Panics(t, func() { requests.With("200").Add(-1) })
+}
+
+func Test_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("requests_total", "Requests.").With().Inc()
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "requests_total 1\n")
+}
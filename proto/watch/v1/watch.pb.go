@@ -0,0 +1,684 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: watch/v1/watch.proto
+
+package watchv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetCurrentBlockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCurrentBlockRequest) Reset() {
+	*x = GetCurrentBlockRequest{}
+	mi := &file_watch_v1_watch_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCurrentBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentBlockRequest) ProtoMessage() {}
+
+func (x *GetCurrentBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_v1_watch_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentBlockRequest.ProtoReflect.Descriptor instead.
+func (*GetCurrentBlockRequest) Descriptor() ([]byte, []int) {
+	return file_watch_v1_watch_proto_rawDescGZIP(), []int{0}
+}
+
+type GetCurrentBlockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BlockNumber   int64                  `protobuf:"varint,1,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCurrentBlockResponse) Reset() {
+	*x = GetCurrentBlockResponse{}
+	mi := &file_watch_v1_watch_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCurrentBlockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentBlockResponse) ProtoMessage() {}
+
+func (x *GetCurrentBlockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_v1_watch_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentBlockResponse.ProtoReflect.Descriptor instead.
+func (*GetCurrentBlockResponse) Descriptor() ([]byte, []int) {
+	return file_watch_v1_watch_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetCurrentBlockResponse) GetBlockNumber() int64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+type SubscribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_watch_v1_watch_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_v1_watch_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_watch_v1_watch_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SubscribeRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type SubscribeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeResponse) Reset() {
+	*x = SubscribeResponse{}
+	mi := &file_watch_v1_watch_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeResponse) ProtoMessage() {}
+
+func (x *SubscribeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_v1_watch_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeResponse.ProtoReflect.Descriptor instead.
+func (*SubscribeResponse) Descriptor() ([]byte, []int) {
+	return file_watch_v1_watch_proto_rawDescGZIP(), []int{3}
+}
+
+type UnsubscribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnsubscribeRequest) Reset() {
+	*x = UnsubscribeRequest{}
+	mi := &file_watch_v1_watch_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnsubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnsubscribeRequest) ProtoMessage() {}
+
+func (x *UnsubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_v1_watch_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnsubscribeRequest.ProtoReflect.Descriptor instead.
+func (*UnsubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_watch_v1_watch_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UnsubscribeRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type UnsubscribeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnsubscribeResponse) Reset() {
+	*x = UnsubscribeResponse{}
+	mi := &file_watch_v1_watch_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnsubscribeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnsubscribeResponse) ProtoMessage() {}
+
+func (x *UnsubscribeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_v1_watch_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnsubscribeResponse.ProtoReflect.Descriptor instead.
+func (*UnsubscribeResponse) Descriptor() ([]byte, []int) {
+	return file_watch_v1_watch_proto_rawDescGZIP(), []int{5}
+}
+
+type ListTransactionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransactionsRequest) Reset() {
+	*x = ListTransactionsRequest{}
+	mi := &file_watch_v1_watch_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransactionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransactionsRequest) ProtoMessage() {}
+
+func (x *ListTransactionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_v1_watch_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransactionsRequest.ProtoReflect.Descriptor instead.
+func (*ListTransactionsRequest) Descriptor() ([]byte, []int) {
+	return file_watch_v1_watch_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListTransactionsRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type ListTransactionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transactions  []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransactionsResponse) Reset() {
+	*x = ListTransactionsResponse{}
+	mi := &file_watch_v1_watch_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransactionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransactionsResponse) ProtoMessage() {}
+
+func (x *ListTransactionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_v1_watch_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransactionsResponse.ProtoReflect.Descriptor instead.
+func (*ListTransactionsResponse) Descriptor() ([]byte, []int) {
+	return file_watch_v1_watch_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListTransactionsResponse) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+type WatchTransactionsRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Address string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// last_id resumes the stream after the last match the client has seen.
+	LastId        int64 `protobuf:"varint,2,opt,name=last_id,json=lastId,proto3" json:"last_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchTransactionsRequest) Reset() {
+	*x = WatchTransactionsRequest{}
+	mi := &file_watch_v1_watch_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchTransactionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchTransactionsRequest) ProtoMessage() {}
+
+func (x *WatchTransactionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_v1_watch_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchTransactionsRequest.ProtoReflect.Descriptor instead.
+func (*WatchTransactionsRequest) Descriptor() ([]byte, []int) {
+	return file_watch_v1_watch_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WatchTransactionsRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *WatchTransactionsRequest) GetLastId() int64 {
+	if x != nil {
+		return x.LastId
+	}
+	return 0
+}
+
+type TransactionMatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Transaction   *Transaction           `protobuf:"bytes,3,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionMatch) Reset() {
+	*x = TransactionMatch{}
+	mi := &file_watch_v1_watch_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionMatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionMatch) ProtoMessage() {}
+
+func (x *TransactionMatch) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_v1_watch_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionMatch.ProtoReflect.Descriptor instead.
+func (*TransactionMatch) Descriptor() ([]byte, []int) {
+	return file_watch_v1_watch_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *TransactionMatch) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *TransactionMatch) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *TransactionMatch) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+// Transaction keeps the hex encoding used by the node.
+type Transaction struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	BlockNumber   string                 `protobuf:"bytes,2,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	From          string                 `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,4,opt,name=to,proto3" json:"to,omitempty"`
+	Value         string                 `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	Gas           string                 `protobuf:"bytes,6,opt,name=gas,proto3" json:"gas,omitempty"`
+	GasPrice      string                 `protobuf:"bytes,7,opt,name=gas_price,json=gasPrice,proto3" json:"gas_price,omitempty"`
+	Input         string                 `protobuf:"bytes,8,opt,name=input,proto3" json:"input,omitempty"`
+	Status        string                 `protobuf:"bytes,9,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_watch_v1_watch_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_watch_v1_watch_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_watch_v1_watch_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Transaction) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Transaction) GetBlockNumber() string {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return ""
+}
+
+func (x *Transaction) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *Transaction) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *Transaction) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Transaction) GetGas() string {
+	if x != nil {
+		return x.Gas
+	}
+	return ""
+}
+
+func (x *Transaction) GetGasPrice() string {
+	if x != nil {
+		return x.GasPrice
+	}
+	return ""
+}
+
+func (x *Transaction) GetInput() string {
+	if x != nil {
+		return x.Input
+	}
+	return ""
+}
+
+func (x *Transaction) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+var File_watch_v1_watch_proto protoreflect.FileDescriptor
+
+const file_watch_v1_watch_proto_rawDesc = "" +
+	"\n" +
+	"\x14watch/v1/watch.proto\x12\bwatch.v1\"\x18\n" +
+	"\x16GetCurrentBlockRequest\"<\n" +
+	"\x17GetCurrentBlockResponse\x12!\n" +
+	"\fblock_number\x18\x01 \x01(\x03R\vblockNumber\",\n" +
+	"\x10SubscribeRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"\x13\n" +
+	"\x11SubscribeResponse\".\n" +
+	"\x12UnsubscribeRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"\x15\n" +
+	"\x13UnsubscribeResponse\"3\n" +
+	"\x17ListTransactionsRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"U\n" +
+	"\x18ListTransactionsResponse\x129\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x15.watch.v1.TransactionR\ftransactions\"M\n" +
+	"\x18WatchTransactionsRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x17\n" +
+	"\alast_id\x18\x02 \x01(\x03R\x06lastId\"u\n" +
+	"\x10TransactionMatch\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x127\n" +
+	"\vtransaction\x18\x03 \x01(\v2\x15.watch.v1.TransactionR\vtransaction\"\xdb\x01\n" +
+	"\vTransaction\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12!\n" +
+	"\fblock_number\x18\x02 \x01(\tR\vblockNumber\x12\x12\n" +
+	"\x04from\x18\x03 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x04 \x01(\tR\x02to\x12\x14\n" +
+	"\x05value\x18\x05 \x01(\tR\x05value\x12\x10\n" +
+	"\x03gas\x18\x06 \x01(\tR\x03gas\x12\x1b\n" +
+	"\tgas_price\x18\a \x01(\tR\bgasPrice\x12\x14\n" +
+	"\x05input\x18\b \x01(\tR\x05input\x12\x16\n" +
+	"\x06status\x18\t \x01(\tR\x06status2\xaa\x03\n" +
+	"\fWatchService\x12V\n" +
+	"\x0fGetCurrentBlock\x12 .watch.v1.GetCurrentBlockRequest\x1a!.watch.v1.GetCurrentBlockResponse\x12D\n" +
+	"\tSubscribe\x12\x1a.watch.v1.SubscribeRequest\x1a\x1b.watch.v1.SubscribeResponse\x12J\n" +
+	"\vUnsubscribe\x12\x1c.watch.v1.UnsubscribeRequest\x1a\x1d.watch.v1.UnsubscribeResponse\x12Y\n" +
+	"\x10ListTransactions\x12!.watch.v1.ListTransactionsRequest\x1a\".watch.v1.ListTransactionsResponse\x12U\n" +
+	"\x11WatchTransactions\x12\".watch.v1.WatchTransactionsRequest\x1a\x1a.watch.v1.TransactionMatch0\x01B5Z3deshev.com/eth-address-watch/proto/watch/v1;watchv1b\x06proto3"
+
+var (
+	file_watch_v1_watch_proto_rawDescOnce sync.Once
+	file_watch_v1_watch_proto_rawDescData []byte
+)
+
+func file_watch_v1_watch_proto_rawDescGZIP() []byte {
+	file_watch_v1_watch_proto_rawDescOnce.Do(func() {
+		file_watch_v1_watch_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_watch_v1_watch_proto_rawDesc), len(file_watch_v1_watch_proto_rawDesc)))
+	})
+	return file_watch_v1_watch_proto_rawDescData
+}
+
+var file_watch_v1_watch_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_watch_v1_watch_proto_goTypes = []any{
+	(*GetCurrentBlockRequest)(nil),   // 0: watch.v1.GetCurrentBlockRequest
+	(*GetCurrentBlockResponse)(nil),  // 1: watch.v1.GetCurrentBlockResponse
+	(*SubscribeRequest)(nil),         // 2: watch.v1.SubscribeRequest
+	(*SubscribeResponse)(nil),        // 3: watch.v1.SubscribeResponse
+	(*UnsubscribeRequest)(nil),       // 4: watch.v1.UnsubscribeRequest
+	(*UnsubscribeResponse)(nil),      // 5: watch.v1.UnsubscribeResponse
+	(*ListTransactionsRequest)(nil),  // 6: watch.v1.ListTransactionsRequest
+	(*ListTransactionsResponse)(nil), // 7: watch.v1.ListTransactionsResponse
+	(*WatchTransactionsRequest)(nil), // 8: watch.v1.WatchTransactionsRequest
+	(*TransactionMatch)(nil),         // 9: watch.v1.TransactionMatch
+	(*Transaction)(nil),              // 10: watch.v1.Transaction
+}
+var file_watch_v1_watch_proto_depIdxs = []int32{
+	10, // 0: watch.v1.ListTransactionsResponse.transactions:type_name -> watch.v1.Transaction
+	10, // 1: watch.v1.TransactionMatch.transaction:type_name -> watch.v1.Transaction
+	0,  // 2: watch.v1.WatchService.GetCurrentBlock:input_type -> watch.v1.GetCurrentBlockRequest
+	2,  // 3: watch.v1.WatchService.Subscribe:input_type -> watch.v1.SubscribeRequest
+	4,  // 4: watch.v1.WatchService.Unsubscribe:input_type -> watch.v1.UnsubscribeRequest
+	6,  // 5: watch.v1.WatchService.ListTransactions:input_type -> watch.v1.ListTransactionsRequest
+	8,  // 6: watch.v1.WatchService.WatchTransactions:input_type -> watch.v1.WatchTransactionsRequest
+	1,  // 7: watch.v1.WatchService.GetCurrentBlock:output_type -> watch.v1.GetCurrentBlockResponse
+	3,  // 8: watch.v1.WatchService.Subscribe:output_type -> watch.v1.SubscribeResponse
+	5,  // 9: watch.v1.WatchService.Unsubscribe:output_type -> watch.v1.UnsubscribeResponse
+	7,  // 10: watch.v1.WatchService.ListTransactions:output_type -> watch.v1.ListTransactionsResponse
+	9,  // 11: watch.v1.WatchService.WatchTransactions:output_type -> watch.v1.TransactionMatch
+	7,  // [7:12] is the sub-list for method output_type
+	2,  // [2:7] is the sub-list for method input_type
+	2,  // [2:2] is the sub-list for extension type_name
+	2,  // [2:2] is the sub-list for extension extendee
+	0,  // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_watch_v1_watch_proto_init() }
+func file_watch_v1_watch_proto_init() {
+	if File_watch_v1_watch_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_watch_v1_watch_proto_rawDesc), len(file_watch_v1_watch_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_watch_v1_watch_proto_goTypes,
+		DependencyIndexes: file_watch_v1_watch_proto_depIdxs,
+		MessageInfos:      file_watch_v1_watch_proto_msgTypes,
+	}.Build()
+	File_watch_v1_watch_proto = out.File
+	file_watch_v1_watch_proto_goTypes = nil
+	file_watch_v1_watch_proto_depIdxs = nil
+}
@@ -0,0 +1,102 @@
+// Package ratelimit keeps the request rate limits shared by the HTTP and gRPC
+// APIs, so a client gets the same limit whichever transport it uses.
+package ratelimit
+
+import (
+	linkedlist "container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"sync"
+	"time"
+)
+
+const maxBuckets = 10_000
+
+// Limiter is a token bucket per client. When there are too many clients, the
+// least recently used bucket is evicted for a new one.
+type Limiter struct {
+	mtx   sync.Mutex
+	rate  float64
+	burst float64
+	// buckets points into lru, which has the most recently used bucket first
+	buckets    map[string]*linkedlist.Element
+	lru        *linkedlist.List
+	maxBuckets int
+	now        func() time.Time
+}
+
+type bucket struct {
+	client string
+	tokens float64
+	last   time.Time
+}
+
+// New limits every client to rps requests per second with bursts of up to
+// burst requests.
+func New(rps float64, burst int) *Limiter {
+	return newLimiter(rps, burst, time.Now)
+}
+
+func newLimiter(rps float64, burst int, now func() time.Time) *Limiter {
+	return &Limiter{
+		rate:       rps,
+		burst:      float64(max(burst, 1)),
+		buckets:    map[string]*linkedlist.Element{},
+		lru:        linkedlist.New(),
+		maxBuckets: maxBuckets,
+		now:        now,
+	}
+}
+
+// Allow takes a token for the client or returns how long until one is
+// available.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := l.now()
+	e, exists := l.buckets[client]
+	if exists {
+		l.lru.MoveToFront(e)
+	} else {
+		if l.lru.Len() >= l.maxBuckets {
+			l.evict()
+		}
+		e = l.lru.PushFront(&bucket{client: client, tokens: l.burst, last: now})
+		l.buckets[client] = e
+	}
+
+	b := e.Value.(*bucket)
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// evict drops the least recently used bucket.
+func (l *Limiter) evict() {
+	oldest := l.lru.Back()
+	l.lru.Remove(oldest)
+	delete(l.buckets, oldest.Value.(*bucket).client)
+}
+
+// Key is the client of requests with an authenticated API key. Only a hash of
+// the key is kept in memory.
+func Key(apiKey string) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return "key:" + hex.EncodeToString(hash[:])
+}
+
+// IP is the client of requests without a valid API key, from the remote
+// address of the connection.
+func IP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return "ip:" + host
+}
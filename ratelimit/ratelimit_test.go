@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Limiter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	limiter := newLimiter(2, 3, func() time.Time { return now })
+
+	for range 3 {
+		allowed, _ := limiter.Allow("a")
+		assert.True(t, allowed)
+	}
+	allowed, wait := limiter.Allow("a")
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// other clients have their own bucket
+	allowed, _ = limiter.Allow("b")
+	assert.True(t, allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	allowed, _ = limiter.Allow("a")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("a")
+	assert.False(t, allowed)
+}
+
+func Test_Limiter_EvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	limiter := newLimiter(1, 1, func() time.Time { return now })
+	limiter.maxBuckets = 2
+
+	allowed, _ := limiter.Allow("a")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("b")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("a")
+	assert.False(t, allowed)
+
+	// b is evicted for c, while a keeps its empty bucket
+	allowed, _ = limiter.Allow("c")
+	assert.True(t, allowed)
+	assert.Equal(t, 2, len(limiter.buckets))
+	assert.Equal(t, 2, limiter.lru.Len())
+	allowed, _ = limiter.Allow("a")
+	assert.False(t, allowed)
+	_, exists := limiter.buckets["b"]
+	assert.False(t, exists)
+}
+
+func Test_Clients(t *testing.T) {
+	assert.Equal(t, "ip:192.0.2.1", IP("192.0.2.1:50000"))
+	assert.Equal(t, "ip:bufconn", IP("bufconn"))
+	assert.Equal(t, Key("key-1"), Key("key-1"))
+	assert.NotContains(t, Key("key-1"), "key-1")
+}
@@ -0,0 +1,66 @@
+// Package tracing sets up OpenTelemetry tracing with W3C trace context
+// propagation and starts the spans of the service.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"deshev.com/eth-address-watch/config"
+)
+
+const (
+	serviceName         = "eth-address-watch"
+	instrumentationName = "deshev.com/eth-address-watch"
+)
+
+// Setup installs the W3C trace context propagator and, when an OTLP endpoint
+// is configured, a tracer provider exporting spans to it over OTLP/HTTP.
+// Without an endpoint the global no-op provider stays in place, so spans are
+// dropped while incoming trace context is still passed on to the node. The
+// returned func flushes pending spans.
+func Setup(log *slog.Logger, cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.OTLPEndpoint == "" {
+		log.Info("no OTLP endpoint configured, traces are not exported")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	if endpoint, err := url.Parse(cfg.OTLPEndpoint); err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", cfg.OTLPEndpoint)
+	}
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+	if err != nil {
+		return nil, fmt.Errorf("OTLP exporter create error: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	log.Info("exporting traces", "endpoint", cfg.OTLPEndpoint)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span with the current global tracer provider.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// Fail records the error on the span and marks it failed.
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+
+	"deshev.com/eth-address-watch/config"
+	"deshev.com/eth-address-watch/tracing/tracingtest"
+)
+
+func Test_Setup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	shutdown, err := Setup(slog.Default(), &config.Config{})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+	_, span := Start(context.Background(), "noop")
+	assert.False(t, span.IsRecording())
+	assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+
+	shutdown, err = Setup(slog.Default(), &config.Config{OTLPEndpoint: "http://127.0.0.1:4318"})
+	require.NoError(t, err)
+	_, span = Start(context.Background(), "exported")
+	assert.True(t, span.IsRecording())
+	span.End()
+	// the collector is not running, so flushing fails or times out
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = shutdown(ctx)
+
+	_, err = Setup(slog.Default(), &config.Config{OTLPEndpoint: "://bad"})
+	assert.Error(t, err)
+}
+
+func Test_Fail(t *testing.T) {
+	recorder := tracingtest.Record(t)
+
+	_, span := Start(context.Background(), "failing")
+	Fail(span, errors.New("boom"))
+	span.End()
+
+	spans := tracingtest.Named(recorder, "failing")
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "boom", spans[0].Status().Description)
+	assert.Len(t, spans[0].Events(), 1)
+}
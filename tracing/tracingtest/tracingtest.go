@@ -0,0 +1,41 @@
+// Package tracingtest records the spans of a test in memory.
+package tracingtest
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Record installs a tracer provider keeping ended spans in the returned
+// recorder, along with the W3C trace context propagator, until the test ends.
+func Record(t testing.TB) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+		_ = provider.Shutdown(context.Background())
+	})
+	return recorder
+}
+
+// Named returns the ended spans with the given name.
+func Named(recorder *tracetest.SpanRecorder, name string) []sdktrace.ReadOnlySpan {
+	spans := []sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}